@@ -0,0 +1,122 @@
+package autoconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// SetSectionFromJSON decodes data into a copy of a single section, checks it, applies it and notifies listeners.
+// Keys are matched as loaders match them (see WithKeyNaming), unknown keys being rejected.
+// If data cannot be decoded or is not valid, the section is left untouched. If it is rejected by a listener, the section is rolled back.
+// It is meant to be used by admin UIs and chat-ops commands editing a section of a running process.
+// data is kept as an override of the section, and applied again after each reload until ClearOverride is called.
+// Overrides are persisted if an OverrideStore has been set (see WithOverrideStore).
+func (c *Config) SetSectionFromJSON(name string, data []byte) error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	s, found := c.sections[name]
+	if !found || s.current == nil || s.derive != nil {
+		return fmt.Errorf("Config: cannot set section %s: %s", name, ErrUnknownSection)
+	}
+	scratch := deepCopy(s.current)
+	strict := c.decoding
+	strict.DisallowUnknownKeys = true
+	if err := strict.JSON(data, scratch); err != nil {
+		return fmt.Errorf("Config: cannot decode section %s: %s", name, err)
+	}
+	if err := c.apply(name, scratch, ReasonManual); err != nil {
+		return err
+	}
+	// The override is applied even if it cannot be persisted
+	return c.addOverride(name, data)
+}
+
+// SetSectionFromJSON decodes data into a section of the default config, checks it, applies it and notifies listeners.
+func SetSectionFromJSON(name string, data []byte) error {
+	return globalConfig.SetSectionFromJSON(name, data)
+}
+
+// Set sets a single value of a section, checks it, applies it and notifies listeners, e.g. for feature toggles.
+// key is a dotted path, matched case-insensitively against the yaml/ini/json tags (or names) of fields (see WithArgs).
+// value must be assignable to the field, or be a string parsed as command-line arguments are.
+// Unlike SetSectionFromJSON, the value is not kept as an override, and is replaced by the value loaded on the next reload.
+//
+// 	cfg.Set("features", "new_checkout", true)
+func (c *Config) Set(name, key string, value interface{}) error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	s, found := c.sections[name]
+	if !found || s.current == nil || s.derive != nil {
+		return fmt.Errorf("Config: cannot set section %s: %s", name, ErrUnknownSection)
+	}
+	scratch := s.copy()
+	f, ok := fieldByPath(reflect.ValueOf(scratch), strings.Split(key, "."), c.decoding)
+	if !ok {
+		return fmt.Errorf("Config: unknown key %s.%s", name, key)
+	}
+	if err := setValue(f, value, c.decoding); err != nil {
+		return fmt.Errorf("Config: invalid value %v for %s.%s: %s", value, name, key, err)
+	}
+	return c.apply(name, scratch, ReasonManual)
+}
+
+// Set sets a single value of a section of the default config, checks it, applies it and notifies listeners.
+func Set(name, key string, value interface{}) error {
+	return globalConfig.Set(name, key, value)
+}
+
+// apply checks scratch, the new value of a section, applies it and notifies listeners of reason.
+// c.reloadMu must be held, so that listeners are not notified concurrently with a reload.
+func (c *Config) apply(name string, scratch interface{}, reason Reason) error {
+	s := c.sections[name]
+	if err := c.check(map[string]interface{}{name: scratch}); err != nil {
+		return err
+	}
+	if err := c.checkFrozen(map[string]interface{}{name: scratch}); err != nil {
+		return err
+	}
+	if err := c.validate(map[string]interface{}{name: scratch}); err != nil {
+		return err
+	}
+	if l, ok := s.current.(sync.Locker); ok && !s.fresh {
+		l.Lock()
+		assign(s.current, scratch)
+		l.Unlock()
+	} else {
+		s.commit(scratch)
+	}
+	c.commitConfig(map[string]interface{}{name: scratch})
+	if _, errs := c.notify(nil, reason); len(errs) > 0 {
+		// Rejected by a listener (see ReconfigurableErr), the section has been rolled back
+		return errs
+	}
+	return nil
+}
+
+// setValue sets f to value, parsing strings if needed.
+func setValue(f reflect.Value, value interface{}, dec decode.Options) error {
+	if value == nil {
+		f.Set(reflect.Zero(f.Type()))
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(f.Type()):
+		f.Set(v)
+	case v.Kind() == reflect.String:
+		return setFromString(f, v.String(), dec)
+	case isNumber(v.Kind()) && isNumber(f.Kind()):
+		f.Set(v.Convert(f.Type()))
+	default:
+		return fmt.Errorf("cannot use a %T as a %s", value, f.Type())
+	}
+	return nil
+}
+
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
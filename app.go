@@ -0,0 +1,116 @@
+package autoconfig
+
+import (
+	"reflect"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// appsKey is the key under which per-application sections are defined.
+const appsKey = "apps"
+
+// WithApp sets the identity of the application, e.g. the name of the binary.
+// Each section is loaded from the <section> key, then overlaid by the apps.<app>.<section> key if defined,
+// so that packages shared by several binaries of a monorepo can be configured differently per binary from a single file.
+//
+// 	log:
+// 	  level: info
+// 	apps:
+// 	  billing-api:
+// 	    log:
+// 	      level: debug
+//
+// INI files use [apps.billing-api.log] sections.
+func WithApp(app string) Option {
+	return func(c *Config) {
+		c.app = app
+	}
+}
+
+// loadTargets loads targets using l, then the sections of the profile and of the application, if set (see loadLayers).
+// Targets are decoded using the decoding options of c (see OptionsLoader).
+// Default tags are then applied to the entries of maps of structs that are not in the defaults.
+// Panics of l are returned as errors.
+func (c *Config) loadTargets(l Loader, targets map[string]interface{}) error {
+	var prefixes []string
+	if len(c.middlewares) == 0 {
+		// Otherwise, overlays are loaded underneath middlewares (see mainLoader)
+		prefixes = c.overlays()
+	}
+	if err := protect("loader", func() error { return loadLayers(l, prefixes, targets, c.decoding) }); err != nil {
+		return err
+	}
+	errs := Errors{}
+	for name, t := range targets {
+		var defaults reflect.Value
+		if s, ok := c.sections[name]; ok {
+			defaults = s.defaults
+		}
+		errs = append(errs, applyEntryDefaultTags(name, reflect.ValueOf(t), defaults, c.decoding)...)
+	}
+	return errs.errorOrNil()
+}
+
+// overlays returns the key prefixes of the sections overlaid on top of the base sections, in order.
+func (c *Config) overlays() []string {
+	var prefixes []string
+	if c.profile != "" {
+		prefixes = append(prefixes, profilesKey+"."+c.profile+".")
+	}
+	if c.app != "" {
+		prefixes = append(prefixes, appsKey+"."+c.app+".")
+	}
+	return prefixes
+}
+
+// loadLayers loads targets using l, then the sections prefixed by each prefix on top of them, decoding them using o.
+func loadLayers(l Loader, prefixes []string, targets map[string]interface{}, o decode.Options) error {
+	if err := LoadWith(l, targets, o); err != nil {
+		return err
+	}
+	for _, prefix := range prefixes {
+		ns := make(map[string]interface{}, len(targets))
+		for name, t := range targets {
+			ns[prefix+name] = t
+		}
+		if err := LoadWith(l, ns, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// layers returns a loader loading the sections of l overlaid by the sections prefixed by prefixes in a single load,
+// so that middlewares wrapping it (e.g. TransformStrings) process each value once.
+func layers(l Loader, prefixes []string) Loader {
+	if len(prefixes) == 0 {
+		return l
+	}
+	ll := layeredLoader{next: l, prefixes: prefixes}
+	if rl, ok := l.(RawLoader); ok {
+		return layeredRawLoader{layeredLoader: ll, raw: rl}
+	}
+	return ll
+}
+
+type layeredLoader struct {
+	next     Loader
+	prefixes []string
+}
+
+func (l layeredLoader) Load(targets map[string]interface{}) error {
+	return l.LoadWith(targets, decode.Options{})
+}
+
+func (l layeredLoader) LoadWith(targets map[string]interface{}, o decode.Options) error {
+	return loadLayers(l.next, l.prefixes, targets, o)
+}
+
+type layeredRawLoader struct {
+	layeredLoader
+	raw RawLoader
+}
+
+func (l layeredRawLoader) Raw() (map[string]interface{}, error) {
+	return l.raw.Raw()
+}
@@ -0,0 +1,122 @@
+package autoconfig
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// WithArgs overrides the values loaded by the loader (and environment variables, see WithEnv) with command-line arguments
+// of the form --section.key=value (or --section.key value), so that operators can tweak a single value at start.
+// Keys are matched case-insensitively against the yaml/ini/json tags (or names) of fields, nested fields using dotted keys.
+// If no args are given, os.Args[1:] is used. Arguments not matching any section are ignored.
+//
+// 	./myapp --db.pool_size=50 --http.tls.enabled=true
+func WithArgs(args ...string) Option {
+	if len(args) == 0 {
+		args = os.Args[1:]
+	}
+	return func(c *Config) {
+		for k, v := range parseArgs(args) {
+			c.args[k] = v
+		}
+	}
+}
+
+// parseArgs returns the values of --key=value and --key value arguments, by key
+func parseArgs(args []string) map[string]string {
+	values := map[string]string{}
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			break
+		}
+		if !strings.HasPrefix(a, "-") {
+			continue
+		}
+		a = strings.TrimLeft(a, "-")
+		if p := strings.IndexByte(a, '='); p >= 0 {
+			values[a[:p]] = a[p+1:]
+		} else if strings.Contains(a, ".") && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			values[a] = args[i+1]
+			i++
+		}
+	}
+	return values
+}
+
+// applyArgs sets the fields of targets overridden by command-line arguments.
+func (c *Config) applyArgs(targets map[string]interface{}) error {
+	if len(c.args) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(c.args))
+	for k := range c.args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	errs := Errors{}
+	for _, key := range keys {
+		name, t := sectionOf(targets, key)
+		if t == nil {
+			continue
+		}
+		f, ok := fieldByPath(reflect.ValueOf(t), strings.Split(key[len(name)+1:], "."), c.decoding)
+		if !ok {
+			errs = append(errs, fmt.Errorf("Config: unknown key %s", key))
+			continue
+		}
+		if err := setFromString(f, c.args[key], c.decoding); err != nil {
+			errs = append(errs, fmt.Errorf("Config: invalid value %q for %s: %s", c.args[key], key, err))
+		}
+	}
+	return errs.errorOrNil()
+}
+
+// sectionOf returns the section a dotted key belongs to, i.e. the longest section name prefixing it.
+func sectionOf(targets map[string]interface{}, key string) (string, interface{}) {
+	var (
+		name string
+		t    interface{}
+	)
+	for n, v := range targets {
+		if strings.HasPrefix(key, n+".") && len(n) > len(name) {
+			name, t = n, v
+		}
+	}
+	return name, t
+}
+
+// fieldByPath returns the field of a struct matching path, using the yaml/ini/json tags (or names) of fields.
+func fieldByPath(v reflect.Value, path []string, dec decode.Options) (reflect.Value, bool) {
+	for _, p := range path {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return v, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return v, false
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath == "" && strings.EqualFold(fieldKey(field, dec), p) {
+				v, found = v.Field(i), true
+				break
+			}
+		}
+		if !found {
+			return v, false
+		}
+	}
+	return v, true
+}
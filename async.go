@@ -0,0 +1,75 @@
+package autoconfig
+
+import (
+	"hash/fnv"
+	"log"
+	"sync"
+)
+
+// WithAsyncNotify notifies listeners asynchronously, using a pool of workers, so that slow listeners
+// (e.g. reconnecting pools or reopening files) do not block reloads.
+// Notifications of a section are always delivered in order, by the same worker. Each worker queues up to queueSize notifications,
+// reloads block when the queue of a worker is full. Close waits for queued notifications to be delivered.
+func WithAsyncNotify(workers, queueSize int) Option {
+	return func(c *Config) {
+		if workers < 1 {
+			workers = 1
+		}
+		c.async = newAsyncNotifier(workers, queueSize)
+	}
+}
+
+type asyncNotifier struct {
+	mu     sync.RWMutex
+	closed bool
+	queues []chan func()
+	wg     sync.WaitGroup
+}
+
+func newAsyncNotifier(workers, queueSize int) *asyncNotifier {
+	a := &asyncNotifier{queues: make([]chan func(), workers)}
+	for i := range a.queues {
+		q := make(chan func(), queueSize)
+		a.queues[i] = q
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			for fn := range q {
+				fn()
+			}
+		}()
+	}
+	return a
+}
+
+// submit runs fn using the worker of section. fn is run synchronously once the notifier has been closed.
+func (a *asyncNotifier) submit(section string, fn func()) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		fn()
+		return
+	}
+	h := fnv.New32a()
+	h.Write([]byte(section))
+	a.queues[h.Sum32()%uint32(len(a.queues))] <- func() {
+		if err := protect("listener", func() error { fn(); return nil }); err != nil {
+			log.Printf("Config: section %s: %s", section, err)
+		}
+	}
+}
+
+// close waits for queued notifications to be delivered, and stops the workers.
+func (a *asyncNotifier) close() {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return
+	}
+	a.closed = true
+	for _, q := range a.queues {
+		close(q)
+	}
+	a.mu.Unlock()
+	a.wg.Wait()
+}
@@ -0,0 +1,106 @@
+// Package autoconfigtest provides helpers to test packages configured using autoconfig.
+//
+// 	func TestReconfigure(t *testing.T) {
+// 		cfg := autoconfig.New(yaml.New(filename))
+// 		cfg.Register("cache", &CacheConf{})
+// 		cfg.Load()
+// 		autoconfigtest.ExpectChange(t, cfg, "cache", func() {
+// 			ioutil.WriteFile(filename, []byte("cache:\n  size: 42\n"), 0600)
+// 		}, &CacheConf{Size: 42})
+// 	}
+package autoconfigtest
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jfbus/autoconfig"
+)
+
+// Timeout is the time listeners have to be notified after a reload (e.g. for throttled sections).
+var Timeout = time.Second
+
+// ExpectChange calls mutate (e.g. to update the config file), reloads cfg, and fails t unless listeners of section
+// are notified of a value equal to expected within Timeout. Values are compared using their JSON encoding.
+func ExpectChange(t testing.TB, cfg *autoconfig.Config, section string, mutate func(), expected interface{}) {
+	t.Helper()
+	want, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatalf("autoconfigtest: cannot encode expected value: %s", err)
+	}
+	r := record(cfg, section, mutate)
+	defer r.stop()
+	var got []string
+	deadline := time.After(Timeout)
+	for {
+		select {
+		case v := <-r.values:
+			if v == string(want) {
+				return
+			}
+			got = append(got, v)
+		case <-deadline:
+			if len(got) == 0 {
+				t.Errorf("autoconfigtest: section %s has not changed, expected %s", section, want)
+			} else {
+				t.Errorf("autoconfigtest: section %s changed to %v, expected %s", section, got, want)
+			}
+			return
+		}
+	}
+}
+
+// ExpectNoChange calls mutate, reloads cfg, and fails t if listeners of section are notified within Timeout.
+func ExpectNoChange(t testing.TB, cfg *autoconfig.Config, section string, mutate func()) {
+	t.Helper()
+	r := record(cfg, section, mutate)
+	defer r.stop()
+	select {
+	case v := <-r.values:
+		t.Errorf("autoconfigtest: section %s should not have changed, changed to %s", section, v)
+	case <-time.After(Timeout):
+	}
+}
+
+// recorder records the values listeners of a section are notified of
+type recorder struct {
+	sync.Mutex
+	active bool
+	values chan string
+}
+
+func record(cfg *autoconfig.Config, section string, mutate func()) *recorder {
+	r := &recorder{values: make(chan string, 100)}
+	// The current value is delivered on registration, and is not a change
+	cfg.Reconfigure(section, r)
+	r.Lock()
+	r.active = true
+	r.Unlock()
+	if mutate != nil {
+		mutate()
+	}
+	cfg.TriggerReload(string(autoconfig.ReasonManual))
+	return r
+}
+
+func (r *recorder) Reconfigure(c interface{}) {
+	r.Lock()
+	defer r.Unlock()
+	if !r.active {
+		return
+	}
+	v, _ := json.Marshal(c)
+	select {
+	case r.values <- string(v):
+	default:
+	}
+}
+
+// stop ignores further notifications, listeners cannot be unregistered
+func (r *recorder) stop() {
+	r.Lock()
+	r.active = false
+	r.Unlock()
+}
@@ -0,0 +1,80 @@
+package autoconfig
+
+import "sync"
+
+// Changes returns a channel receiving the new value of a section each time it changes (see Reconfigure), so that goroutines
+// can select on config changes. The channel buffers a single value : values not received yet are replaced by newer ones,
+// so that slow receivers always get the latest value without blocking reloads.
+// StopChanges must be called once the channel is no longer used.
+//
+// 	changes := cfg.Changes("db")
+// 	defer cfg.StopChanges(changes)
+// 	for {
+// 		select {
+// 		case c := <-changes:
+// 			pool.Resize(c.(*DBConf).Size)
+// 		case <-ctx.Done():
+// 			return
+// 		}
+// 	}
+func (c *Config) Changes(name string) <-chan interface{} {
+	l := &chanListener{ch: make(chan interface{}, 1)}
+	c.Reconfigure(name, l)
+	return l.ch
+}
+
+// Changes returns a channel receiving the new value of a section of the default config each time it changes.
+func Changes(name string) <-chan interface{} {
+	return globalConfig.Changes(name)
+}
+
+// StopChanges unregisters a channel returned by Changes, and closes it.
+func (c *Config) StopChanges(ch <-chan interface{}) {
+	c.removeListeners(func(r Reconfigurable) bool {
+		if l, ok := r.(*chanListener); ok && (<-chan interface{})(l.ch) == ch {
+			l.close()
+			return true
+		}
+		return false
+	})
+}
+
+// StopChanges unregisters a channel returned by Changes, and closes it.
+func StopChanges(ch <-chan interface{}) {
+	globalConfig.StopChanges(ch)
+}
+
+// removeListeners removes the listeners matching match from all sections.
+func (c *Config) removeListeners(match func(Reconfigurable) bool) {
+	for _, s := range c.registered() {
+		s.unlisten(match)
+	}
+}
+
+type chanListener struct {
+	mu     sync.Mutex
+	ch     chan interface{}
+	closed bool
+}
+
+func (l *chanListener) Reconfigure(cfg interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	select {
+	case <-l.ch:
+	default:
+	}
+	l.ch <- cfg
+}
+
+func (l *chanListener) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.closed {
+		l.closed = true
+		close(l.ch)
+	}
+}
@@ -0,0 +1,69 @@
+package autoconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+)
+
+// Checksummer defines loaders able to compute a checksum of their raw source (e.g. the bytes of the config file).
+// Reloads triggered by signals or watchers are skipped when the checksum is the same as the one of the last successful load,
+// avoiding decoding all sections again and comparing them.
+type Checksummer interface {
+	Checksum() (string, error)
+}
+
+// checksum returns the checksum of the source of l, or false if it cannot be computed.
+func checksum(l Loader) (string, bool) {
+	switch t := l.(type) {
+	case wrappedLoader:
+		return checksum(t.next)
+	case wrappedRawLoader:
+		return checksum(t.next)
+	case Checksummer:
+		sum, err := t.Checksum()
+		return sum, err == nil
+	}
+	return "", false
+}
+
+// Checksum combines the checksums of the config loader and of per-section loaders.
+func (sl sectionLoaders) Checksum() (string, error) {
+	h := sha256.New()
+	names := []string{""}
+	loaders := map[string]Loader{"": sl.c.loader}
+	for name, s := range sl.c.sections {
+		if s.loader != nil {
+			names = append(names, name)
+			loaders[name] = s.loader
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sum, ok := checksum(loaders[name])
+		if !ok {
+			return "", errNoChecksum
+		}
+		h.Write([]byte(name + "=" + sum + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// unchanged returns true if the source of l has not changed since the last successful load, and the checksum of the source.
+func (c *Config) unchanged(l Loader) (bool, string) {
+	sum, ok := checksum(l)
+	if !ok {
+		return false, ""
+	}
+	return sum == c.checksum, sum
+}
+
+// skipped returns the outcome of a reload skipped because the source has not changed.
+func (c *Config) skipped() *ReloadResult {
+	res := &ReloadResult{Time: c.clock.Now()}
+	for name := range c.sections {
+		res.Unchanged = append(res.Unchanged, name)
+	}
+	sort.Strings(res.Unchanged)
+	return res
+}
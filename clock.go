@@ -0,0 +1,122 @@
+package autoconfig
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Clock defines the time source used for polling, jitter and scheduling.
+// It can be replaced using WithClock, e.g. to run time-based tests deterministically.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer defines a function scheduled by Clock.AfterFunc.
+type Timer interface {
+	Stop() bool
+}
+
+// Rand defines the randomness source used to compute jitter. *math/rand.Rand implements it.
+type Rand interface {
+	Int63n(n int64) int64
+}
+
+// Option defines a config option, to be passed to New or Load.
+type Option func(*Config)
+
+// WithClock sets the clock used by the config.
+func WithClock(cl Clock) Option {
+	return func(c *Config) {
+		c.clock = cl
+	}
+}
+
+// WithRand sets the randomness source used by the config.
+func WithRand(r Rand) Option {
+	return func(c *Config) {
+		c.rand = r
+	}
+}
+
+// WithJitter randomly spreads periodic tasks (see Every) by up to the given fraction of their interval,
+// so that a fleet of processes does not poll a remote source at the same time.
+func WithJitter(fraction float64) Option {
+	return func(c *Config) {
+		c.jitter = fraction
+	}
+}
+
+// Now returns the current time of the config clock (see WithClock).
+func (c *Config) Now() time.Time {
+	return c.clock.Now()
+}
+
+// Now returns the current time of the default config clock.
+func Now() time.Time {
+	return globalConfig.Now()
+}
+
+// Every calls fn every interval (plus jitter), using the config clock. Calling the returned function, or Close, stops it.
+func (c *Config) Every(interval time.Duration, fn func()) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-c.done:
+				return
+			case <-c.clock.After(c.jittered(interval)):
+				fn()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// Every calls fn every interval (plus jitter), using the default config clock.
+func Every(interval time.Duration, fn func()) (stop func()) {
+	return globalConfig.Every(interval, fn)
+}
+
+func (c *Config) jittered(d time.Duration) time.Duration {
+	max := int64(float64(d) * c.jitter)
+	if max <= 0 {
+		return d
+	}
+	return d + time.Duration(c.rand.Int63n(max))
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// lockedRand makes a *rand.Rand safe for concurrent use.
+type lockedRand struct {
+	sync.Mutex
+	r *rand.Rand
+}
+
+func newLockedRand() *lockedRand {
+	return &lockedRand{r: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (l *lockedRand) Int63n(n int64) int64 {
+	l.Lock()
+	defer l.Unlock()
+	return l.r.Int63n(n)
+}
@@ -0,0 +1,45 @@
+// Package cobra integrates autoconfig with CLI tools built on https://github.com/spf13/cobra and https://github.com/spf13/pflag.
+// It defines a --section.key flag for each field of the registered sections, and loads the config before commands are run.
+// 	root := &cobra.Command{Use: "myapp", Run: run}
+// 	autoconfigcobra.Bind(root, autoconfig.New(yaml.New(filename)))
+// 	root.Execute()
+package cobra
+
+import (
+	"flag"
+
+	"github.com/jfbus/autoconfig"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// BindFlags defines a flag on fs for each field of the registered sections of c (see autoconfig.BindFlags).
+// Values of parsed flags override values from the loader and environment variables, on each load.
+func BindFlags(c *autoconfig.Config, fs *pflag.FlagSet) {
+	gfs := flag.NewFlagSet("autoconfig", flag.ContinueOnError)
+	c.BindFlags(gfs)
+	fs.AddGoFlagSet(gfs)
+}
+
+// Bind defines flags for the sections of c on the persistent flags of cmd, and loads c before cmd (or any of its subcommands) is run.
+// The PersistentPreRunE/PersistentPreRun function of cmd, if any, is run after the config has been loaded.
+// cobra only runs the persistent pre-run function of the nearest command defining one : if a subcommand defines its own,
+// the config is not loaded before it is run, unless cobra.EnableTraverseRunHooks is set.
+// Sections must be registered before Bind is called.
+func Bind(cmd *cobra.Command, c *autoconfig.Config) {
+	BindFlags(c, cmd.PersistentFlags())
+	preRunE, preRun := cmd.PersistentPreRunE, cmd.PersistentPreRun
+	cmd.PersistentPreRun = nil
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := c.Load(); err != nil {
+			return err
+		}
+		switch {
+		case preRunE != nil:
+			return preRunE(cmd, args)
+		case preRun != nil:
+			preRun(cmd, args)
+		}
+		return nil
+	}
+}
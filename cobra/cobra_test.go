@@ -0,0 +1,48 @@
+package cobra
+
+import (
+	"testing"
+
+	"github.com/jfbus/autoconfig"
+	"github.com/jfbus/autoconfig/yaml"
+	"github.com/spf13/cobra"
+)
+
+type testCfg struct {
+	Key  string `yaml:"key"`
+	Port int    `yaml:"port"`
+	Name string `yaml:"name"`
+}
+
+func TestBind(t *testing.T) {
+	cfg := autoconfig.New(yaml.NewFromBytes([]byte("section:\n  key: file\n  port: 80\n  name: file\n")))
+	c := &testCfg{}
+	cfg.Register("section", c)
+	preRun := false
+	var run *testCfg
+	root := &cobra.Command{
+		Use:              "app",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) { preRun = true },
+	}
+	root.AddCommand(&cobra.Command{
+		Use: "serve",
+		Run: func(cmd *cobra.Command, args []string) {
+			v := *c
+			run = &v
+		},
+	})
+	Bind(root, cfg)
+	root.SetArgs([]string{"serve", "--section.key=flag", "--section.port", "8080"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Execute() returned %s", err)
+	}
+	if !preRun {
+		t.Error("The persistent pre-run function of the command should be run")
+	}
+	if run == nil {
+		t.Fatal("The command should be run")
+	}
+	if run.Key != "flag" || run.Port != 8080 || run.Name != "file" {
+		t.Errorf("Flags should override the values of the loader before the command is run, got %+v", *run)
+	}
+}
@@ -0,0 +1,21 @@
+package autoconfig
+
+import (
+	"encoding/json"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// Sections are serialized (e.g. by Export, Cache or overrides) using the keys loaders decode : the config tag of fields,
+// their json tag, or their name as set by the key naming strategy of the config (see WithKeyNaming),
+// so that serialized sections can be decoded again by any loader.
+
+// encodeJSON encodes v, a section, as JSON, using the key options o.
+func encodeJSON(v interface{}, o decode.Options) ([]byte, error) {
+	return json.Marshal(decode.Encode(v, "json", o))
+}
+
+// decodeJSON decodes data, a section encoded by encodeJSON, to v using the key options of c.
+func (c *Config) decodeJSON(data []byte, v interface{}) error {
+	return c.decoding.JSON(data, v)
+}
@@ -6,6 +6,7 @@ Config can be dynamically updated when the application receives a signal.
 
 
 Supported file format are INI (using https://github.com/go-ini/ini) and YAML (using https://gopkg.in/yaml.v2).
+Kubernetes ConfigMaps/Secrets and JSON with comments (JSONC) are supported by the k8s and jsonc loaders.
 
 Usage - YAML
 
@@ -114,37 +115,93 @@ Any config file format can be used, provided a loader class implementing the `Lo
 
 Caveats
 
-* Only a single config file is supported,
-
 * Values types are supported only if the underlying format supports them (e.g. INI does not support slices).
 
 */
 package autoconfig
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"os"
-	"os/signal"
 	"reflect"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jfbus/autoconfig/decode"
 )
 
 type section struct {
+	name      string
 	defaults  reflect.Value
 	current   interface{}
 	signature string
+	mu        sync.Mutex // guards onchange, which listeners may change (e.g. Handle.Release) while they are notified
 	onchange  []Reconfigurable
+	deps      []string
+	priority  int
+	derive    DeriveFunc
+	throttle  *throttle
+	fresh     bool
+	frozen    bool
+	loader    Loader
+	// accepted is a copy of the last value notified to listeners
+	accepted interface{}
+	// published holds current, for readers not holding the lock of the section (see Get)
+	published atomic.Value
 }
 
 // Config defines a config
 type Config struct {
-	filename string
-	sections map[string]*section
-	current  map[string]interface{}
-	loader   Loader
-	loaded   bool
+	filename  string
+	sections  map[string]*section
+	loader    Loader
+	loaded    atomic.Bool
+	clock     Clock
+	rand      Rand
+	jitter    float64
+	lintRules []lintRule
+	shadow    *shadow
+	app       string
+	profile   string
+	envPrefix string
+	args      map[string]string
+	decoding  decode.Options
+
+	secretPolicy SecretPolicy
+	degraded     *degraded
+	middlewares  []LoaderMiddleware
+	debounce     *debounce
+	history      *history
+	checksum     string
+	async        *asyncNotifier
+	copyOnWrite  bool
+	present      map[string]bool
+
+	listenerTimeout time.Duration
+	beforeReload    []func()
+	reloadMu        sync.Mutex
+	sectionsMu      sync.RWMutex
+	requests        requests
+	afterReload     []func(*ReloadResult)
+	reloadErrorsMu  sync.Mutex
+	reloadErrors    []func(error)
+	reportMu        sync.Mutex
+	report          *ReloadResult
+	status          LoadStatus
+	anyChange       []func(section string, cfg interface{})
+	restartHooks    []func(section string, fields []string)
+	validators      []func(section string, cfg interface{}) error
+
+	// done is closed by Close, stopping signal handling and periodic tasks
+	done      chan struct{}
+	closeOnce sync.Once
+
+	overrideStore OverrideStore
+	overrides     map[string]json.RawMessage
 }
 
 // UpdatableConfig defines the interface updateable config need to implement.
@@ -167,31 +224,48 @@ type Loader interface {
 }
 
 var (
-	globalConfig = Config{sections: map[string]*section{}, current: map[string]interface{}{}}
+	globalConfig = New(nil)
+
+	ErrNoLoader       = errors.New("No loader was defined")
+	ErrUnknownSection = errors.New("Unknown section")
 
-	ErrNoLoader = errors.New("No loader was defined")
+	errNoChecksum = errors.New("No checksum")
 )
 
 // New defines a config, based on a loader.
-func New(l Loader) *Config {
-	return &Config{sections: map[string]*section{}, current: map[string]interface{}{}, loader: l}
+func New(l Loader, opts ...Option) *Config {
+	c := &Config{
+		sections: map[string]*section{},
+		loader:   l,
+		clock:    systemClock{},
+		rand:     newLockedRand(),
+		args:     map[string]string{},
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Default returns the default config, used by package-level functions.
+func Default() *Config {
+	return globalConfig
 }
 
 // Load loads the config by calling the Load() function of the loader.
 func (c *Config) Load() error {
-	c.loaded = true
-	return c.load()
+	return c.TriggerReload(string(ReasonManual)).Err
 }
 
-// Load defines the loader for the default config, and loads the config file.
-func Load(l Loader) error {
-	globalConfig.loader = l
-	return globalConfig.Load()
+// Load defines the loader (and options) for the default config, and loads the config file.
+func Load(l Loader, opts ...Option) error {
+	return LoadNamed("", l, opts...)
 }
 
 // Reload reloads the config file
 func (c *Config) Reload() error {
-	return c.Load()
+	return c.TriggerReload(string(ReasonManual)).Err
 }
 
 // Reload reloads the config file for the default config
@@ -200,14 +274,44 @@ func Reload() error {
 }
 
 // ReloadOn defines signal to monitor. On reception of a signal, the config will be reloaded.
+// Signals are monitored until Close is called.
 func (c *Config) ReloadOn(signals ...os.Signal) {
-	go func() {
-		ch := make(chan os.Signal, 1)
-		signal.Notify(ch, signals...)
-		for _ = range ch {
-			c.Reload()
+	c.ReloadOnContext(context.Background(), signals...)
+}
+
+// ReloadOnContext defines signal to monitor, until ctx is done or Close is called. On reception of a signal, the config will be reloaded.
+func (c *Config) ReloadOnContext(ctx context.Context, signals ...os.Signal) {
+	c.handleSignals(ctx, ReloadAction, signals...)
+}
+
+// ReloadOnContext defines signal to monitor, until ctx is done or Close is called. On reception of a signal, the default config will be reloaded.
+func ReloadOnContext(ctx context.Context, signals ...os.Signal) {
+	globalConfig.ReloadOnContext(ctx, signals...)
+}
+
+// Close stops signal handling (see ReloadOn), periodic tasks (see Every and Poll) and pending debounced reloads,
+// and waits for asynchronous notifications to be delivered (see WithAsyncNotify).
+// The config can still be reloaded explicitly.
+func (c *Config) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		if d := c.debounce; d != nil {
+			d.Lock()
+			if d.pending != nil {
+				d.pending.Stop()
+			}
+			d.Unlock()
+		}
+		if c.async != nil {
+			c.async.close()
 		}
-	}()
+	})
+	return nil
+}
+
+// Close stops signal handling, periodic tasks and pending debounced reloads of the default config.
+func Close() error {
+	return globalConfig.Close()
 }
 
 // ReloadOn defines signal to monitor. On reception of a signal, the default config will be reloaded.
@@ -218,16 +322,26 @@ func ReloadOn(signals ...os.Signal) {
 // Register registers a config structure for a config file section. The values passed will be used as
 // defaults in the future.
 // Defaults will be remembered : if a variable is defined, and then unset, it will be reset to the default value.
+// Zero fields tagged with `default:"..."` are set to the value of the tag, parsed as environment variables are (see WithEnv).
 // If s implements UpdateableConfig, s.Changed() will be called when the config is reloaded and has changed.
-// If config has been previously loaded, s.Changed() will be called immediatly.
-func (c *Config) Register(name string, s interface{}) bool {
-	if uc, ok := s.(UpdatableConfig); ok {
-		c.register(name, s, &reconfigurableCfg{uc})
+// If config has been previously loaded, the section is loaded and s.Changed() will be called immediatly.
+// Other sections are not reloaded, so that each listener is notified exactly once with the initial config,
+// whether it has been registered before or after the config has been loaded.
+// Options (e.g. DependsOn) can be set on the section. Register waits for a running reload to complete, it must not be called by listeners.
+func (c *Config) Register(name string, s interface{}, opts ...SectionOption) bool {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	_, uc := s.(UpdatableConfig)
+	_, ue := s.(UpdatableConfigErr)
+	if uc || ue {
+		c.register(name, s, &reconfigurableCfg{s}, opts...)
 	} else {
-		c.register(name, s, nil)
+		c.register(name, s, nil, opts...)
 	}
-	if c.loaded {
-		c.Reload()
+	if c.loaded.Load() {
+		if err := c.loadSection(name); err != nil {
+			log.Printf("Config: Cannot load section %s: %s", name, err)
+		}
 	}
 	return true
 }
@@ -240,18 +354,28 @@ func (c *Config) Register(name string, s interface{}) bool {
 // 	var (
 // 		_ = config.Register("section_name", &PkgConfig{Value: "default"})
 // 	)
-func Register(name string, s interface{}) bool {
-	return globalConfig.Register(name, s)
+func Register(name string, s interface{}, opts ...SectionOption) bool {
+	return globalConfig.Register(name, s, opts...)
 }
 
-// Reconfigure registers an instance. The config section must have been registered before using Register
+// Reconfigure registers an instance. The config section should have been registered before using Register,
+// otherwise the instance will be notified when the section is registered.
 // r.Reconfigure() will be called when config is reloaded and has changed.
 // If config has been previously loaded, r.Reconfigure() will be called immediatly.
+// Instances can be registered (and unsubscribed) by listeners, as long as the section has been registered.
 func (c *Config) Reconfigure(name string, r Reconfigurable) bool {
-	c.register(name, nil, r)
-	if c.loaded {
+	if s, found := c.lookup(name); found {
+		s.listen(r)
+	} else {
+		c.reloadMu.Lock()
+		c.register(name, nil, r)
+		c.reloadMu.Unlock()
+	}
+	if c.loaded.Load() {
 		if cfg, ok := c.Get(name); ok {
-			r.Reconfigure(cfg)
+			if err := deliver(r, Event{Section: name, Reason: ReasonInitialLoad, Config: cfg}); err != nil {
+				log.Printf("Config: section %s: listener rejected the current config : %s", name, err)
+			}
 		}
 	}
 	return true
@@ -270,8 +394,12 @@ func Reconfigure(name string, r Reconfigurable) bool {
 
 // Get returns the configuration for a section
 func (c *Config) Get(name string) (interface{}, bool) {
-	cfg, ok := c.current[name]
-	return cfg, ok
+	s, ok := c.lookup(name)
+	if !ok {
+		return nil, false
+	}
+	v := s.get()
+	return v, v != nil
 }
 
 // Get returns the configuration for a section
@@ -279,9 +407,37 @@ func Get(name string) (interface{}, bool) {
 	return globalConfig.Get(name)
 }
 
+// Snapshot returns a deep copy of the configuration for a section, sharing no slice, map or pointer with it, so that it can be
+// inspected without locking nor racing with reloads. Sections implementing sync.Locker are locked while being copied.
+func (c *Config) Snapshot(name string) (interface{}, bool) {
+	s, ok := c.lookup(name)
+	if !ok {
+		return nil, false
+	}
+	v := s.get()
+	if v == nil {
+		return nil, false
+	}
+	if reflect.TypeOf(v).Kind() != reflect.Ptr {
+		// Derived sections may be computed as values
+		return v, true
+	}
+	if l, ok := v.(sync.Locker); ok && !s.fresh {
+		l.Lock()
+		defer l.Unlock()
+	}
+	return deepCopy(v), true
+}
+
+// Snapshot returns a deep copy of the configuration for a section of the default config.
+func Snapshot(name string) (interface{}, bool) {
+	return globalConfig.Snapshot(name)
+}
+
 // MustGet returns the configuration for the specified section. If the section does not exist, something will panic.
 func (c *Config) MustGet(name string) interface{} {
-	return c.current[name]
+	s, _ := c.lookup(name)
+	return s.get()
 }
 
 // MustGet returns the configuration for the specified section from the default configuration. If the section does not exist, something will panic.
@@ -290,11 +446,28 @@ func MustGet(name string) interface{} {
 }
 
 type reconfigurableCfg struct {
-	c UpdatableConfig
+	c interface{}
 }
 
 func (r *reconfigurableCfg) Reconfigure(n interface{}) {
-	r.c.Changed()
+	r.changed(Event{Config: n})
+}
+
+func (r *reconfigurableCfg) ReconfigureEvent(ev Event) {
+	r.changed(ev)
+}
+
+// changed notifies the section struct, using ChangedErr or ChangedEvent if implemented.
+func (r *reconfigurableCfg) changed(ev Event) error {
+	switch c := r.c.(type) {
+	case UpdatableConfigErr:
+		return c.ChangedErr()
+	case UpdatableConfigEvent:
+		c.ChangedEvent(ev)
+	case UpdatableConfig:
+		c.Changed()
+	}
+	return nil
 }
 
 func (r *reconfigurableCfg) Lock() {
@@ -309,17 +482,46 @@ func (r *reconfigurableCfg) Unlock() {
 	}
 }
 
-func (c *Config) register(name string, defaults interface{}, r Reconfigurable) {
-	v := reflect.Indirect(reflect.ValueOf(defaults))
+// lookup returns the section named name. Sections are added and removed while holding both c.reloadMu and c.sectionsMu :
+// functions not holding c.reloadMu (e.g. Get) must read them using lookup or registered.
+func (c *Config) lookup(name string) (*section, bool) {
+	c.sectionsMu.RLock()
+	defer c.sectionsMu.RUnlock()
+	s, found := c.sections[name]
+	return s, found
+}
+
+// registered returns a copy of the sections, by name (see lookup).
+func (c *Config) registered() map[string]*section {
+	c.sectionsMu.RLock()
+	defer c.sectionsMu.RUnlock()
+	sections := make(map[string]*section, len(c.sections))
+	for name, s := range c.sections {
+		sections[name] = s
+	}
+	return sections
+}
+
+// register adds a section, or sets its defaults, listener and options. c.reloadMu must be held, as sections are read by reloads.
+func (c *Config) register(name string, defaults interface{}, r Reconfigurable, opts ...SectionOption) {
 	if _, found := c.sections[name]; !found {
+		c.sectionsMu.Lock()
 		c.sections[name] = &section{
-			defaults: reflect.New(v.Type()),
+			name:     name,
 			onchange: []Reconfigurable{},
 		}
+		c.sectionsMu.Unlock()
 	}
 	if defaults != nil {
+		if errs := applyDefaultTags(name, reflect.ValueOf(defaults), c.decoding); len(errs) > 0 {
+			log.Printf("Config: Cannot set default values of section %s: %s", name, errs)
+		}
+		v := reflect.Indirect(reflect.ValueOf(defaults))
+		if !c.sections[name].defaults.IsValid() {
+			c.sections[name].defaults = reflect.New(v.Type())
+		}
 		d := c.sections[name].defaults
-		switch d.Type().Kind() {
+		switch d.Elem().Kind() {
 		case reflect.Struct:
 			addStructDefaults(d, v)
 		case reflect.Map:
@@ -327,100 +529,175 @@ func (c *Config) register(name string, defaults interface{}, r Reconfigurable) {
 		default:
 		}
 		if c.sections[name].current == nil {
-			c.sections[name].current = defaults
-			c.current[name] = defaults
+			c.sections[name].set(defaults)
 		}
 	}
 	if r != nil {
-		c.sections[name].onchange = append(c.sections[name].onchange, r)
-	}
-}
-
-func (c *Config) load() error {
-	if c.loader == nil {
-		return ErrNoLoader
+		c.sections[name].listen(r)
 	}
-	for _, section := range c.sections {
-		if l, ok := section.current.(sync.Locker); ok {
-			l.Lock()
-			defer l.Unlock()
-		}
+	if c.copyOnWrite {
+		c.sections[name].fresh = true
 	}
-	err := c.loader.Load(c.current)
-	if err != nil {
-		return err
+	for _, opt := range opts {
+		opt(c.sections[name])
 	}
-	for _, section := range c.sections {
-		section.change()
+	if t := c.sections[name].throttle; t != nil {
+		t.clock = c.clock
+		t.reload = &c.reloadMu
 	}
-	return err
 }
 
-func (s *section) change() {
+// change notifies listeners if the section has changed since the last call, and returns true if it did.
+// Notifications may be delayed if the section is throttled.
+// The first notification of a section always has the ReasonInitialLoad reason.
+// Listeners are notified using async, if set (see WithAsyncNotify).
+// Synchronous notifications can be rejected by listeners : the section is then rolled back and change returns false and a ListenerError.
+func (s *section) change(reason Reason, async *asyncNotifier) (bool, error) {
+	if s.current == nil {
+		// Listeners registered before the section itself
+		return false, nil
+	}
 	sig, err := json.Marshal(s.current)
 	if err != nil || string(sig) != s.signature {
-		for _, r := range s.onchange {
-			r.Reconfigure(s.current)
+		if s.signature == "" {
+			reason = ReasonInitialLoad
 		}
+		prev, last := s.signature, s.accepted
 		s.signature = string(sig)
+		if async == nil && s.throttle == nil {
+			return s.notifySync(Event{Section: s.name, Reason: reason, Config: s.current, Previous: last}, prev)
+		}
+		s.accepted = deepCopy(s.current)
+		notify := func() {
+			ev := Event{Section: s.name, Reason: reason, Config: s.current, Previous: last}
+			deliverAll := func() {
+				if _, err := s.reconfigure(ev); err != nil {
+					log.Printf("Config: section %s: a listener rejected the new config, which cannot be rolled back when notifications are delayed : %s", s.name, err)
+				}
+			}
+			if async != nil {
+				async.submit(s.name, deliverAll)
+			} else {
+				deliverAll()
+			}
+		}
+		if s.throttle == nil || s.throttle.allow(notify) {
+			notify()
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// reconfigure notifies listeners in registration order, until one of them rejects ev.
+// It returns the number of listeners that accepted ev, and the error of the listener that rejected it.
+func (s *section) reconfigure(ev Event) (int, error) {
+	return notifyAll(s.listeners(), ev)
+}
+
+// notifyAll notifies listeners of ev, in order, until one of them rejects it (see reconfigure).
+func notifyAll(listeners []Reconfigurable, ev Event) (int, error) {
+	for i, r := range listeners {
+		if err := deliver(r, ev); err != nil {
+			return i, err
+		}
+	}
+	return len(listeners), nil
+}
+
+// listen adds r to the listeners of s.
+func (s *section) listen(r Reconfigurable) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onchange = append(s.onchange, r)
+}
+
+// listeners returns the current listeners of s. Listeners added or removed while they are notified are not affected.
+func (s *section) listeners() []Reconfigurable {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.onchange
+}
+
+// unlisten removes the listeners of s matching match, and returns the number of listeners removed.
+// The slice is copied, so that notifications in progress are not affected.
+func (s *section) unlisten(match func(Reconfigurable) bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := make([]Reconfigurable, 0, len(s.onchange))
+	for _, r := range s.onchange {
+		if !match(r) {
+			kept = append(kept, r)
+		}
 	}
+	removed := len(s.onchange) - len(kept)
+	s.onchange = kept
+	return removed
 }
 
+// addMapDefaults adds the entries of from missing in to. Entries of maps of structs present in both are merged field by field
+// (see addStructDefaults).
 func addMapDefaults(to, from reflect.Value) {
 	to = reflect.Indirect(to)
 	from = reflect.Indirect(from)
+	if to.IsNil() {
+		to.Set(reflect.MakeMap(to.Type()))
+	}
 	for _, key := range from.MapKeys() {
 		f := to.MapIndex(key)
-		if reflect.DeepEqual(f.Interface(), reflect.Zero(f.Type()).Interface()) {
-			if !f.CanSet() {
-				log.Printf("Config: Cannot set default value for key %s of %s", key, f.Type().Name())
-				continue
-			}
-			docopy(f, from.MapIndex(key))
+		e := reflect.New(to.Type().Elem()).Elem()
+		switch {
+		case !f.IsValid() || f.IsZero():
+			copyValue(e, from.MapIndex(key))
+		case isNestedStruct(to.Type().Elem()) && !(f.Kind() == reflect.Ptr && from.MapIndex(key).IsNil()):
+			// Map values are not addressable : entries are merged into a copy
+			copyValue(e, f)
+			addStructDefaults(e, from.MapIndex(key))
+		default:
+			continue
 		}
+		to.SetMapIndex(key, e)
 	}
 }
 
+// addStructDefaults sets the zero exported fields of to to the ones of from. Nested structs, embedded ones included, and non-nil pointers
+// to structs are merged recursively, so that defaults registered by several packages for different nested fields are all kept.
 func addStructDefaults(to, from reflect.Value) {
 	to = reflect.Indirect(to)
 	from = reflect.Indirect(from)
 	for i := 0; i < to.NumField(); i++ {
 		f := to.Field(i)
-		if to.Type().Field(i).PkgPath != "" {
+		ft := to.Type().Field(i)
+		if (ft.PkgPath != "" && !ft.Anonymous) || isLock(ft.Type) {
+			continue
+		}
+		if isNestedStruct(ft.Type) && (ft.Type.Kind() == reflect.Struct || (!f.IsNil() && !from.Field(i).IsNil())) {
+			addStructDefaults(f, from.Field(i))
+			continue
+		}
+		if ft.Type.Kind() == reflect.Map && isNestedStruct(ft.Type.Elem()) && !f.IsNil() && !from.Field(i).IsNil() {
+			addMapDefaults(f, from.Field(i))
+			continue
+		}
+		if ft.PkgPath != "" {
+			// Unexported embedded non-struct types
 			continue
 		}
-		if reflect.DeepEqual(f.Interface(), reflect.Zero(f.Type()).Interface()) {
+		if f.IsZero() {
 			if !f.CanSet() {
-				log.Printf("Config: Cannot set default value for field %s of %s", f.Type().Field(i).Name, f.Type().Name())
+				log.Printf("Config: Cannot set default value for field %s of %s", ft.Name, to.Type().Name())
 				continue
 			}
-			docopy(f, from.Field(i))
-			//f.Set(from.Field(i))
+			copyValue(f, from.Field(i))
 		}
 	}
 }
 
-func docopy(to, from reflect.Value) {
-	to = reflect.Indirect(to)
-	from = reflect.Indirect(from)
-	if to.Type() == from.Type() {
-		switch to.Type().Kind() {
-		case reflect.Struct:
-			for i := 0; i < to.NumField(); i++ {
-				docopy(to.Field(i), from.Field(i))
-			}
-		case reflect.Slice:
-			if to.Cap() > from.Len() {
-				to.SetLen(from.Len())
-			} else {
-				to.Set(reflect.MakeSlice(to.Type(), from.Len(), from.Len()))
-			}
-			for i := 0; i < from.Len(); i++ {
-				to.Index(i).Set(from.Index(i))
-			}
-		default:
-			to.Set(from)
-		}
-
+// isNestedStruct returns true for struct types (or pointers to struct types) holding config fields,
+// as opposed to values decoded from strings (e.g. time.Time).
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
 	}
+	return t.Kind() == reflect.Struct && !decode.IsText(t)
 }
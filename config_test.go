@@ -1,18 +1,40 @@
 package autoconfig
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
 	"io/ioutil"
+	"log"
+	"math"
+	"net"
+	"net/url"
 	"os"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/jfbus/autoconfig/decode"
 	"github.com/jfbus/autoconfig/ini"
+	"github.com/jfbus/autoconfig/jsonc"
+	"github.com/jfbus/autoconfig/msgpack"
 	"github.com/jfbus/autoconfig/yaml"
+	vmsgpack "github.com/vmihailenco/msgpack/v5"
 )
 
 type testCfg struct {
-	Key     string `ini:"key" yaml:"key"`
-	None    string `ini:"none" yaml:"none"`
+	Key     string `ini:"key" yaml:"key" json:"key"`
+	None    string `ini:"none" yaml:"none" json:"none"`
 	changed int
 }
 
@@ -148,6 +170,18 @@ func (l *iniLoader) loader(raw string) (Loader, error) {
 	return ini.New(l.f.Name()), nil
 }
 
+type jsoncLoader struct {
+	testLoader
+}
+
+func (l *jsoncLoader) loader(raw string) (Loader, error) {
+	err := l.write(raw)
+	if err != nil {
+		return nil, err
+	}
+	return jsonc.New(l.f.Name()), nil
+}
+
 type yamlLoader struct {
 	testLoader
 }
@@ -255,6 +289,20 @@ key=bar
 			afterLoad:   &testSliceCfg{changed: 1},
 			afterUpdate: &testSliceCfg{Key: []string{"foo", "bar"}, changed: 2},
 		},
+		testCase{
+			name: "jsonc",
+			raw: `{
+  // comment
+  "section": {
+    "key": "foo", /* trailing comma */
+  },
+}`,
+			rawUpdated:  `{"section": {"key": "bar"}}`,
+			loader:      &jsoncLoader{},
+			defaults:    func() changeCounter { return &testCfg{None: "foobar"} },
+			afterLoad:   &testCfg{Key: "foo", None: "foobar", changed: 1},
+			afterUpdate: &testCfg{Key: "bar", None: "foobar", changed: 2},
+		},
 		testCase{
 			name: "yaml map",
 			raw: `section:
@@ -373,3 +421,2919 @@ func TestNoLoader(t *testing.T) {
 		t.Errorf("When no loader is defined, Load should return <%s>, got <%s>", ErrNoLoader, err)
 	}
 }
+
+type fakeTimer struct {
+	stopped bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.stopped = true
+	return true
+}
+
+// fakeClock only moves forward when Advance is called.
+type fakeClock struct {
+	sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at    time.Time
+	fn    func()
+	timer *fakeTimer
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.AfterFunc(d, func() { ch <- c.Now() })
+	return ch
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.Lock()
+	defer c.Unlock()
+	t := &fakeTimer{}
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), fn: f, timer: t})
+	return t
+}
+
+// Advance moves the clock forward and runs expired timers.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.Lock()
+	c.now = c.now.Add(d)
+	expired := []fakeWaiter{}
+	pending := []fakeWaiter{}
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+		} else {
+			expired = append(expired, w)
+		}
+	}
+	c.waiters = pending
+	c.Unlock()
+	for _, w := range expired {
+		if !w.timer.stopped {
+			w.fn()
+		}
+	}
+}
+
+// waitTimers waits until n timers are pending on the clock.
+func (c *fakeClock) waitTimers(n int) {
+	for i := 0; i < 1000; i++ {
+		c.Lock()
+		l := len(c.waiters)
+		c.Unlock()
+		if l >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type fixedRand int64
+
+func (r fixedRand) Int63n(n int64) int64 {
+	if int64(r) >= n {
+		return n - 1
+	}
+	return int64(r)
+}
+
+func TestEvery(t *testing.T) {
+	clock := newFakeClock()
+	cfg := New(nil, WithClock(clock), WithRand(fixedRand(5*time.Second)), WithJitter(0.5))
+	calls := make(chan struct{}, 10)
+	stop := cfg.Every(time.Minute, func() { calls <- struct{}{} })
+	defer stop()
+	clock.waitTimers(1)
+	clock.Advance(time.Minute)
+	select {
+	case <-calls:
+		t.Error("Every should wait for interval + jitter")
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(5 * time.Second)
+	select {
+	case <-calls:
+	case <-time.After(time.Second):
+		t.Error("Every should call fn after interval + jitter")
+	}
+}
+
+func TestTriggerReload(t *testing.T) {
+	tc := testCases[0]
+	l, err := tc.loader.loader(tc.raw)
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer tc.loader.clean()
+	cfg := New(l)
+	cfg.Register("section", tc.defaults())
+	cfg.Register("other", &testCfg{})
+	res := cfg.TriggerReload("test")
+	if res.Err != nil {
+		t.Errorf("TriggerReload returned %s", res.Err)
+	}
+	if res.Reason != "test" || !reflect.DeepEqual(res.Changed, []string{"other", "section"}) || res.Unchanged != nil {
+		t.Errorf("On first load, expected all sections to have changed, got <%#v>", res)
+	}
+	tc.loader.update(tc.rawUpdated)
+	res = cfg.TriggerReload("test")
+	if !reflect.DeepEqual(res.Changed, []string{"section"}) || !reflect.DeepEqual(res.Unchanged, []string{"other"}) {
+		t.Errorf("On reload, expected only section to have changed, got <%#v>", res)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	tc := testCases[1]
+	l, err := tc.loader.loader(tc.raw)
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer tc.loader.clean()
+	cfg := New(l)
+	cfg.Register("section", tc.defaults())
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := cfg.Export(buf); err != nil {
+		t.Fatalf("Export() returned %s", err)
+	}
+
+	replay := New(nil)
+	scfg := tc.defaults()
+	replay.Register("section", scfg)
+	if err := replay.Import(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Import() returned %s", err)
+	}
+	if !reflect.DeepEqual(scfg, tc.afterLoad) {
+		t.Errorf("When importing a snapshot, expected <%#v>, got <%#v>", tc.afterLoad, scfg)
+	}
+
+	wrongType := New(nil)
+	wrongType.Register("section", &testDeepCfg{})
+	if err := wrongType.Import(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("When importing a snapshot into a section of a different type, Import() should fail")
+	}
+}
+
+type testLimits struct {
+	Max int
+}
+
+func TestRegisterDerived(t *testing.T) {
+	tc := testCases[1]
+	l, err := tc.loader.loader(tc.raw)
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer tc.loader.clean()
+	cfg := New(l)
+	cfg.Register("section", tc.defaults())
+	calls := 0
+	cfg.RegisterDerived("derived", []string{"section"}, func(deps ...interface{}) interface{} {
+		calls++
+		return &testLimits{Max: len(deps[0].(*testCfg).Key)}
+	})
+	i := &testClass{}
+	cfg.Reconfigure("derived", i)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if d, _ := cfg.Get("derived"); !reflect.DeepEqual(d, &testLimits{Max: 3}) {
+		t.Errorf("Expected derived section to be computed on load, got <%#v>", d)
+	}
+	cfg.Reload()
+	if calls != 1 || i.changed != 1 {
+		t.Errorf("Derived section should not be recomputed when dependencies are unchanged, got %d calls, %d notifications", calls, i.changed)
+	}
+	tc.loader.update(`section:
+  key: foobar
+`)
+	res := cfg.TriggerReload("test")
+	if d, _ := cfg.Get("derived"); !reflect.DeepEqual(d, &testLimits{Max: 6}) || i.changed != 2 {
+		t.Errorf("Expected derived section to be recomputed and notified on change, got <%#v>", d)
+	}
+	if !reflect.DeepEqual(res.Changed, []string{"derived", "section"}) {
+		t.Errorf("Expected derived section to be reported as changed, got <%#v>", res.Changed)
+	}
+}
+
+type orderRecorder struct {
+	name  string
+	order *[]string
+}
+
+func (o *orderRecorder) Reconfigure(interface{}) {
+	*o.order = append(*o.order, o.name)
+}
+
+func TestDependsOn(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	order := []string{}
+	cfg.Register("a", &testCfg{}, DependsOn("c"))
+	cfg.Register("b", &testCfg{})
+	cfg.Register("c", &testCfg{}, DependsOn("b"))
+	for _, name := range []string{"a", "b", "c"} {
+		cfg.Reconfigure(name, &orderRecorder{name: name, order: &order})
+	}
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if !reflect.DeepEqual(order, []string{"b", "c", "a"}) {
+		t.Errorf("Expected dependencies to be notified first, got %v", order)
+	}
+}
+
+// memLoader is a yaml loader whose document can be updated any number of times.
+type memLoader struct {
+	mu  sync.Mutex
+	raw string
+}
+
+func (l *memLoader) Load(cfg map[string]interface{}) error {
+	l.mu.Lock()
+	raw := l.raw
+	l.mu.Unlock()
+	return yaml.NewFromBytes([]byte(raw)).Load(cfg)
+}
+
+func (l *memLoader) update(raw string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.raw = raw
+}
+
+func TestThrottle(t *testing.T) {
+	ml := &memLoader{raw: "section:\n  key: foo\n"}
+	clock := newFakeClock()
+	cfg := New(ml, WithClock(clock))
+	cfg.Register("section", &testCfg{}, Throttle(1, time.Second))
+	i := &testClass{}
+	cfg.Reconfigure("section", i)
+	cfg.Load()
+	for _, v := range []string{"bar", "baz"} {
+		ml.update("section:\n  key: " + v + "\n")
+		cfg.Reload()
+	}
+	if i.changeCount() != 1 {
+		t.Errorf("Expected a single notification during the throttling interval, got %d", i.changeCount())
+	}
+	clock.Advance(time.Second)
+	if i.changeCount() != 2 || i.cfg.Key != "baz" {
+		t.Errorf("Expected a trailing notification with the latest value, got %d notifications with <%#v>", i.changeCount(), i.cfg)
+	}
+}
+
+type testEnumCfg struct {
+	Mode  string   `yaml:"mode" enum:"fast,slow"`
+	Modes []string `yaml:"modes" enum:"fast,slow"`
+}
+
+func TestEnum(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader(`section:
+  mode: FAST
+  modes: [slow, Fast]
+`)
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	scfg := &testEnumCfg{}
+	cfg.Register("section", scfg)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	expected := &testEnumCfg{Mode: "fast", Modes: []string{"slow", "fast"}}
+	if !reflect.DeepEqual(scfg, expected) {
+		t.Errorf("Expected enum values to be normalized to <%#v>, got <%#v>", expected, scfg)
+	}
+	yl.update(`section:
+  mode: fsat
+`)
+	err = cfg.Reload()
+	if errs, ok := err.(Errors); !ok || len(errs) != 1 || errs[0].(*EnumError).Field != "section.Mode" {
+		t.Errorf("Expected an enum error for section.Mode, got %v", err)
+	}
+}
+
+func TestLint(t *testing.T) {
+	tc := testCases[0]
+	l, err := tc.loader.loader(tc.raw)
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer tc.loader.clean()
+	cfg := New(l)
+	scfg := tc.defaults()
+	cfg.Register("section", scfg)
+	cfg.AddLintRule("no-foo", LintWarning, func(doc map[string]interface{}) []error {
+		if s, ok := doc["section"].(map[string]interface{}); ok && s["key"] == "foo" {
+			return []error{errors.New("key should not be foo")}
+		}
+		return nil
+	})
+	cfg.AddLintRule("has-key", LintError, func(doc map[string]interface{}) []error {
+		if s, ok := doc["section"].(map[string]interface{}); !ok || s["key"] == nil {
+			return []error{errors.New("key is missing")}
+		}
+		return nil
+	})
+	res := cfg.TriggerReload("test")
+	if res.Err != nil || len(res.Warnings) != 1 || res.Warnings[0].(*LintProblem).Rule != "no-foo" {
+		t.Errorf("Expected a lint warning, got <%#v>", res)
+	}
+	if !reflect.DeepEqual(scfg, tc.afterLoad) {
+		t.Errorf("Lint warnings should not prevent loading, expected <%#v>, got <%#v>", tc.afterLoad, scfg)
+	}
+	tc.loader.update("[section]\n")
+	if err := cfg.Reload(); err == nil {
+		t.Errorf("Expected a lint error")
+	}
+	if !reflect.DeepEqual(scfg, tc.afterLoad) {
+		t.Errorf("Lint errors should prevent loading, expected <%#v>, got <%#v>", tc.afterLoad, scfg)
+	}
+}
+
+func TestLoadFromBytes(t *testing.T) {
+	for _, l := range []Loader{ini.NewFromBytes([]byte(iniRaw)), yaml.NewFromReader(bytes.NewBufferString("section:\n  key: foo\n"))} {
+		cfg := New(l)
+		scfg := &testCfg{None: "foobar"}
+		cfg.Register("section", scfg)
+		if err := cfg.Load(); err != nil {
+			t.Errorf("Load() returned %s", err)
+		}
+		if err := cfg.Reload(); err != nil {
+			t.Errorf("Reload() returned %s", err)
+		}
+		expected := &testCfg{Key: "foo", None: "foobar", changed: 1}
+		if !reflect.DeepEqual(scfg, expected) {
+			t.Errorf("When loading from bytes, expected <%#v>, got <%#v>", expected, scfg)
+		}
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{"config.yaml": &fstest.MapFile{Data: []byte("section:\n  key: foo\n")}}
+	cfg := New(yaml.NewFS(fsys, "config.yaml"))
+	scfg := &testCfg{None: "foobar"}
+	cfg.Register("section", scfg)
+	if err := cfg.Load(); err != nil {
+		t.Errorf("Load() returned %s", err)
+	}
+	expected := &testCfg{Key: "foo", None: "foobar", changed: 1}
+	if !reflect.DeepEqual(scfg, expected) {
+		t.Errorf("When loading from a fs.FS, expected <%#v>, got <%#v>", expected, scfg)
+	}
+}
+
+func TestSetSectionFromJSON(t *testing.T) {
+	cfg := New(nil)
+	scfg := &testCfg{Key: "foo"}
+	cfg.Register("section", scfg)
+	if err := cfg.SetSectionFromJSON("section", []byte(`{"key": "bar"}`)); err != nil {
+		t.Fatalf("SetSectionFromJSON returned %s", err)
+	}
+	expected := &testCfg{Key: "bar", changed: 1}
+	if !reflect.DeepEqual(scfg, expected) {
+		t.Errorf("Expected <%#v>, got <%#v>", expected, scfg)
+	}
+	if err := cfg.SetSectionFromJSON("section", []byte(`{"key": {"a": 1}}`)); err == nil {
+		t.Error("SetSectionFromJSON should fail on invalid data")
+	}
+	if err := cfg.SetSectionFromJSON("section", []byte(`{"key": "baz", "Unknown": 1}`)); err == nil || !strings.Contains(err.Error(), "unknown key Unknown") {
+		t.Errorf("SetSectionFromJSON should fail on unknown keys, got %v", err)
+	}
+	if err := cfg.SetSectionFromJSON("unknown", []byte(`{}`)); err == nil {
+		t.Error("SetSectionFromJSON should fail on unknown sections")
+	}
+	if !reflect.DeepEqual(scfg, expected) {
+		t.Errorf("Section should be left untouched on errors, expected <%#v>, got <%#v>", expected, scfg)
+	}
+
+	cfg = New(nil, WithKeyNaming(decode.SnakeCase))
+	ccfg := &testCodecCfg{}
+	cfg.Register("section", ccfg)
+	if err := cfg.SetSectionFromJSON("section", []byte(`{"pool_size": 4, "timeout": "1s", "max_conns": 2}`)); err != nil {
+		t.Fatalf("SetSectionFromJSON returned %s", err)
+	}
+	if ccfg.PoolSize != 4 || ccfg.Timeout != time.Second || ccfg.MaxConns != 2 {
+		t.Errorf("SetSectionFromJSON should match keys as loaders do, got %#v", ccfg)
+	}
+}
+
+func TestShadow(t *testing.T) {
+	drifts := map[string]string{}
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  key: foo\nother:\n  key: foo\n")), WithShadow(
+		yaml.NewFromBytes([]byte("section:\n  key: bar\nother:\n  key: foo\n")),
+		func(section string, authoritative, shadow interface{}) {
+			drifts[section] = shadow.(*testCfg).Key
+		},
+	))
+	scfg := &testCfg{}
+	cfg.Register("section", scfg)
+	cfg.Register("other", &testCfg{})
+	res := cfg.TriggerReload("test")
+	if !reflect.DeepEqual(res.Drifted, []string{"section"}) || !reflect.DeepEqual(drifts, map[string]string{"section": "bar"}) {
+		t.Errorf("Expected section to have drifted, got %v / %v", res.Drifted, drifts)
+	}
+	if scfg.Key != "foo" {
+		t.Errorf("Shadow values should not be applied, got %s", scfg.Key)
+	}
+}
+
+type testEventClass struct {
+	testClass
+	reasons []Reason
+}
+
+func (t *testEventClass) ReconfigureEvent(ev Event) {
+	t.reasons = append(t.reasons, ev.Reason)
+	t.Reconfigure(ev.Config)
+}
+
+func TestReconfigureEvent(t *testing.T) {
+	tc := testCases[0]
+	l, err := tc.loader.loader(tc.raw)
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer tc.loader.clean()
+	cfg := New(l)
+	cfg.Register("section", tc.defaults())
+	i := &testEventClass{}
+	cfg.Reconfigure("section", i)
+	cfg.Load()
+	tc.loader.update(tc.rawUpdated)
+	cfg.TriggerReload(string(ReasonSignal))
+	if !reflect.DeepEqual(i.reasons, []Reason{ReasonInitialLoad, ReasonSignal}) {
+		t.Errorf("Expected reasons to be passed to listeners, got %v", i.reasons)
+	}
+}
+
+func TestSingleInitialNotification(t *testing.T) {
+	tc := testCases[0]
+	l, err := tc.loader.loader(tc.raw + "[other]\nkey=foo\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer tc.loader.clean()
+	cfg := New(l)
+	other := &testCfg{}
+	cfg.Register("other", other)
+	early := &testClass{}
+	cfg.Reconfigure("section", early)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	tc.loader.update(tc.rawUpdated + "[other]\nkey=bar\n")
+	scfg := tc.defaults()
+	cfg.Register("section", scfg)
+	late := &testClass{}
+	cfg.Reconfigure("section", late)
+	if early.changeCount() != 1 || late.changeCount() != 1 || scfg.changeCount() != 1 {
+		t.Errorf("Expected a single initial notification, got %d/%d/%d", early.changeCount(), late.changeCount(), scfg.changeCount())
+	}
+	if other.changeCount() != 1 || other.Key != "foo" {
+		t.Errorf("Registering a section should not reload other sections, got <%#v>", other)
+	}
+}
+
+func TestLoadMsgpack(t *testing.T) {
+	data, err := vmsgpack.Marshal(map[string]interface{}{"section": map[string]string{"Key": "foo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := New(msgpack.NewFromBytes(data))
+	scfg := &testCfg{None: "foobar"}
+	cfg.Register("section", scfg)
+	if err := cfg.Load(); err != nil {
+		t.Errorf("Load() returned %s", err)
+	}
+	expected := &testCfg{Key: "foo", None: "foobar", changed: 1}
+	if !reflect.DeepEqual(scfg, expected) {
+		t.Errorf("When loading msgpack data, expected <%#v>, got <%#v>", expected, scfg)
+	}
+}
+
+func TestFresh(t *testing.T) {
+	tc := testCases[1]
+	l, err := tc.loader.loader(tc.raw)
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer tc.loader.clean()
+	cfg := New(l)
+	registered := &testCfg{None: "foobar"}
+	cfg.Register("section", registered, Fresh())
+	i := &testClass{}
+	cfg.Reconfigure("section", i)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if registered.Key != "" || registered.None != "foobar" {
+		t.Errorf("Fresh sections should never be modified, got <%#v>", registered)
+	}
+	expected := &testCfg{Key: "foo", None: "foobar"}
+	if got, _ := cfg.Get("section"); !reflect.DeepEqual(got, expected) || got != i.cfg {
+		t.Errorf("Expected Get and listeners to return the new instance <%#v>, got <%#v> and <%#v>", expected, got, i.cfg)
+	}
+	tc.loader.update(tc.rawUpdated)
+	cfg.Reload()
+	if got, _ := cfg.Get("section"); got.(*testCfg).Key != "bar" || got == expected {
+		t.Errorf("Expected a new instance on reload, got <%#v>", got)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	tc := testCases[0]
+	l, err := tc.loader.loader(tc.raw)
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer tc.loader.clean()
+	dir, err := ioutil.TempDir("", "autoconfig_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := FileOverrideStore(dir + "/overrides.json")
+
+	cfg := New(l, WithOverrideStore(store))
+	scfg := &testCfg{}
+	cfg.Register("section", scfg)
+	cfg.Load()
+	if err := cfg.SetSectionFromJSON("section", []byte(`{"key": "override"}`)); err != nil {
+		t.Fatalf("SetSectionFromJSON returned %s", err)
+	}
+	cfg.Reload()
+	if scfg.Key != "override" {
+		t.Errorf("Overrides should be applied after reloads, got %s", scfg.Key)
+	}
+
+	restarted := New(l, WithOverrideStore(store))
+	rcfg := &testCfg{}
+	restarted.Register("section", rcfg)
+	restarted.Load()
+	if rcfg.Key != "override" {
+		t.Errorf("Persisted overrides should be applied after restarts, got %s", rcfg.Key)
+	}
+	if err := restarted.ClearOverride("section"); err != nil {
+		t.Fatalf("ClearOverride returned %s", err)
+	}
+	if rcfg.Key != "foo" || len(restarted.Overrides()) != 0 {
+		t.Errorf("Cleared overrides should not be applied, got %s", rcfg.Key)
+	}
+}
+
+func TestWithApp(t *testing.T) {
+	cfg := New(yaml.NewFromBytes([]byte(`section:
+  key: foo
+  none: shared
+apps:
+  api:
+    section:
+      key: bar
+`)), WithApp("api"))
+	scfg := &testCfg{}
+	cfg.Register("section", scfg)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if scfg.Key != "bar" || scfg.None != "shared" {
+		t.Errorf("Expected application section to overlay the shared one, got <%#v>", scfg)
+	}
+}
+
+func TestFrozen(t *testing.T) {
+	tc := testCases[0]
+	l, err := tc.loader.loader(tc.raw + "[other]\nkey=foo\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer tc.loader.clean()
+	cfg := New(l)
+	frozen := &testCfg{}
+	cfg.Register("section", frozen, Frozen())
+	other := &testCfg{}
+	cfg.Register("other", other)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	tc.loader.update(tc.rawUpdated + "[other]\nkey=bar\n")
+	err = cfg.Reload()
+	if errs, ok := err.(Errors); !ok || len(errs) != 1 || errs[0].(*FrozenError).Section != "section" {
+		t.Errorf("Expected a FrozenError, got %v", err)
+	}
+	if frozen.Key != "foo" || frozen.changeCount() != 1 {
+		t.Errorf("Frozen section should not change, got <%#v>", frozen)
+	}
+	if other.Key != "bar" {
+		t.Errorf("Other sections should be updated, got <%#v>", other)
+	}
+}
+
+type panicLoader struct{}
+
+func (panicLoader) Load(map[string]interface{}) error {
+	panic("malformed document")
+}
+
+func TestLoaderPanic(t *testing.T) {
+	cfg := New(panicLoader{})
+	c := &testCfg{Key: "default"}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err == nil {
+		t.Error("Load() should return an error when the loader panics")
+	}
+	if c.Key != "default" {
+		t.Errorf("Section should not change, got <%#v>", c)
+	}
+}
+
+func TestLoadMalformed(t *testing.T) {
+	cfg := New(ini.NewFromBytes([]byte("[map]\nkey=1\n")))
+	cfg.Register("map", &testCfgMap{})
+	if err := cfg.Load(); err == nil {
+		t.Error("Load() should return an error for sections the INI loader cannot map to")
+	}
+	cfg = New(msgpack.NewFromBytes([]byte("\x81\xa7section\x81\xc6\xc6key")))
+	cfg.Register("section", &testCfg{})
+	if err := cfg.Load(); err == nil {
+		t.Error("Load() should return an error for truncated MessagePack")
+	}
+}
+
+type testExampleCfg struct {
+	Listen string   `yaml:"listen" example:"0.0.0.0:8080"`
+	Peers  []string `yaml:"peers" example:"[\"10.0.0.1\"]"`
+	Port   int      `yaml:"port" example:"8080"`
+	Name   string   `yaml:"name"`
+	Hidden string   `yaml:"-"`
+}
+
+func TestSample(t *testing.T) {
+	cfg := New(nil)
+	cfg.Register("server", &testExampleCfg{Listen: "localhost:80", Name: "default"})
+	expected := map[string]interface{}{
+		"server": map[string]interface{}{
+			"listen": "0.0.0.0:8080",
+			"peers":  []string{"10.0.0.1"},
+			"port":   8080,
+			"name":   "default",
+		},
+	}
+	if sample := cfg.Sample("yaml"); !reflect.DeepEqual(sample, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, sample)
+	}
+}
+
+func TestMulti(t *testing.T) {
+	cfg := New(Multi(
+		yaml.NewFromBytes([]byte("section:\n  key: default\n  none: default\n")),
+		yaml.New("/nonexistent/autoconfig_test.yaml"),
+		yaml.NewFromBytes([]byte("section:\n  key: user\n")),
+	))
+	c := &testCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "user" || c.None != "default" {
+		t.Errorf("Expected later loaders to override earlier ones, got <%#v>", c)
+	}
+}
+
+func TestDir(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp/", "autoconfig_test_")
+	if err != nil {
+		t.Fatal("Unable to create config temp dir")
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/10-base.yaml", []byte("section:\n  key: base\n  none: base\n"), 0600)
+	ioutil.WriteFile(dir+"/20-local.yaml", []byte("section:\n  key: local\n"), 0600)
+	ioutil.WriteFile(dir+"/README", []byte("not a yaml file"), 0600)
+	cfg := New(Dir(dir+"/*.yaml", func(f string) Loader {
+		return yaml.New(f)
+	}))
+	c := &testCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "local" || c.None != "base" {
+		t.Errorf("Expected files to be merged in lexical order, got <%#v>", c)
+	}
+	ioutil.WriteFile(dir+"/30-override.yaml", []byte("section:\n  none: override\n"), 0600)
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+	if c.None != "override" {
+		t.Errorf("Expected new files to be loaded on reload, got <%#v>", c)
+	}
+}
+
+func TestInclude(t *testing.T) {
+	fsys := fstest.MapFS{
+		"conf/main.yaml":        {Data: []byte("include:\n  - common/base.yaml\nsection:\n  key: main\n")},
+		"conf/common/base.yaml": {Data: []byte("section:\n  key: base\n  none: base\n")},
+		"conf/main.ini":         {Data: []byte("include = common/base.ini\n[section]\nkey=main\n")},
+		"conf/common/base.ini":  {Data: []byte("[section]\nkey=base\nnone=base\n")},
+		"conf/cycle.yaml":       {Data: []byte("include: cycle2.yaml\n")},
+		"conf/cycle2.yaml":      {Data: []byte("include: cycle.yaml\n")},
+	}
+	for _, l := range []Loader{yaml.NewFS(fsys, "conf/main.yaml"), ini.NewFS(fsys, "conf/main.ini")} {
+		cfg := New(l)
+		c := &testCfg{}
+		cfg.Register("section", c)
+		if err := cfg.Load(); err != nil {
+			t.Fatalf("Load() returned %s", err)
+		}
+		if c.Key != "main" || c.None != "base" {
+			t.Errorf("Expected included files to be merged, got <%#v>", c)
+		}
+	}
+	cfg := New(yaml.NewFS(fsys, "conf/cycle.yaml"))
+	cfg.Register("section", &testCfg{})
+	if err := cfg.Load(); err == nil {
+		t.Error("Load() should return an error on include cycles")
+	}
+}
+
+func TestWithProfile(t *testing.T) {
+	raw := "section:\n  key: base\n  none: base\nprofiles:\n  prod:\n    section:\n      key: prod\napps:\n  api:\n    section:\n      none: api\n"
+	cfg := New(yaml.NewFromBytes([]byte(raw)), WithProfile("prod"), WithApp("api"))
+	c := &testCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "prod" || c.None != "api" {
+		t.Errorf("Expected profile then application overlays, got <%#v>", c)
+	}
+	if f := ProfileFilename("/etc/myapp/config.yaml", "prod"); f != "/etc/myapp/config.prod.yaml" {
+		t.Errorf("Expected /etc/myapp/config.prod.yaml, got %s", f)
+	}
+}
+
+type testSecretCfg struct {
+	User     string `json:"user"`
+	Password string `json:"password" secret:"true"`
+	Nested   struct {
+		Token string `secret:"true"`
+	} `json:"nested"`
+}
+
+func TestSecretPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		policy   SecretPolicy
+		password interface{}
+	}{
+		{policy: nil, password: "p4ss"},
+		{policy: StripSecrets, password: nil},
+		{policy: TokenizeSecrets([]byte("key")), password: "secret:d590958fbe385abf"},
+	} {
+		cfg := New(nil, WithSecretPolicy(tc.policy))
+		s := &testSecretCfg{User: "admin", Password: "p4ss"}
+		s.Nested.Token = "t0k"
+		cfg.Register("db", s)
+		buf := &bytes.Buffer{}
+		if err := cfg.Export(buf); err != nil {
+			t.Fatalf("Export() returned %s", err)
+		}
+		snap := snapshot{}
+		json.Unmarshal(buf.Bytes(), &snap)
+		v := map[string]interface{}{}
+		json.Unmarshal(snap.Sections["db"].Value, &v)
+		if v["user"] != "admin" || v["password"] != tc.password {
+			t.Errorf("Expected password %v, got %v", tc.password, v)
+		}
+		if tc.policy != nil && strings.Contains(buf.String(), "t0k") {
+			t.Errorf("Nested secrets should be scrubbed, got %s", buf.String())
+		}
+		redacted, err := cfg.Redacted("db", s)
+		if err != nil {
+			t.Fatalf("Redacted() returned %s", err)
+		}
+		if strings.Contains(string(redacted), "p4ss") || strings.Contains(string(redacted), "t0k") || !strings.Contains(string(redacted), "admin") {
+			t.Errorf("Redacted values should never hold secrets, got %s", redacted)
+		}
+	}
+}
+
+type testEnvCfg struct {
+	Key     string        `yaml:"key"`
+	Size    int           `yaml:"pool_size"`
+	Timeout time.Duration `yaml:"timeout"`
+	Hosts   []string      `yaml:"hosts"`
+	Custom  string        `yaml:"custom" env:"CUSTOM_VAR"`
+	Nested  struct {
+		Enabled bool `yaml:"enabled"`
+	} `yaml:"nested"`
+}
+
+func TestWithEnv(t *testing.T) {
+	for k, v := range map[string]string{
+		"TEST_MY_SECTION_POOL_SIZE":      "50",
+		"TEST_MY_SECTION_TIMEOUT":        "3s",
+		"TEST_MY_SECTION_HOSTS":          "a, b",
+		"CUSTOM_VAR":                     "custom",
+		"TEST_MY_SECTION_NESTED_ENABLED": "true",
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	cfg := New(yaml.NewFromBytes([]byte("my-section:\n  key: file\n  pool_size: 5\n")), WithEnv("TEST"))
+	c := &testEnvCfg{}
+	cfg.Register("my-section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "file" || c.Size != 50 || c.Timeout != 3*time.Second || !reflect.DeepEqual(c.Hosts, []string{"a", "b"}) || c.Custom != "custom" || !c.Nested.Enabled {
+		t.Errorf("Expected environment variables to override the file, got <%#v>", c)
+	}
+	os.Setenv("TEST_MY_SECTION_POOL_SIZE", "many")
+	if err := cfg.Reload(); err == nil {
+		t.Error("Reload() should fail on invalid environment values")
+	}
+}
+
+type failingLoader struct {
+	fail *bool
+}
+
+func (l failingLoader) Load(cfg map[string]interface{}) error {
+	if *l.fail {
+		return errors.New("source unreachable")
+	}
+	if c, ok := cfg["section"].(*testCfg); ok {
+		c.Key = "remote"
+	}
+	return nil
+}
+
+func TestWithDegraded(t *testing.T) {
+	fail := false
+	cfg := New(failingLoader{&fail}, WithDegraded(2, yaml.NewFromBytes([]byte("section:\n  key: degraded\n"))))
+	c := &testEventClass{}
+	cfg.Register("section", &testCfg{})
+	cfg.Reconfigure("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	fail = true
+	if res := cfg.TriggerReload("test"); res.Err == nil || res.Degraded || cfg.Degraded() {
+		t.Errorf("Config should not be degraded after a single failure, got %#v", res)
+	}
+	if res := cfg.TriggerReload("test"); res.Err == nil || !res.Degraded || !cfg.Degraded() {
+		t.Errorf("Config should be degraded after 2 failures, got %#v", res)
+	}
+	if last := c.reasons[len(c.reasons)-1]; last != ReasonDegraded || c.cfg.Key != "degraded" {
+		t.Errorf("Expected a degraded event, got %s <%#v>", last, c.cfg)
+	}
+	fail = false
+	if res := cfg.TriggerReload("test"); res.Err != nil || cfg.Degraded() {
+		t.Errorf("Config should leave degraded mode after a successful reload, got %#v", res)
+	}
+}
+
+func TestWithArgs(t *testing.T) {
+	cfg := New(yaml.NewFromBytes([]byte("my.section:\n  key: file\n  pool_size: 5\n")), WithArgs(
+		"-v", "--my.section.POOL_SIZE=50", "--my.section.nested.enabled", "true", "--other.key=ignored", "--", "--my.section.key=ignored",
+	))
+	c := &testEnvCfg{}
+	cfg.Register("my.section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "file" || c.Size != 50 || !c.Nested.Enabled {
+		t.Errorf("Expected arguments to override the file, got <%#v>", c)
+	}
+	cfg = New(yaml.NewFromBytes(nil), WithArgs("--section.unknown=1"))
+	cfg.Register("section", &testCfg{})
+	if err := cfg.Load(); err == nil {
+		t.Error("Unknown keys of known sections should be errors")
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  key: file\n  pool_size: 5\n")))
+	c := &testEnvCfg{Timeout: time.Second}
+	cfg.Register("section", c)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	cfg.BindFlags(fs)
+	if f := fs.Lookup("section.timeout"); f == nil || f.DefValue != "1s" {
+		t.Fatalf("Expected a section.timeout flag, got %v", f)
+	}
+	if err := fs.Parse([]string{"-section.pool_size=50", "-section.nested.enabled"}); err != nil {
+		t.Fatalf("Parse() returned %s", err)
+	}
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "file" || c.Size != 50 || !c.Nested.Enabled {
+		t.Errorf("Expected flags to override the file, got <%#v>", c)
+	}
+	if err := fs.Parse([]string{"-section.pool_size=many"}); err == nil {
+		t.Error("Parse() should fail on invalid values")
+	}
+}
+
+func TestRegisterWithLoader(t *testing.T) {
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  key: file\ndb:\n  key: file\n")))
+	c := &testCfg{}
+	cfg.Register("section", c)
+	db := &testCfg{}
+	cfg.RegisterWithLoader("db", db, jsonc.NewFromBytes([]byte(`{"db": {"key": "vault"}}`)))
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "file" || db.Key != "vault" {
+		t.Errorf("Expected sections to be loaded by their own loader, got <%#v> <%#v>", c, db)
+	}
+	late := &testCfg{}
+	cfg.RegisterWithLoader("late", late, jsonc.NewFromBytes([]byte(`{"late": {"key": "late"}}`)))
+	if late.Key != "late" {
+		t.Errorf("Expected sections registered after Load to be loaded by their own loader, got <%#v>", late)
+	}
+}
+
+func TestLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp/", "autoconfig_test_")
+	if err != nil {
+		t.Fatal("Unable to create config temp dir")
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(dir+"/config.yaml", []byte("section:\n  key: main\n  none: main\n"), 0600)
+	format := func(f string) Loader {
+		return yaml.New(f)
+	}
+	cfg := New(Local(dir+"/config.yaml", format))
+	c := &testCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() should skip missing local files, returned %s", err)
+	}
+	ioutil.WriteFile(dir+"/config.local.yaml", []byte("section:\n  key: local\n"), 0600)
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+	if c.Key != "local" || c.None != "main" {
+		t.Errorf("Expected the local file to override the main file, got <%#v>", c)
+	}
+	cfg = New(Local(dir+"/missing.yaml", format))
+	cfg.Register("section", &testCfg{})
+	if err := cfg.Load(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Load() should fail when the main file is missing, returned %v", err)
+	}
+}
+
+type testBackend struct {
+	Name string `yaml:"name"`
+	Addr string `yaml:"addr"`
+}
+
+type testMergeCfg struct {
+	Listeners []string          `yaml:"listeners" merge:"append"`
+	Backends  []testBackend     `yaml:"backends" merge:"key=name"`
+	Headers   map[string]string `yaml:"headers" merge:"replace"`
+	Tags      []string          `yaml:"tags"`
+}
+
+func TestMergeStrategies(t *testing.T) {
+	cfg := New(Multi(
+		yaml.NewFromBytes([]byte("section:\n  listeners: [a]\n  backends: [{name: x, addr: old}, {name: w, addr: w}]\n  headers: {h1: v1}\n  tags: [t1]\n")),
+		yaml.NewFromBytes([]byte("section:\n  listeners: [b]\n  backends: [{name: x, addr: new}, {name: z, addr: z}]\n  headers: {h2: v2}\n  tags: [t2]\n")),
+		yaml.NewFromBytes([]byte("section:\n  tags: [t3]\n")),
+	))
+	c := &testMergeCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	expected := &testMergeCfg{
+		Listeners: []string{"a", "b"},
+		Backends:  []testBackend{{"x", "new"}, {"w", "w"}, {"z", "z"}},
+		Headers:   map[string]string{"h2": "v2"},
+		Tags:      []string{"t3"},
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, c)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	os.Setenv("TEST_MIDDLEWARE_HOST", "example.com")
+	defer os.Unsetenv("TEST_MIDDLEWARE_HOST")
+	var calls []string
+	trace := func(name string) LoaderMiddleware {
+		return LoaderFunc(func(next Loader, cfg map[string]interface{}) error {
+			calls = append(calls, name)
+			return next.Load(cfg)
+		})
+	}
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  key: http://${TEST_MIDDLEWARE_HOST}/\n  none: enc:secret\n")),
+		WithMiddleware(trace("outer"), ExpandEnv, trace("inner")),
+		WithMiddleware(TransformStrings(func(s string) (string, error) {
+			return strings.TrimPrefix(s, "enc:"), nil
+		})))
+	c := &testCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "http://example.com/" || c.None != "secret" {
+		t.Errorf("Expected values to be transformed, got <%#v>", c)
+	}
+	if !reflect.DeepEqual(calls, []string{"outer", "inner"}) {
+		t.Errorf("Expected middlewares to be chained in order, got %v", calls)
+	}
+	if _, ok := Chain(yaml.NewFromBytes(nil), ExpandEnv).(RawLoader); !ok {
+		t.Error("Middlewares should keep raw documents available to lint rules")
+	}
+}
+
+func TestMiddlewareProfile(t *testing.T) {
+	raw := "section:\n  key: x\n  none: y\nprofiles:\n  prod:\n    section:\n      none: z\n"
+	cfg := New(yaml.NewFromBytes([]byte(raw)), WithProfile("prod"), WithMiddleware(TransformStrings(func(s string) (string, error) {
+		return s + "!", nil
+	})))
+	c := &testCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "x!" || c.None != "z!" {
+		t.Errorf("Values should be transformed once, after overlays, got <%s> and <%s>", c.Key, c.None)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	fail := true
+	cfg := New(Fallback(failingLoader{&fail}, yaml.NewFromBytes([]byte("section:\n  key: local\n"))))
+	c := &testCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "local" {
+		t.Errorf("Expected fallback value, got <%s>", c.Key)
+	}
+	fail = false
+	cfg.Reload()
+	if c.Key != "remote" {
+		t.Errorf("Expected primary value, got <%s>", c.Key)
+	}
+	cfg = New(Fallback(failingLoader{&fail}, failingLoader{&fail}))
+	cfg.Register("section", &testCfg{})
+	fail = true
+	if err := cfg.Load(); err == nil {
+		t.Error("Load() should fail when both loaders fail")
+	}
+}
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp/", "autoconfig_test_")
+	if err != nil {
+		t.Fatal("Unable to create config temp dir")
+	}
+	defer os.RemoveAll(dir)
+	fail := true
+	cfg := New(failingLoader{&fail}, WithMiddleware(Cache(dir+"/cache.json")))
+	c := &testCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err == nil {
+		t.Error("Load() should fail without a cache file")
+	}
+	fail = false
+	if err := cfg.Reload(); err != nil || c.Key != "remote" {
+		t.Fatalf("Reload() returned %v, value <%s>", err, c.Key)
+	}
+	// Sections registered after the load are added to the cache
+	cfg.Register("other", &testCfg{Key: "late"})
+	fail = true
+	cfg = New(failingLoader{&fail}, WithMiddleware(Cache(dir+"/cache.json")))
+	c = &testCfg{}
+	other := &testCfg{}
+	cfg.Register("section", c)
+	cfg.Register("other", other)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "remote" || other.Key != "late" {
+		t.Errorf("Expected cached values, got <%s> and <%s>", c.Key, other.Key)
+	}
+}
+
+type testNotifyCfg struct {
+	Key string `yaml:"key"`
+	ch  chan string
+}
+
+func (t *testNotifyCfg) Changed() {
+	t.ch <- t.Key
+}
+
+func TestPoll(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp/", "autoconfig_test_")
+	if err != nil {
+		t.Fatal("Unable to create config temp dir")
+	}
+	defer os.RemoveAll(dir)
+	filename := dir + "/config.yaml"
+	ioutil.WriteFile(filename, []byte("section:\n  key: one\n"), 0600)
+	clock := newFakeClock()
+	loads := make(chan struct{}, 10)
+	count := LoaderFunc(func(next Loader, cfg map[string]interface{}) error {
+		loads <- struct{}{}
+		return next.Load(cfg)
+	})
+	cfg := New(yaml.New(filename), WithClock(clock), WithMiddleware(count))
+	c := &testNotifyCfg{ch: make(chan string, 10)}
+	cfg.Register("section", c)
+	cfg.Load()
+	<-c.ch
+	<-loads
+	stop := cfg.Poll(time.Second, filename)
+	defer stop()
+	expect := func(expected string) {
+		clock.waitTimers(1)
+		clock.Advance(time.Second)
+		select {
+		case v := <-c.ch:
+			if v != expected {
+				t.Errorf("Expected <%s>, got <%s>", expected, v)
+			}
+		case <-time.After(50 * time.Millisecond):
+			if expected != "" {
+				t.Errorf("Expected a reload with <%s>", expected)
+			}
+		}
+	}
+	now := time.Now()
+	os.Chtimes(filename, now, now.Add(time.Hour))
+	expect("")
+	if len(loads) > 0 {
+		t.Error("Touching a file should not reload the config")
+	}
+	ioutil.WriteFile(filename, []byte("section:\n  key: two\n"), 0600)
+	expect("two")
+}
+
+func TestWithDebounce(t *testing.T) {
+	clock := newFakeClock()
+	loads := make(chan struct{}, 10)
+	count := LoaderFunc(func(next Loader, cfg map[string]interface{}) error {
+		loads <- struct{}{}
+		return next.Load(cfg)
+	})
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  key: value\n")), WithClock(clock), WithDebounce(time.Second), WithMiddleware(count))
+	cfg.Register("section", &testCfg{})
+	for i := 0; i < 3; i++ {
+		cfg.RequestReload(string(ReasonFileChange))
+		clock.Advance(500 * time.Millisecond)
+	}
+	if len(loads) > 0 {
+		t.Error("Reloads should be delayed until the debounce window has passed")
+	}
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case <-loads:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a reload after the debounce window")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if len(loads) > 0 {
+		t.Error("Reload requests should be coalesced into a single reload")
+	}
+}
+
+func TestClose(t *testing.T) {
+	clock := newFakeClock()
+	cfg := New(nil, WithClock(clock))
+	calls := make(chan struct{}, 10)
+	cfg.Every(time.Minute, func() { calls <- struct{}{} })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg.ReloadOnContext(ctx, os.Interrupt)
+	clock.waitTimers(1)
+	if err := cfg.Close(); err != nil {
+		t.Fatalf("Close() returned %s", err)
+	}
+	cfg.Close()
+	clock.Advance(time.Minute)
+	select {
+	case <-calls:
+		t.Error("Close should stop periodic tasks")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestCheck(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  mode: fast\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	c := &testEnumCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Check(); err != nil {
+		t.Errorf("Check() returned %s", err)
+	}
+	if c.Mode != "" {
+		t.Errorf("Check() should not apply values, got <%#v>", c)
+	}
+	yl.update("section:\n  mode: unknown\n")
+	if err := cfg.Check(); err == nil {
+		t.Error("Check() should return enum errors")
+	}
+}
+
+type testValidatedCfg struct {
+	Key       string `yaml:"key"`
+	committed []string
+}
+
+func (t *testValidatedCfg) ValidateConfig(new interface{}) error {
+	if new.(*testValidatedCfg).Key == "invalid" {
+		return errors.New("invalid key")
+	}
+	return nil
+}
+
+func (t *testValidatedCfg) CommitConfig() {
+	t.committed = append(t.committed, t.Key)
+}
+
+func TestValidateCommit(t *testing.T) {
+	yl := &memLoader{raw: "section:\n  key: one\nother:\n  key: one\n"}
+	cfg := New(yl)
+	c := &testValidatedCfg{}
+	other := &testCfg{}
+	cfg.Register("section", c)
+	cfg.Register("other", other)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	yl.update("section:\n  key: invalid\nother:\n  key: two\n")
+	err := cfg.Reload()
+	var veto *VetoError
+	if !errors.As(err, &veto) || veto.Section != "section" {
+		t.Errorf("Expected a VetoError, got %v", err)
+	}
+	if c.Key != "one" || other.Key != "one" {
+		t.Errorf("A vetoed reload should not change any section, got <%s> and <%s>", c.Key, other.Key)
+	}
+	yl.update("section:\n  key: two\nother:\n  key: two\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+	if c.Key != "two" || other.Key != "two" || !reflect.DeepEqual(c.committed, []string{"one", "two"}) {
+		t.Errorf("Expected new values to be committed, got <%#v> and <%#v>", c, other)
+	}
+}
+
+func TestReloadReport(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  mode: fast\nother:\n  key: one\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	cfg.Register("section", &testEnumCfg{})
+	cfg.Register("other", &testCfg{})
+	if cfg.ReloadReport() != nil {
+		t.Error("ReloadReport() should be nil before the first load")
+	}
+	cfg.Load()
+	yl.update("section:\n  mode: unknown\nother:\n  key: one\n")
+	cfg.Reload()
+	res := cfg.ReloadReport()
+	if res == nil || res.Err == nil {
+		t.Fatalf("Expected the last reload to have failed, got <%v>", res)
+	}
+	var enumErr *EnumError
+	if len(res.Failed) != 1 || !errors.As(res.Failed["section"], &enumErr) {
+		t.Errorf("Expected section to have failed, got <%v>", res.Failed)
+	}
+	if s := res.String(); !strings.Contains(s, "failed: section") {
+		t.Errorf("Expected the summary to list failed sections, got <%s>", s)
+	}
+}
+
+// testDocLoader counts the loads and the document reads of a yaml loader
+type testDocLoader struct {
+	*yaml.Loader
+	loads, reads int
+}
+
+func (l *testDocLoader) Load(cfg map[string]interface{}) error {
+	l.loads++
+	return l.Loader.Load(cfg)
+}
+
+func (l *testDocLoader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	l.loads++
+	return l.Loader.LoadWith(cfg, o)
+}
+
+func (l *testDocLoader) Document() (func(cfg map[string]interface{}, o decode.Options) error, error) {
+	l.reads++
+	return l.Loader.Document()
+}
+
+func TestDecodeIsolation(t *testing.T) {
+	fsys := fstest.MapFS{"config.yaml": {Data: []byte("limits:\n  max: 10\nother:\n  key: one\n")}}
+	l := &testDocLoader{Loader: yaml.NewFS(fsys, "config.yaml")}
+	cfg := New(l)
+	limits := &testLimits{}
+	other := &testCfg{}
+	cfg.Register("limits", limits)
+	cfg.Register("other", other)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	fsys["config.yaml"].Data = []byte("limits:\n  max: many\nother:\n  key: two\n")
+	l.loads, l.reads = 0, 0
+	res := cfg.TriggerReload("test")
+	if l.loads != 0 || l.reads != 1 {
+		t.Errorf("Sections should be decoded from a single read of the document, got %d loads and %d reads", l.loads, l.reads)
+	}
+	var decodeErr *DecodeError
+	if !errors.As(res.Err, &decodeErr) || decodeErr.Section != "limits" || res.Failed["limits"] == nil {
+		t.Errorf("Expected a DecodeError for limits, got %v", res.Err)
+	}
+	if limits.Max != 10 {
+		t.Errorf("A section that cannot be decoded should keep its previous value, got %d", limits.Max)
+	}
+	if other.Key != "two" || !reflect.DeepEqual(res.Changed, []string{"other"}) {
+		t.Errorf("Valid sections should still be updated, got <%s>", other.Key)
+	}
+}
+
+func TestHistoryRollback(t *testing.T) {
+	yl := &memLoader{raw: "section:\n  key: one\n"}
+	cfg := New(yl, WithHistory(2))
+	c := &testCfg{}
+	cfg.Register("section", c)
+	cfg.Load()
+	yl.update("section:\n  key: two\n")
+	cfg.Reload()
+	yl.update("section:\n  key: bad\n")
+	cfg.Reload()
+	h := cfg.History("section")
+	if len(h) != 2 || h[0].Generation != 2 || h[1].Generation != 3 || h[1].Reason != ReasonManual {
+		t.Fatalf("Expected the last 2 generations, got <%#v>", h)
+	}
+	if err := cfg.Rollback("section", 1); err == nil {
+		t.Error("Rollback() should fail for generations no longer kept")
+	}
+	reasons := &testEventClass{}
+	cfg.Reconfigure("section", reasons)
+	if err := cfg.Rollback("section", 2); err != nil {
+		t.Fatalf("Rollback() returned %s", err)
+	}
+	if c.Key != "two" || reasons.reasons[len(reasons.reasons)-1] != ReasonRollback {
+		t.Errorf("Expected the section to be rolled back with the rollback reason, got <%s> and %v", c.Key, reasons.reasons)
+	}
+	if len(cfg.Overrides()) != 0 {
+		t.Errorf("Rolled back values should not be kept as overrides, got %v", cfg.Overrides())
+	}
+	yl.update("section:\n  key: three\n")
+	cfg.Reload()
+	if c.Key != "three" {
+		t.Errorf("Expected rolled back value to be replaced on reload, got <%s>", c.Key)
+	}
+}
+
+func TestHistoryRollbackMap(t *testing.T) {
+	yl := &memLoader{raw: "section:\n  a: 1\n"}
+	cfg := New(yl, WithHistory(2))
+	m := &map[string]int{}
+	cfg.Register("section", m)
+	cfg.Load()
+	yl.update("section:\n  a: 1\n  b: 2\n")
+	cfg.Reload()
+	if err := cfg.Rollback("section", 1); err != nil {
+		t.Fatalf("Rollback() returned %s", err)
+	}
+	v, _ := cfg.Get("section")
+	if got := *v.(*map[string]int); !reflect.DeepEqual(got, map[string]int{"a": 1}) {
+		t.Errorf("Keys added after the rolled back generation should be removed, got %v", got)
+	}
+}
+
+func TestConcurrentRegister(t *testing.T) {
+	// Sections are exported while being loaded
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  key: one\n")), WithCopyOnWrite())
+	cfg.Register("section", &testCfg{})
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			name := fmt.Sprintf("other%d", i)
+			cfg.Register(name, &testCfg{})
+			cfg.Unregister(name)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		cfg.Get("section")
+		cfg.Snapshot("section")
+		cfg.Sections()
+		cfg.Export(ioutil.Discard)
+		l := &testNopClass{}
+		cfg.Reconfigure("section", l)
+		cfg.Unsubscribe("section", l)
+	}
+	<-done
+}
+
+func TestChecksumSkip(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  key: one\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	loads := 0
+	count := LoaderFunc(func(next Loader, cfg map[string]interface{}) error {
+		loads++
+		return next.Load(cfg)
+	})
+	cfg := New(l, WithMiddleware(count))
+	c := &testCfg{}
+	cfg.Register("section", c)
+	cfg.Load()
+	res := cfg.TriggerReload(string(ReasonSignal))
+	if loads != 1 || !reflect.DeepEqual(res.Unchanged, []string{"section"}) {
+		t.Errorf("Reloading an unchanged file should be skipped, got %d loads", loads)
+	}
+	cfg.Reload()
+	if loads != 2 {
+		t.Errorf("Explicit reloads should not be skipped, got %d loads", loads)
+	}
+	yl.update("section:\n  key: two\n")
+	cfg.TriggerReload(string(ReasonSignal))
+	if loads != 3 || c.Key != "two" {
+		t.Errorf("Changed files should be reloaded, got %d loads", loads)
+	}
+}
+
+type testBlockingClass struct {
+	block   chan struct{}
+	done    chan string
+	section string
+}
+
+func (t *testBlockingClass) Reconfigure(interface{}) {
+	if t.block != nil {
+		<-t.block
+	}
+	t.done <- t.section
+}
+
+func TestWithAsyncNotify(t *testing.T) {
+	// slow and fast are dispatched to different workers
+	cfg := New(yaml.NewFromBytes([]byte("slow:\n  key: value\nfast:\n  key: value\n")), WithAsyncNotify(2, 10))
+	done := make(chan string, 10)
+	slow := &testBlockingClass{block: make(chan struct{}), done: done, section: "slow"}
+	cfg.Register("slow", &testCfg{})
+	cfg.Register("fast", &testCfg{})
+	cfg.Reconfigure("slow", slow)
+	cfg.Reconfigure("fast", &testBlockingClass{done: done, section: "fast"})
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	select {
+	case s := <-done:
+		if s != "fast" {
+			t.Errorf("Expected fast listener to be notified first, got %s", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("A slow listener should not block other listeners")
+	}
+	close(slow.block)
+	cfg.Close()
+	if len(done) != 1 {
+		t.Error("Close() should wait for queued notifications")
+	}
+}
+
+func TestPriority(t *testing.T) {
+	cfg := New(yaml.NewFromBytes(nil))
+	order := []string{}
+	cfg.Register("a", &testCfg{})
+	cfg.Register("b", &testCfg{}, Priority(1))
+	cfg.Register("db", &testCfg{}, Priority(10), DependsOn("c"))
+	cfg.Register("c", &testCfg{})
+	cfg.Register("log", &testCfg{}, Priority(100))
+	for _, name := range []string{"a", "b", "c", "db", "log"} {
+		cfg.Reconfigure(name, &orderRecorder{name: name, order: &order})
+	}
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if !reflect.DeepEqual(order, []string{"log", "b", "a", "c", "db"}) {
+		t.Errorf("Expected sections to be notified by priority, got %v", order)
+	}
+}
+
+type testCtxClass struct {
+	hang bool
+	err  chan error
+}
+
+func (t *testCtxClass) Reconfigure(ctx context.Context, cfg interface{}) {
+	if t.hang {
+		<-ctx.Done()
+	}
+	t.err <- ctx.Err()
+}
+
+func TestReconfigureCtx(t *testing.T) {
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  key: value\n")), WithListenerTimeout(10*time.Millisecond))
+	cfg.Register("section", &testCfg{})
+	ok := &testCtxClass{err: make(chan error, 1)}
+	hung := &testCtxClass{hang: true, err: make(chan error, 1)}
+	cfg.ReconfigureCtx("section", ok)
+	cfg.ReconfigureCtx("section", hung)
+	start := time.Now()
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Hung listeners should not block reloads")
+	}
+	if err := <-ok.err; err != nil {
+		t.Errorf("Expected the context to be valid, got %s", err)
+	}
+	if err := <-hung.err; err != context.DeadlineExceeded {
+		t.Errorf("Expected the context deadline to be exceeded, got %v", err)
+	}
+}
+
+func TestReloadOnChan(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  key: one\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	defer cfg.Close()
+	c := &testNotifyCfg{ch: make(chan string, 10)}
+	cfg.Register("section", c)
+	cfg.Load()
+	<-c.ch
+	trigger := make(chan struct{})
+	cfg.ReloadOnChan(trigger)
+	yl.update("section:\n  key: two\n")
+	trigger <- struct{}{}
+	select {
+	case v := <-c.ch:
+		if v != "two" {
+			t.Errorf("Expected <two>, got <%s>", v)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected a reload when triggered")
+	}
+	close(trigger)
+}
+
+type testLockedCfg struct {
+	sync.Mutex
+	Key string `yaml:"key"`
+}
+
+func TestLockerSection(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  key: one\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	c := &testLockedCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	yl.update("section:\n  key: two\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+	c.Lock()
+	defer c.Unlock()
+	if c.Key != "two" {
+		t.Errorf("Expected <two>, got <%s>", c.Key)
+	}
+}
+
+func TestReloadHooks(t *testing.T) {
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  key: value\n")))
+	calls := []string{}
+	cfg.Register("section", &testCfg{})
+	cfg.Reconfigure("section", &orderRecorder{name: "listener", order: &calls})
+	cfg.OnBeforeReload(func() { calls = append(calls, "before") })
+	cfg.OnAfterReload(func(res *ReloadResult) {
+		calls = append(calls, "after:"+strings.Join(res.Changed, ","))
+	})
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if !reflect.DeepEqual(calls, []string{"before", "listener", "after:section"}) {
+		t.Errorf("Expected hooks to be called around the reload, got %v", calls)
+	}
+}
+
+func TestCoalesceReloads(t *testing.T) {
+	running := make(chan struct{})
+	release := make(chan struct{})
+	loads := make(chan struct{}, 10)
+	first := true
+	block := LoaderFunc(func(next Loader, cfg map[string]interface{}) error {
+		loads <- struct{}{}
+		if first {
+			first = false
+			close(running)
+			<-release
+		}
+		return next.Load(cfg)
+	})
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  key: value\n")), WithMiddleware(block))
+	cfg.Register("section", &testCfg{})
+	done := make(chan struct{})
+	go func() {
+		cfg.RequestReload(string(ReasonManual))
+		close(done)
+	}()
+	<-running
+	for i := 0; i < 3; i++ {
+		cfg.RequestReload(string(ReasonManual))
+	}
+	close(release)
+	<-done
+	if len(loads) != 2 {
+		t.Errorf("Expected requests received during a reload to be coalesced into a single reload, got %d loads", len(loads))
+	}
+}
+
+func TestOnReloadError(t *testing.T) {
+	fail := true
+	cfg := New(failingLoader{&fail})
+	cfg.Register("section", &testCfg{})
+	errs := []error{}
+	cfg.OnReloadError(func(err error) { errs = append(errs, err) })
+	cfg.RequestReload(string(ReasonSignal))
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "source unreachable") {
+		t.Errorf("Expected the reload error to be reported, got %v", errs)
+	}
+}
+
+func TestSignalActions(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log.SetOutput(buf)
+	defer log.SetOutput(os.Stderr)
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  key: value\ndb:\n  password: p4ss\n")))
+	cfg.Register("section", &testCfg{})
+	cfg.Register("db", &testSecretCfg{})
+	cfg.Load()
+	DumpAction(cfg)
+	if !strings.Contains(buf.String(), `"key": "value"`) {
+		t.Errorf("Expected the config to be dumped, got %s", buf)
+	}
+	if strings.Contains(buf.String(), "p4ss") {
+		t.Errorf("Secrets should not be dumped, got %s", buf)
+	}
+	CheckAction(cfg)
+	if !strings.Contains(buf.String(), "check succeeded") {
+		t.Errorf("Expected the check outcome to be logged, got %s", buf)
+	}
+}
+
+type testRejectingClass struct {
+	keys []string
+}
+
+func (t *testRejectingClass) Reconfigure(c interface{}) {}
+
+func (t *testRejectingClass) ReconfigureErr(c interface{}) error {
+	if key := c.(*testCfg).Key; key == "rejected" {
+		return errors.New("cannot apply " + key)
+	}
+	t.keys = append(t.keys, c.(*testCfg).Key)
+	return nil
+}
+
+func TestListenerVeto(t *testing.T) {
+	yl := &memLoader{raw: "section:\n  key: one\n"}
+	cfg := New(yl)
+	c := &testCfg{}
+	before := &testEventClass{}
+	rejecting := &testRejectingClass{}
+	cfg.Register("section", c)
+	// Listeners unsubscribing while being notified do not change which listeners are rolled back
+	var h *Handle
+	h = cfg.ReconfigureHandle("section", ReconfigureFunc(func(cfg interface{}) {
+		if cfg.(*testCfg).Key == "rejected" {
+			h.Release()
+		}
+	}))
+	cfg.Reconfigure("section", before)
+	cfg.Reconfigure("section", rejecting)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	yl.update("section:\n  key: rejected\n")
+	err := cfg.Reload()
+	var lerr *ListenerError
+	if !errors.As(err, &lerr) || lerr.Section != "section" {
+		t.Errorf("Expected a ListenerError, got %v", err)
+	}
+	if c.Key != "one" {
+		t.Errorf("A rejected section should be rolled back, got <%s>", c.Key)
+	}
+	if !reflect.DeepEqual(before.reasons, []Reason{ReasonInitialLoad, ReasonManual, ReasonRollback}) || before.cfg.Key != "one" {
+		t.Errorf("Expected listeners to be notified of the rollback, got %v", before.reasons)
+	}
+	yl.update("section:\n  key: two\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+	if c.Key != "two" || !reflect.DeepEqual(rejecting.keys, []string{"one", "two"}) {
+		t.Errorf("Expected <two> to be accepted, got <%s> and %v", c.Key, rejecting.keys)
+	}
+}
+
+func TestOnChange(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  key: one\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	cfg.Register("section", &testCfg{})
+	keys := []string{}
+	cfg.OnChange("section", func(c interface{}) {
+		keys = append(keys, "untyped:"+c.(*testCfg).Key)
+	})
+	OnChangeOf(cfg, "section", func(c *testCfg) {
+		keys = append(keys, "typed:"+c.Key)
+	})
+	OnChangeOf(cfg, "section", func(c *testEnvCfg) {
+		t.Error("Values of other types should not be delivered")
+	})
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	yl.update("section:\n  key: two\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+	if !reflect.DeepEqual(keys, []string{"untyped:one", "typed:one", "untyped:two", "typed:two"}) {
+		t.Errorf("Unexpected notifications %v", keys)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  key: one\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	cfg.Register("section", &testCfg{}, Fresh())
+	changes := cfg.Changes("section")
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	yl.update("section:\n  key: two\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+	if c := <-changes; c.(*testCfg).Key != "two" {
+		t.Errorf("Expected the latest value <two>, got <%s>", c.(*testCfg).Key)
+	}
+	cfg.StopChanges(changes)
+	if _, ok := <-changes; ok {
+		t.Error("StopChanges should close the channel")
+	}
+	yl.update("section:\n  key: three\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+}
+
+type testDiffClass struct {
+	diffs []string
+}
+
+func (t *testDiffClass) Reconfigure(c interface{}) {}
+
+func (t *testDiffClass) ReconfigureDiff(old, new interface{}) {
+	from := "nil"
+	if old != nil {
+		from = old.(*testCfg).Key
+	}
+	t.diffs = append(t.diffs, from+"->"+new.(*testCfg).Key)
+}
+
+func TestReconfigureDiff(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  key: one\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	cfg.Register("section", &testCfg{})
+	d := &testDiffClass{}
+	cfg.Reconfigure("section", d)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	yl.update("section:\n  key: two\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+	if !reflect.DeepEqual(d.diffs, []string{"nil->one", "one->two"}) {
+		t.Errorf("Unexpected diffs %v", d.diffs)
+	}
+}
+
+type testFieldsClass struct {
+	changed [][]string
+}
+
+func (t *testFieldsClass) Reconfigure(c interface{}) {}
+
+func (t *testFieldsClass) ReconfigureFields(c interface{}, changed []string) {
+	t.changed = append(t.changed, changed)
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		old, new interface{}
+		changed  []string
+	}{
+		{nil, &testDeepCfg{}, []string{"Deeper", "None"}},
+		{&testDeepCfg{Deeper: Deeper{Key: "a"}}, &testDeepCfg{Deeper: Deeper{Key: "b"}, changed: 1}, []string{"Deeper.Key"}},
+		{&testSliceCfg{Key: []string{"a", "b"}}, &testSliceCfg{Key: []string{"a", "c"}}, []string{"Key[1]"}},
+		{&testSliceCfg{Key: []string{"a"}}, &testSliceCfg{Key: []string{"a", "b"}}, []string{"Key"}},
+		{&testCfgMap{"a": 1, "b": 2}, &testCfgMap{"a": 1, "b": 3, "c": 4}, []string{"b", "c"}},
+		{&testCfg{Key: "a"}, &testCfg{Key: "a"}, []string{}},
+	}
+	for _, test := range tests {
+		if changed := Diff(test.old, test.new); !reflect.DeepEqual(changed, test.changed) {
+			t.Errorf("Diff(%#v, %#v) returned %v, expected %v", test.old, test.new, changed, test.changed)
+		}
+	}
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  key: one\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	cfg.Register("section", &testCfg{})
+	f := &testFieldsClass{}
+	cfg.Reconfigure("section", f)
+	cfg.Load()
+	yl.update("section:\n  key: two\n")
+	cfg.Reload()
+	if !reflect.DeepEqual(f.changed, [][]string{{"Key", "None"}, {"Key"}}) {
+		t.Errorf("Unexpected changed fields %v", f.changed)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  key: one\nother:\n  key: one\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	c, other := &testCfg{}, &testCfg{}
+	cfg.Register("section", c)
+	cfg.Register("other", other)
+	kept, removed := &testClass{}, &testClass{}
+	cfg.Reconfigure("section", kept)
+	cfg.Reconfigure("section", removed)
+	cfg.OnChange("section", func(interface{}) {})
+	cfg.Load()
+	if !cfg.Unsubscribe("section", removed) || cfg.Unsubscribe("section", removed) {
+		t.Error("Unsubscribe() should only remove registered listeners")
+	}
+	if !cfg.Unregister("other") || cfg.Unregister("other") {
+		t.Error("Unregister() should only remove registered sections")
+	}
+	yl.update("section:\n  key: two\nother:\n  key: two\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+	if kept.changed != 2 || removed.changed != 1 {
+		t.Errorf("Expected removed listeners not to be notified, got %d and %d notifications", kept.changed, removed.changed)
+	}
+	if other.Key != "one" {
+		t.Errorf("Expected unregistered sections not to be loaded, got <%s>", other.Key)
+	}
+}
+
+func TestReconfigureHandle(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  key: one\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	cfg.Register("section", &testCfg{})
+	r := &testDiffClass{}
+	h := cfg.ReconfigureHandle("section", r)
+	cfg.Load()
+	h.Release()
+	h.Release()
+	yl.update("section:\n  key: two\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+	if !reflect.DeepEqual(r.diffs, []string{"nil->one"}) {
+		t.Errorf("Released instances should not be notified, got %v", r.diffs)
+	}
+	if n := len(cfg.sections["section"].onchange); n != 1 {
+		t.Errorf("Expected released instances to be removed, got %d listeners", n)
+	}
+
+	// Instances registered and released while the config is reloaded (run with -race)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			cfg.ReconfigureHandle("section", &testNopClass{}).Release()
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		cfg.TriggerReload(string(ReasonManual))
+	}
+	<-done
+	if n := len(cfg.sections["section"].onchange); n != 1 {
+		t.Errorf("Expected released instances to be removed, got %d listeners", n)
+	}
+}
+
+type testNopClass struct {
+	_ int
+}
+
+func (*testNopClass) Reconfigure(interface{}) {}
+
+func TestOnAnyChange(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  key: one\nother:\n  key: one\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	cfg.Register("section", &testCfg{})
+	changes := []string{}
+	cfg.OnAnyChange(func(section string, c interface{}) {
+		changes = append(changes, section+":"+c.(*testCfg).Key)
+	})
+	cfg.Load()
+	cfg.Register("other", &testCfg{})
+	yl.update("section:\n  key: one\nother:\n  key: two\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+	if !reflect.DeepEqual(changes, []string{"section:one", "other:one", "other:two"}) {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+}
+
+func TestOnce(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  key: one\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	cfg.Register("section", &testCfg{}, Fresh())
+	keys := []string{}
+	cfg.Once("section", func(c interface{}) {
+		keys = append(keys, "before:"+c.(*testCfg).Key)
+	})
+	cfg.Load()
+	cfg.Once("section", func(c interface{}) {
+		keys = append(keys, "after:"+c.(*testCfg).Key)
+	})
+	yl.update("section:\n  key: two\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+	if !reflect.DeepEqual(keys, []string{"before:one", "after:one"}) {
+		t.Errorf("Unexpected notifications %v", keys)
+	}
+}
+
+func TestOnChangePath(t *testing.T) {
+	yl := &memLoader{raw: "section:\n  deeper:\n    key: one\n  none: one\n"}
+	cfg := New(yl)
+	cfg.Register("section", &testDeepCfg{})
+	values := []interface{}{}
+	cfg.OnChangePath("section", "deeper.key", func(v interface{}) {
+		values = append(values, v)
+	})
+	cfg.Load()
+	yl.update("section:\n  deeper:\n    key: one\n  none: two\n")
+	cfg.Reload()
+	yl.update("section:\n  deeper:\n    key: two\n  none: two\n")
+	cfg.Reload()
+	if !reflect.DeepEqual(values, []interface{}{"one", "two"}) {
+		t.Errorf("Expected notifications of changes of deeper.key only, got %v", values)
+	}
+}
+
+func TestGetOf(t *testing.T) {
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  key: value\n")))
+	cfg.Register("section", &testCfg{})
+	cfg.Load()
+	if c, ok := GetOf[testCfg](cfg, "section"); !ok || c.Key != "value" {
+		t.Errorf("Expected the section to be returned, got %v", c)
+	}
+	if _, ok := GetOf[testDeepCfg](cfg, "section"); ok {
+		t.Error("GetOf() should fail for other types")
+	}
+	if _, ok := GetOf[testCfg](cfg, "unknown"); ok {
+		t.Error("GetOf() should fail for unknown sections")
+	}
+}
+
+func TestSectionHandle(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  key: one\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	h := SectionOf(cfg, "section", &testCfg{Key: "default"})
+	if h.Load().Key != "default" {
+		t.Errorf("Expected the defaults before loading, got <%s>", h.Load().Key)
+	}
+	cfg.Load()
+	first := h.Load()
+	yl.update("section:\n  key: two\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+	if first.Key != "one" || h.Load().Key != "two" {
+		t.Errorf("Expected snapshots <one> and <two>, got <%s> and <%s>", first.Key, h.Load().Key)
+	}
+}
+
+func TestCopyOnWrite(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  key: one\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l, WithCopyOnWrite())
+	defaults := &testCfg{Key: "default"}
+	cfg.Register("section", defaults)
+	cfg.Load()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if c, ok := cfg.Get("section"); !ok || c.(*testCfg).Key == "" {
+				t.Error("Get() should always return a complete value")
+				return
+			}
+		}
+	}()
+	yl.update("section:\n  key: two\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+	<-done
+	if defaults.Key != "default" || cfg.MustGet("section").(*testCfg).Key != "two" {
+		t.Errorf("Expected a new instance to be published, got <%s> and <%s>", defaults.Key, cfg.MustGet("section").(*testCfg).Key)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  key:\n    - a\n    - b\n")))
+	c := &testSliceCfg{}
+	cfg.Register("section", c)
+	cfg.Load()
+	v, ok := cfg.Snapshot("section")
+	if !ok {
+		t.Fatal("Snapshot() should return registered sections")
+	}
+	c.Key[0] = "changed"
+	if snap := v.(*testSliceCfg); snap == c || !reflect.DeepEqual(snap.Key, []string{"a", "b"}) {
+		t.Errorf("Expected a deep copy, got %v", snap.Key)
+	}
+	if _, ok := cfg.Snapshot("unknown"); ok {
+		t.Error("Snapshot() should fail for unknown sections")
+	}
+}
+
+func TestSet(t *testing.T) {
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  deeper:\n    key: one\n")))
+	c := &testDeepCfg{}
+	cfg.Register("section", c)
+	cfg.Load()
+	if err := cfg.Set("section", "deeper.key", "two"); err != nil {
+		t.Fatalf("Set() returned %s", err)
+	}
+	if c.Deeper.Key != "two" || c.changed != 2 {
+		t.Errorf("Expected the value to be set and listeners notified, got <%s> and %d notifications", c.Deeper.Key, c.changed)
+	}
+	if err := cfg.Set("section", "deeper.unknown", "two"); err == nil {
+		t.Error("Set() should fail for unknown keys")
+	}
+	if err := cfg.Set("section", "deeper.key", 12); err == nil {
+		t.Error("Set() should fail for values of other types")
+	}
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+	if c.Deeper.Key != "one" {
+		t.Errorf("Expected values set to be replaced on reload, got <%s>", c.Deeper.Key)
+	}
+}
+
+func TestNamed(t *testing.T) {
+	if Named("") != Default() {
+		t.Error("Named(\"\") should return the default config")
+	}
+	c := &testCfg{}
+	Named("test-tuning").Register("section", c)
+	if Named("test-tuning") == Default() || Named("test-tuning") != Named("test-tuning") {
+		t.Error("Named() should return the same config for a name")
+	}
+	if err := LoadNamed("test-tuning", yaml.NewFromBytes([]byte("section:\n  key: value\n"))); err != nil {
+		t.Fatalf("LoadNamed() returned %s", err)
+	}
+	if c.Key != "value" {
+		t.Errorf("Expected the named config to be loaded, got <%s>", c.Key)
+	}
+}
+
+func TestSections(t *testing.T) {
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  key: value\n")))
+	cfg.Register("section", &testCfg{Key: "default"})
+	cfg.Register("other", &testCfg{})
+	cfg.Reconfigure("section", &testClass{})
+	cfg.Load()
+	infos := cfg.Sections()
+	if len(infos) != 2 || infos[0].Name != "other" || infos[1].Name != "section" {
+		t.Fatalf("Unexpected sections %#v", infos)
+	}
+	s := infos[1]
+	if s.Type != "*autoconfig.testCfg" || !s.Present || s.Listeners != 2 || s.Defaults.(*testCfg).Key != "default" {
+		t.Errorf("Unexpected section info %#v", s)
+	}
+	if infos[0].Present {
+		t.Error("Sections missing from the file should not be present")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	yl := &memLoader{raw: "section:\n  mode: fast\n"}
+	cfg := New(yl)
+	cfg.Register("section", &testEnumCfg{})
+	if st := cfg.Status(); st.Generation != 0 || !st.LastSuccess.IsZero() {
+		t.Errorf("Unexpected status before loading %#v", st)
+	}
+	cfg.Load()
+	yl.update("section:\n  mode: unknown\n")
+	cfg.Reload()
+	st := cfg.Status()
+	if st.Generation != 1 || st.LastError == nil || st.LastSuccess.IsZero() || st.LastErrorTime.Before(st.LastSuccess) {
+		t.Errorf("Expected a failing status, got %#v", st)
+	}
+	yl.update("section:\n  mode: slow\n")
+	cfg.Reload()
+	if st := cfg.Status(); st.Generation != 2 || st.LastErrorTime.After(st.LastSuccess) {
+		t.Errorf("Expected a successful status, got %#v", st)
+	}
+}
+
+type testDefaultTagCfg struct {
+	Port    int           `yaml:"port" default:"8080"`
+	Timeout time.Duration `yaml:"timeout" default:"30s"`
+	Hosts   []string      `yaml:"hosts" default:"a, b"`
+	Nested  struct {
+		Name string `yaml:"name" default:"nested"`
+	} `yaml:"nested"`
+}
+
+func TestDefaultTags(t *testing.T) {
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  port: 9090\n")))
+	c := &testDefaultTagCfg{Timeout: time.Second}
+	cfg.Register("section", c)
+	if c.Port != 8080 || c.Timeout != time.Second || !reflect.DeepEqual(c.Hosts, []string{"a", "b"}) || c.Nested.Name != "nested" {
+		t.Errorf("Expected default tags to be applied to zero fields, got %#v", c)
+	}
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Port != 9090 || c.Nested.Name != "nested" {
+		t.Errorf("Expected loaded values to override default tags, got %#v", c)
+	}
+}
+
+type testRequiredCfg struct {
+	DSN   string   `yaml:"dsn" required:"true"`
+	Port  int      `yaml:"port" required:"true" default:"5432"`
+	Hosts []string `yaml:"hosts" required:"true"`
+}
+
+func TestRequired(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  hosts: []\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	c := &testRequiredCfg{}
+	cfg.Register("section", c)
+	err = cfg.Load()
+	var rerr *RequiredError
+	if !errors.As(err, &rerr) || rerr.Field != "section.DSN" || !strings.Contains(err.Error(), "section.Hosts") || strings.Contains(err.Error(), "section.Port") {
+		t.Errorf("Expected missing DSN and Hosts, got %v", err)
+	}
+	yl.update("section:\n  dsn: postgres://db\n  hosts:\n    - db1\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatalf("Reload() returned %s", err)
+	}
+	if c.DSN != "postgres://db" || c.Port != 5432 {
+		t.Errorf("Unexpected values %#v", c)
+	}
+}
+
+type testStaticCfg struct {
+	Listen string `yaml:"listen" autoconfig:"static"`
+	Key    string `yaml:"key"`
+}
+
+func TestStaticFields(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  listen: \":80\"\n  key: one\n")
+	if err != nil {
+		t.Fatal("Unable to create config temp file")
+	}
+	defer yl.clean()
+	cfg := New(l)
+	c := &testStaticCfg{}
+	cfg.Register("section", c)
+	restarts := []string{}
+	cfg.OnRestartRequired(func(section string, fields []string) {
+		restarts = append(restarts, section+":"+strings.Join(fields, ","))
+	})
+	cfg.Load()
+	yl.update("section:\n  listen: \":8080\"\n  key: two\n")
+	res := cfg.TriggerReload(string(ReasonManual))
+	if res.Err != nil {
+		t.Fatalf("Reload returned %s", res.Err)
+	}
+	if c.Listen != ":80" || c.Key != "two" {
+		t.Errorf("Expected static fields to be kept, got %#v", c)
+	}
+	if !reflect.DeepEqual(res.RestartRequired, map[string][]string{"section": {"section.Listen"}}) || !reflect.DeepEqual(restarts, []string{"section:section.Listen"}) {
+		t.Errorf("Expected a restart to be required, got %v and %v", res.RestartRequired, restarts)
+	}
+}
+
+type testDurationCfg struct {
+	Timeout time.Duration            `yaml:"timeout" ini:"timeout" json:"timeout" msgpack:"timeout"`
+	Retries []time.Duration          `yaml:"retries" json:"retries" msgpack:"retries"`
+	Default time.Duration            `yaml:"default" ini:"default" json:"default" msgpack:"default" default:"5m"`
+	ByName  map[string]time.Duration `yaml:"by_name" json:"by_name" msgpack:"by_name"`
+}
+
+func TestDurations(t *testing.T) {
+	packed, err := vmsgpack.Marshal(map[string]interface{}{"section": map[string]interface{}{"timeout": "30s", "retries": []string{"1s", "2s"}, "by_name": map[string]string{"a": "1h"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaders := map[string]Loader{
+		"yaml":    yaml.NewFromBytes([]byte("section:\n  timeout: 30s\n  retries: [1s, 2s]\n  by_name:\n    a: 1h\n")),
+		"jsonc":   jsonc.NewFromBytes([]byte(`{"section": {"timeout": "30s", "retries": ["1s", "2s"], "by_name": {"a": "1h"}}}`)),
+		"msgpack": msgpack.NewFromBytes(packed),
+	}
+	for name, l := range loaders {
+		cfg := New(l)
+		c := &testDurationCfg{}
+		cfg.Register("section", c)
+		if err := cfg.Load(); err != nil {
+			t.Errorf("%s: Load() returned %s", name, err)
+			continue
+		}
+		if c.Timeout != 30*time.Second || !reflect.DeepEqual(c.Retries, []time.Duration{time.Second, 2 * time.Second}) || c.Default != 5*time.Minute || c.ByName["a"] != time.Hour {
+			t.Errorf("%s: unexpected durations %#v", name, c)
+		}
+	}
+	cfg := New(ini.NewFromBytes([]byte("[section]\ntimeout=30s\n")))
+	c := &testDurationCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil || c.Timeout != 30*time.Second {
+		t.Errorf("ini: unexpected duration %s (%v)", c.Timeout, err)
+	}
+	cfg = New(jsonc.NewFromBytes([]byte(`{"section": {"timeout": "soon"}}`)))
+	cfg.Register("section", &testDurationCfg{})
+	if err := cfg.Load(); err == nil {
+		t.Error("Invalid durations should fail")
+	}
+}
+
+type testSizeCfg struct {
+	Max     Size `yaml:"max" ini:"max" json:"max" msgpack:"max"`
+	Bytes   Size `yaml:"bytes" ini:"bytes" json:"bytes" msgpack:"bytes"`
+	Default Size `yaml:"default" ini:"default" json:"default" msgpack:"default" default:"64MiB"`
+}
+
+func TestSize(t *testing.T) {
+	for s, expected := range map[string]Size{"256MB": 256e6, "1.5GiB": 3 << 29, "10 kb": 10000, "1024": 1024, "2b": 2} {
+		if v, err := ParseSize(s); err != nil || v != expected {
+			t.Errorf("ParseSize(%q) returned %d (%v), expected %d", s, v, err, expected)
+		}
+	}
+	for _, s := range []string{"", "MB", "12XB", "-1MB"} {
+		if _, err := ParseSize(s); err == nil {
+			t.Errorf("ParseSize(%q) should fail", s)
+		}
+	}
+	if s := Size(64 << 20).String(); s != "64MiB" {
+		t.Errorf("Unexpected String() %s", s)
+	}
+	packed, err := vmsgpack.Marshal(map[string]interface{}{"section": map[string]interface{}{"max": "1.5GiB", "bytes": 1024}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaders := map[string]Loader{
+		"yaml":    yaml.NewFromBytes([]byte("section:\n  max: 1.5GiB\n  bytes: 1024\n")),
+		"jsonc":   jsonc.NewFromBytes([]byte(`{"section": {"max": "1.5GiB", "bytes": 1024}}`)),
+		"ini":     ini.NewFromBytes([]byte("[section]\nmax=1.5GiB\nbytes=1024\n")),
+		"msgpack": msgpack.NewFromBytes(packed),
+	}
+	for name, l := range loaders {
+		cfg := New(l)
+		c := &testSizeCfg{}
+		cfg.Register("section", c)
+		if err := cfg.Load(); err != nil {
+			t.Errorf("%s: Load() returned %s", name, err)
+			continue
+		}
+		if c.Max != 3<<29 || c.Bytes != 1024 || c.Default != 64<<20 {
+			t.Errorf("%s: unexpected sizes %#v", name, c)
+		}
+	}
+	cfg := New(ini.NewFromBytes([]byte("[section]\nmax=lots\n")))
+	cfg.Register("section", &testSizeCfg{})
+	if err := cfg.Load(); err == nil {
+		t.Error("Invalid sizes should fail")
+	}
+}
+
+type testRichCfg struct {
+	Endpoint *url.URL       `yaml:"endpoint" ini:"endpoint" json:"endpoint" msgpack:"endpoint"`
+	IP       net.IP         `yaml:"ip" ini:"ip" json:"ip" msgpack:"ip"`
+	Network  net.IPNet      `yaml:"network" ini:"network" json:"network" msgpack:"network"`
+	Filter   *regexp.Regexp `yaml:"filter" ini:"filter" json:"filter" msgpack:"filter"`
+	Start    time.Time      `yaml:"start" ini:"start" json:"start" msgpack:"start" layout:"2006-01-02"`
+	Timeout  time.Duration  `yaml:"timeout" ini:"timeout" json:"timeout" msgpack:"timeout"`
+	Port     int            `yaml:"port" ini:"port" json:"port" msgpack:"port"`
+	Tags     []string       `yaml:"tags" json:"tags" msgpack:"tags"`
+	Default  *url.URL       `yaml:"default" ini:"default" json:"default" msgpack:"default" default:"http://localhost:8080"`
+}
+
+func TestRichTypes(t *testing.T) {
+	doc := map[string]interface{}{"endpoint": "https://example.com/api", "ip": "10.0.0.1", "network": "10.0.0.0/8", "filter": "^[a-z]+$", "start": "2024-03-01", "timeout": "30s", "port": 8080, "tags": []string{"a", "b"}}
+	packed, err := vmsgpack.Marshal(map[string]interface{}{"section": doc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaders := map[string]Loader{
+		"yaml":    yaml.NewFromBytes([]byte("section:\n  endpoint: https://example.com/api\n  ip: 10.0.0.1\n  network: 10.0.0.0/8\n  filter: ^[a-z]+$\n  start: 2024-03-01\n  timeout: 30s\n  port: 8080\n  tags: [a, b]\n")),
+		"jsonc":   jsonc.NewFromBytes([]byte(`{"section": {"endpoint": "https://example.com/api", "ip": "10.0.0.1", "network": "10.0.0.0/8", "filter": "^[a-z]+$", "start": "2024-03-01", "timeout": "30s", "port": 8080, "tags": ["a", "b"]}}`)),
+		"ini":     ini.NewFromBytes([]byte("[section]\nendpoint=https://example.com/api\nip=10.0.0.1\nnetwork=10.0.0.0/8\nfilter=^[a-z]+$\nstart=2024-03-01\ntimeout=30s\nport=8080\n")),
+		"msgpack": msgpack.NewFromBytes(packed),
+	}
+	for name, l := range loaders {
+		cfg := New(l)
+		c := &testRichCfg{}
+		cfg.Register("section", c)
+		if err := cfg.Load(); err != nil {
+			t.Errorf("%s: Load() returned %s", name, err)
+			continue
+		}
+		if c.Endpoint == nil || c.Endpoint.Host != "example.com" || c.Endpoint.Path != "/api" {
+			t.Errorf("%s: unexpected endpoint %v", name, c.Endpoint)
+		}
+		if !c.IP.Equal(net.ParseIP("10.0.0.1")) || c.Network.String() != "10.0.0.0/8" || !c.Network.Contains(net.ParseIP("10.1.2.3")) {
+			t.Errorf("%s: unexpected addresses %s %s", name, c.IP, c.Network.String())
+		}
+		if c.Filter == nil || !c.Filter.MatchString("abc") || c.Filter.MatchString("ABC") {
+			t.Errorf("%s: unexpected filter %v", name, c.Filter)
+		}
+		if !c.Start.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) || c.Timeout != 30*time.Second || c.Port != 8080 {
+			t.Errorf("%s: unexpected values %s %s %d", name, c.Start, c.Timeout, c.Port)
+		}
+		if name != "ini" && !reflect.DeepEqual(c.Tags, []string{"a", "b"}) {
+			t.Errorf("%s: unexpected tags %v", name, c.Tags)
+		}
+		if c.Default == nil || c.Default.Port() != "8080" {
+			t.Errorf("%s: unexpected default %v", name, c.Default)
+		}
+	}
+	for _, l := range []Loader{
+		jsonc.NewFromBytes([]byte(`{"section": {"network": "10.0.0.0"}}`)),
+		yaml.NewFromBytes([]byte("section:\n  filter: \"[\"\n")),
+		ini.NewFromBytes([]byte("[section]\nstart=2024-03-01T00:00:00Z\n")),
+	} {
+		cfg := New(l)
+		cfg.Register("section", &testRichCfg{})
+		if err := cfg.Load(); err == nil {
+			t.Error("Invalid values should fail")
+		}
+	}
+}
+
+type testLevel int
+
+type testTaggedCfg struct {
+	Workers int               `config:"workers" yaml:"ignored"`
+	Name    string            `config:"name"`
+	Enabled bool              `config:"enabled"`
+	Hosts   []string          `config:"hosts"`
+	Level   testLevel         `config:"level"`
+	Labels  map[string]string `config:"labels"`
+	Nested  struct {
+		Port int `config:"port"`
+	} `config:"nested"`
+}
+
+func TestDecodeLayer(t *testing.T) {
+	level := func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		if to != reflect.TypeOf(testLevel(0)) || from.Kind() != reflect.String {
+			return data, nil
+		}
+		switch data {
+		case "low":
+			return testLevel(1), nil
+		case "high":
+			return testLevel(2), nil
+		}
+		return nil, fmt.Errorf("unknown level %v", data)
+	}
+	packed, err := vmsgpack.Marshal(map[string]interface{}{"section": map[string]interface{}{"workers": 4, "name": "api", "enabled": true, "hosts": []string{"a", "b"}, "level": "high", "labels": map[string]string{"env": "prod"}, "nested": map[string]interface{}{"port": 8080}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaders := map[string]Loader{
+		"yaml":    yaml.NewFromBytes([]byte("section:\n  workers: 4\n  name: api\n  enabled: true\n  hosts: [a, b]\n  level: high\n  labels:\n    env: prod\n  nested:\n    port: 8080\n")),
+		"jsonc":   jsonc.NewFromBytes([]byte(`{"section": {"workers": 4, "name": "api", "enabled": true, "hosts": ["a", "b"], "level": "high", "labels": {"env": "prod"}, "nested": {"port": 8080}}}`)),
+		"ini":     ini.NewFromBytes([]byte("[section]\nworkers=4\nname=api\nenabled=yes\nhosts=a, b\nlevel=high\n[section.labels]\nenv=prod\n[section.nested]\nport=8080\n")),
+		"msgpack": msgpack.NewFromBytes(packed),
+	}
+	expected := testTaggedCfg{Workers: 4, Name: "api", Enabled: true, Hosts: []string{"a", "b"}, Level: 2, Labels: map[string]string{"env": "prod"}}
+	expected.Nested.Port = 8080
+	for name, l := range loaders {
+		cfg := New(l)
+		cfg.RegisterDecodeHook(level)
+		c := &testTaggedCfg{}
+		cfg.Register("section", c)
+		if err := cfg.Load(); err != nil {
+			t.Errorf("%s: Load() returned %s", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(*c, expected) {
+			t.Errorf("%s: expected %#v, got %#v", name, expected, *c)
+		}
+	}
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  level: medium\n")))
+	cfg.RegisterDecodeHook(level)
+	cfg.Register("section", &testTaggedCfg{})
+	if err := cfg.Load(); err == nil || !strings.Contains(err.Error(), "unknown level") {
+		t.Errorf("Hook errors should be returned, got %v", err)
+	}
+	cfg = New(yaml.NewFromBytes([]byte("section:\n  level: high\n")))
+	cfg.Register("section", &testTaggedCfg{})
+	if err := cfg.Load(); err == nil {
+		t.Error("Hooks registered on a config should not apply to other configs")
+	}
+}
+
+type testNamingCfg struct {
+	MaxConns int
+	HTTPPort int
+	UserID   string
+	Tagged   string `yaml:"Tagged_Key"`
+}
+
+func TestKeyNaming(t *testing.T) {
+	for name, expected := range map[string][]string{"MaxConns": {"max_conns", "max-conns", "maxConns"}, "HTTPPort": {"http_port", "http-port", "httpPort"}, "UserID": {"user_id", "user-id", "userId"}} {
+		if s, k, c := decode.SnakeCase(name), decode.KebabCase(name), decode.CamelCase(name); s != expected[0] || k != expected[1] || c != expected[2] {
+			t.Errorf("Unexpected names for %s : %s %s %s", name, s, k, c)
+		}
+	}
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  max_conns: 10\n  HTTPPort: 8080\n  user_id: joe\n  Tagged_Key: foo\n")), WithKeyNaming(decode.SnakeCase))
+	c := &testNamingCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if c.MaxConns != 10 || c.HTTPPort != 8080 || c.UserID != "joe" || c.Tagged != "foo" {
+		t.Errorf("Unexpected values %#v", c)
+	}
+	cfg = New(yaml.NewFromBytes([]byte("section:\n  max_conns: 10\n  MAX_CONNS: 10\n  tagged_key: foo\n")))
+	c = &testNamingCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if c.MaxConns != 0 || c.Tagged != "" {
+		t.Errorf("Keys should match case-sensitively by default, without naming strategy, got %#v", c)
+	}
+	cfg = New(yaml.NewFromBytes([]byte("section:\n  MAXCONNS: 10\n  tagged_key: foo\n")), WithCaseInsensitiveKeys())
+	c = &testNamingCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if c.MaxConns != 10 || c.Tagged != "foo" {
+		t.Errorf("Keys should match case-insensitively, got %#v", c)
+	}
+}
+
+func TestKeyNamingWrappers(t *testing.T) {
+	doc := yaml.NewFromBytes([]byte("section:\n  max_conns: 10\n  user_id: joe\n"))
+	fail := true
+	custom := LoaderFunc(func(next Loader, cfg map[string]interface{}) error {
+		return next.Load(cfg)
+	})
+	cache := t.TempDir() + "/cache.json"
+	// The cache file is written by the middleware loader, then read by the cache loader
+	for _, tc := range []struct {
+		name string
+		l    Loader
+	}{
+		{"fallback primary", Fallback(doc, failingLoader{&fail})},
+		{"fallback secondary", Fallback(failingLoader{&fail}, doc)},
+		{"multi", Multi(doc, yaml.NewFromBytes([]byte("section:\n  user_id: jane\n")))},
+		{"middleware", Chain(doc, custom, Cache(cache))},
+		{"cache", Chain(failingLoader{&fail}, Cache(cache))},
+	} {
+		name := tc.name
+		cfg := New(tc.l, WithKeyNaming(decode.SnakeCase))
+		c := &testNamingCfg{}
+		cfg.Register("section", c)
+		if err := cfg.Load(); err != nil {
+			t.Fatalf("%s: Load() returned %s", name, err)
+		}
+		if c.MaxConns != 10 || c.UserID == "" {
+			t.Errorf("%s: sections should be decoded using the key naming of the config, got %#v", name, c)
+		}
+	}
+}
+
+type testCodecCfg struct {
+	PoolSize int           `config:"pool_size"`
+	Timeout  time.Duration `config:"timeout"`
+	Password string        `config:"password" secret:"true"`
+	testNamingCfg
+}
+
+func TestExportKeys(t *testing.T) {
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  pool_size: 4\n  timeout: 2s\n  password: pwd\n  max_conns: 10\n  Tagged_Key: foo\n")), WithKeyNaming(decode.SnakeCase))
+	cfg.Register("section", &testCodecCfg{})
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := cfg.Export(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"pool_size": 4`, `"timeout": "2s"`, `"max_conns": 10`} {
+		if !strings.Contains(strings.Join(strings.Fields(buf.String()), " "), key) {
+			t.Errorf("Export should use the keys of config files, %s not found in %s", key, buf.String())
+		}
+	}
+	replay := New(nil, WithKeyNaming(decode.SnakeCase))
+	c := &testCodecCfg{}
+	replay.Register("section", c)
+	if err := replay.Import(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if c.PoolSize != 4 || c.Timeout != 2*time.Second || c.Password != "pwd" || c.MaxConns != 10 || c.Tagged != "foo" {
+		t.Errorf("Unexpected imported values %#v", c)
+	}
+
+	cfg = New(yaml.NewFromBytes([]byte("section:\n  password: pwd\n")), WithSecretPolicy(StripSecrets))
+	cfg.Register("section", &testCodecCfg{})
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := cfg.Export(buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "pwd") || !strings.Contains(buf.String(), "MaxConns") {
+		t.Errorf("Secrets should be stripped from exported keys, got %s", buf.String())
+	}
+}
+
+type testBaseCfg struct {
+	Host  string `yaml:"host"`
+	Port  int    `yaml:"port"`
+	Proto string `yaml:"proto" default:"tcp"`
+}
+
+type testNestedDefaultsCfg struct {
+	testBaseCfg `yaml:",inline"`
+	TLS         struct {
+		Cert   string `yaml:"cert"`
+		Verify bool   `yaml:"verify"`
+	} `yaml:"tls"`
+	Start time.Time `yaml:"start"`
+}
+
+func TestNestedDefaults(t *testing.T) {
+	l := &yamlLoader{}
+	ld, err := l.loader("section:\n  host: example.com\n  tls:\n    cert: b.pem\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.clean()
+	cfg := New(ld)
+	c := &testNestedDefaultsCfg{testBaseCfg: testBaseCfg{Host: "localhost"}}
+	c.TLS.Cert = "a.pem"
+	cfg.Register("section", c)
+	other := &testNestedDefaultsCfg{testBaseCfg: testBaseCfg{Port: 80}}
+	other.TLS.Verify = true
+	cfg.Register("section", other)
+	d := cfg.Sections()[0].Defaults.(*testNestedDefaultsCfg)
+	if d.Host != "localhost" || d.Port != 80 || d.Proto != "tcp" || d.TLS.Cert != "a.pem" || !d.TLS.Verify {
+		t.Errorf("Defaults should be merged recursively, got %#v", d)
+	}
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Host != "example.com" || c.TLS.Cert != "b.pem" || c.Port != 80 || !c.TLS.Verify {
+		t.Errorf("Unexpected values %#v", c)
+	}
+	l.update("section:\n  port: 8080\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Host != "localhost" || c.TLS.Cert != "a.pem" || c.Port != 8080 || !c.TLS.Verify {
+		t.Errorf("Values removed from the file should revert to their defaults, got %#v", c)
+	}
+}
+
+type testPointerCfg struct {
+	Workers *int  `yaml:"workers"`
+	Debug   *bool `yaml:"debug"`
+	Retries *int  `yaml:"retries" default:"5"`
+	TLS     *struct {
+		Cert string `yaml:"cert"`
+	} `yaml:"tls"`
+}
+
+func TestPointerFields(t *testing.T) {
+	os.Setenv("TESTPTR_SECTION_TLS_CERT", "a.pem")
+	defer os.Unsetenv("TESTPTR_SECTION_TLS_CERT")
+	l := &yamlLoader{}
+	ld, err := l.loader("section:\n  workers: 0\n  debug: false\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.clean()
+	cfg := New(ld, WithEnv("TESTPTR"))
+	ten := 10
+	c := &testPointerCfg{Workers: &ten}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Workers == nil || *c.Workers != 0 || c.Debug == nil || *c.Debug || c.Retries == nil || *c.Retries != 5 {
+		t.Errorf("Explicit zero values should be set, got %#v", c)
+	}
+	if c.TLS == nil || c.TLS.Cert != "a.pem" {
+		t.Errorf("Nested pointers should be allocated when set, got %#v", c.TLS)
+	}
+	prev := deepCopy(c)
+	l.update("section:\n  debug: null\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Workers == nil || *c.Workers != 10 || c.Workers == &ten || c.Debug != nil {
+		t.Errorf("Omitted values should revert to their defaults, and nulls unset values, got %#v", c)
+	}
+	if changed := Diff(prev, c); !reflect.DeepEqual(changed, []string{"Workers", "Debug"}) {
+		t.Errorf("Unexpected changed fields %v", changed)
+	}
+}
+
+type testBackendCfg struct {
+	Host    string        `yaml:"host"`
+	Port    int           `yaml:"port" default:"80"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func TestMapDefaults(t *testing.T) {
+	l := &yamlLoader{}
+	ld, err := l.loader("section:\n  primary:\n    host: x\n  extra:\n    host: z\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.clean()
+	cfg := New(ld)
+	c := &map[string]testBackendCfg{"primary": {Host: "a", Port: 8080}}
+	cfg.Register("section", c)
+	cfg.Register("section", &map[string]testBackendCfg{"primary": {Timeout: time.Second}, "backup": {Host: "b"}})
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]testBackendCfg{
+		"primary": {Host: "x", Port: 8080, Timeout: time.Second},
+		"backup":  {Host: "b", Port: 80},
+		"extra":   {Host: "z", Port: 80},
+	}
+	if !reflect.DeepEqual(*c, expected) {
+		t.Errorf("Entries should be merged with their defaults, expected %#v, got %#v", expected, *c)
+	}
+	l.update("section:\n  extra:\n    port: 81\n")
+	if err := cfg.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]testBackendCfg{
+		"primary": {Host: "a", Port: 8080, Timeout: time.Second},
+		"backup":  {Host: "b", Port: 80},
+		"extra":   {Port: 81},
+	}
+	if !reflect.DeepEqual(*c, expected) {
+		t.Errorf("Entries removed from the file should revert to their defaults, expected %#v, got %#v", expected, *c)
+	}
+}
+
+type testMoney int64
+
+type testPriceCfg struct {
+	Price    testMoney   `yaml:"price"`
+	Discount testMoney   `yaml:"discount"`
+	Shipping testMoney   `yaml:"shipping" default:"$4.99"`
+	Tiers    []testMoney `yaml:"tiers"`
+}
+
+func TestRegisterDecodeHook(t *testing.T) {
+	os.Setenv("TESTHOOK_SECTION_DISCOUNT", "$1.50")
+	defer os.Unsetenv("TESTHOOK_SECTION_DISCOUNT")
+	cfg := New(yaml.NewFromBytes([]byte("section:\n  price: $12.30\n  tiers: [$1, $2.50]\n")), WithEnv("TESTHOOK"))
+	cfg.RegisterDecodeHook(func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		if to != reflect.TypeOf(testMoney(0)) || from.Kind() != reflect.String {
+			return data, nil
+		}
+		f, err := strconv.ParseFloat(strings.TrimPrefix(data.(string), "$"), 64)
+		if err != nil {
+			return nil, err
+		}
+		return testMoney(math.Round(f * 100)), nil
+	})
+	c := &testPriceCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+	expected := testPriceCfg{Price: 1230, Discount: 150, Shipping: 499, Tiers: []testMoney{100, 250}}
+	if !reflect.DeepEqual(*c, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, *c)
+	}
+}
+
+type testPoolCfg struct {
+	Min int `yaml:"min"`
+	Max int `yaml:"max"`
+}
+
+func (c *testPoolCfg) Validate() error {
+	if c.Min > c.Max {
+		return errors.New("min must not exceed max")
+	}
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  min: 1\n  max: 10\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer yl.clean()
+	cfg := New(l)
+	c := &testPoolCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+	yl.update("section:\n  min: 20\n  max: 10\n")
+	res := cfg.TriggerReload(string(ReasonManual))
+	var verr *ValidationError
+	if !errors.As(res.Err, &verr) || verr.Section != "section" || res.Failed["section"] == nil {
+		t.Errorf("Expected a ValidationError, got %v", res.Err)
+	}
+	if c.Min != 1 || c.Max != 10 {
+		t.Errorf("An invalid config should not be applied, got %#v", c)
+	}
+}
+
+type testPortCfg struct {
+	Port int `yaml:"port"`
+	TLS  struct {
+		Cert string `yaml:"cert"`
+	} `yaml:"tls"`
+}
+
+func TestAddValidator(t *testing.T) {
+	yl := &yamlLoader{}
+	l, err := yl.loader("section:\n  port: 8080\n  tls:\n    cert: a.pem\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer yl.clean()
+	cfg := New(l)
+	cfg.AddValidator(func(section string, v interface{}) error {
+		c := v.(*testPortCfg)
+		errs := Errors{}
+		if c.Port < 1 || c.Port > 65535 {
+			errs = append(errs, &ValidationError{Section: section, Field: section + ".Port", Err: errors.New("out of range")})
+		}
+		if c.TLS.Cert == "" {
+			errs = append(errs, &ValidationError{Section: section, Field: section + ".TLS.Cert", Err: errors.New("required")})
+		}
+		return errs.errorOrNil()
+	})
+	c := &testPortCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatal(err)
+	}
+	yl.update("section:\n  port: 70000\n")
+	res := cfg.TriggerReload(string(ReasonManual))
+	errs, ok := res.Err.(Errors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("Expected an error per invalid field, got %v", res.Err)
+	}
+	var fields []string
+	for _, err := range errs {
+		if verr, ok := err.(*ValidationError); ok {
+			fields = append(fields, verr.Field)
+		}
+	}
+	sort.Strings(fields)
+	if !reflect.DeepEqual(fields, []string{"section.Port", "section.TLS.Cert"}) || res.Failed["section"] == nil {
+		t.Errorf("Unexpected invalid fields %v", fields)
+	}
+	if c.Port != 8080 || c.TLS.Cert != "a.pem" {
+		t.Errorf("An invalid config should not be applied, got %#v", c)
+	}
+}
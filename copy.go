@@ -0,0 +1,115 @@
+package autoconfig
+
+import (
+	"reflect"
+	"sync"
+)
+
+// deepCopy returns a copy of the exported fields of v, sharing no slice, map or pointer with v.
+// v must be a pointer, as registered sections are.
+func deepCopy(v interface{}) interface{} {
+	src := reflect.ValueOf(v)
+	dst := reflect.New(src.Type().Elem())
+	if src.Elem().Kind() == reflect.Struct {
+		copyFields(dst.Elem(), src.Elem())
+	} else {
+		copyValue(dst.Elem(), src.Elem())
+	}
+	return dst.Interface()
+}
+
+func copyValue(to, from reflect.Value) {
+	switch from.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		if from.IsNil() {
+			// e.g. unset optional values
+			to.Set(reflect.Zero(to.Type()))
+			return
+		}
+	}
+	switch from.Kind() {
+	case reflect.Ptr:
+		p := reflect.New(from.Type().Elem())
+		copyValue(p.Elem(), from.Elem())
+		to.Set(p)
+	case reflect.Interface:
+		e := reflect.New(from.Elem().Type()).Elem()
+		copyValue(e, from.Elem())
+		to.Set(e)
+	case reflect.Struct:
+		// Unexported fields of nested structs (e.g. time.Time) are copied as is
+		to.Set(from)
+		copyFields(to, from)
+	case reflect.Slice:
+		s := reflect.MakeSlice(from.Type(), from.Len(), from.Len())
+		for i := 0; i < from.Len(); i++ {
+			copyValue(s.Index(i), from.Index(i))
+		}
+		to.Set(s)
+	case reflect.Array:
+		for i := 0; i < from.Len(); i++ {
+			copyValue(to.Index(i), from.Index(i))
+		}
+	case reflect.Map:
+		m := reflect.MakeMapWithSize(from.Type(), from.Len())
+		for _, k := range from.MapKeys() {
+			e := reflect.New(from.Type().Elem()).Elem()
+			copyValue(e, from.MapIndex(k))
+			m.SetMapIndex(k, e)
+		}
+		to.Set(m)
+	default:
+		to.Set(from)
+	}
+}
+
+// copyFields deep copies the exported fields of from to to, locks excepted.
+// Exported fields of unexported embedded structs are copied too.
+func copyFields(to, from reflect.Value) {
+	for i := 0; i < from.NumField(); i++ {
+		f := from.Type().Field(i)
+		if isLock(f.Type) {
+			continue
+		}
+		if f.PkgPath != "" {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				copyFields(to.Field(i), from.Field(i))
+			}
+			continue
+		}
+		copyValue(to.Field(i), from.Field(i))
+	}
+}
+
+// assign sets the exported fields of to (a pointer) to the ones of from (a pointer of the same type), fields of embedded structs included.
+// Unexported fields (e.g. derived state) and locks are left untouched.
+func assign(to, from interface{}) {
+	t := reflect.ValueOf(to).Elem()
+	f := reflect.ValueOf(from).Elem()
+	if t.Kind() != reflect.Struct {
+		t.Set(f)
+		return
+	}
+	assignFields(t, f)
+}
+
+func assignFields(t, f reflect.Value) {
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Type().Field(i)
+		switch {
+		case isLock(ft.Type):
+		case ft.PkgPath == "":
+			t.Field(i).Set(f.Field(i))
+		case ft.Anonymous && ft.Type.Kind() == reflect.Struct:
+			// Exported fields of unexported embedded structs
+			assignFields(t.Field(i), f.Field(i))
+		}
+	}
+}
+
+var lockerType = reflect.TypeOf((*sync.Locker)(nil)).Elem()
+
+// isLock returns true for lock types (e.g. an embedded sync.Mutex), whose state must not be copied.
+func isLock(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && reflect.PtrTo(t).Implements(lockerType)
+}
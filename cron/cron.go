@@ -0,0 +1,125 @@
+// Package cron binds a config section of named cron expressions to a scheduler.
+// Jobs are added, removed and rescheduled each time the section changes.
+//
+// 	cron:
+// 	  cleanup: "0 3 * * *"
+// 	  report: "*/15 * * * *"
+//
+// 	c := robfig.New()
+// 	cron.Bind(autoconfig.Default(), "cron", cron.Robfig(c), map[string]func(){
+// 		"cleanup": cleanup,
+// 		"report":  report,
+// 	})
+// 	c.Start()
+package cron
+
+import (
+	"log"
+	"sync"
+
+	"github.com/jfbus/autoconfig"
+	robfig "github.com/robfig/cron/v3"
+)
+
+// Schedules maps job names to cron expressions.
+// Unlike plain maps, jobs removed from the config file are removed from Schedules on reload.
+type Schedules map[string]string
+
+// UnmarshalYAML replaces the schedules instead of merging them
+func (s *Schedules) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	m := map[string]string{}
+	if err := unmarshal(&m); err != nil {
+		return err
+	}
+	*s = m
+	return nil
+}
+
+// Scheduler defines the interface of cron schedulers.
+type Scheduler interface {
+	Schedule(spec string, job func()) (id interface{}, err error)
+	Unschedule(id interface{})
+}
+
+// Binder keeps a scheduler in sync with a section.
+type Binder struct {
+	sync.Mutex
+	scheduler Scheduler
+	jobs      map[string]func()
+	entries   map[string]entry
+}
+
+type entry struct {
+	spec string
+	id   interface{}
+}
+
+// Bind registers section in c, and schedules jobs according to it. Jobs without a cron expression are not scheduled.
+func Bind(c *autoconfig.Config, section string, s Scheduler, jobs map[string]func()) *Binder {
+	b := &Binder{scheduler: s, jobs: jobs, entries: map[string]entry{}}
+	c.Register(section, &Schedules{})
+	c.Reconfigure(section, b)
+	return b
+}
+
+// Reconfigure updates the scheduler with the new schedules
+func (b *Binder) Reconfigure(cfg interface{}) {
+	s, ok := cfg.(*Schedules)
+	if !ok {
+		return
+	}
+	b.Lock()
+	defer b.Unlock()
+	for name, e := range b.entries {
+		if spec, found := (*s)[name]; !found || spec != e.spec {
+			b.scheduler.Unschedule(e.id)
+			delete(b.entries, name)
+		}
+	}
+	for name, spec := range *s {
+		if _, found := b.entries[name]; found {
+			continue
+		}
+		job, found := b.jobs[name]
+		if !found {
+			log.Printf("cron: no job named %s", name)
+			continue
+		}
+		id, err := b.scheduler.Schedule(spec, job)
+		if err != nil {
+			log.Printf("cron: cannot schedule %s with %q: %s", name, spec, err)
+			continue
+		}
+		b.entries[name] = entry{spec: spec, id: id}
+	}
+}
+
+// Scheduled returns the cron expressions of the jobs currently scheduled
+func (b *Binder) Scheduled() map[string]string {
+	b.Lock()
+	defer b.Unlock()
+	m := make(map[string]string, len(b.entries))
+	for name, e := range b.entries {
+		m[name] = e.spec
+	}
+	return m
+}
+
+type robfigScheduler struct {
+	c *robfig.Cron
+}
+
+// Robfig adapts a github.com/robfig/cron scheduler
+func Robfig(c *robfig.Cron) Scheduler {
+	return robfigScheduler{c: c}
+}
+
+func (r robfigScheduler) Schedule(spec string, job func()) (interface{}, error) {
+	return r.c.AddFunc(spec, job)
+}
+
+func (r robfigScheduler) Unschedule(id interface{}) {
+	if eid, ok := id.(robfig.EntryID); ok {
+		r.c.Remove(eid)
+	}
+}
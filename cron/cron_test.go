@@ -0,0 +1,68 @@
+package cron
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jfbus/autoconfig"
+	"github.com/jfbus/autoconfig/yaml"
+)
+
+// testScheduler records the scheduled jobs, by id
+type testScheduler struct {
+	next  int
+	specs map[int]string
+}
+
+func (s *testScheduler) Schedule(spec string, job func()) (interface{}, error) {
+	if spec == "invalid" {
+		return nil, errors.New("invalid spec")
+	}
+	s.next++
+	s.specs[s.next] = spec
+	return s.next, nil
+}
+
+func (s *testScheduler) Unschedule(id interface{}) {
+	delete(s.specs, id.(int))
+}
+
+// testLoader loads its raw yaml document, which can be changed between reloads
+type testLoader struct {
+	raw string
+}
+
+func (l *testLoader) Load(cfg map[string]interface{}) error {
+	return yaml.NewFromBytes([]byte(l.raw)).Load(cfg)
+}
+
+func TestBind(t *testing.T) {
+	l := &testLoader{raw: "cron:\n  cleanup: \"0 3 * * *\"\n  report: \"*/15 * * * *\"\n"}
+	cfg := autoconfig.New(l)
+	s := &testScheduler{specs: map[int]string{}}
+	job := func() {}
+	b := Bind(cfg, "cron", s, map[string]func(){"cleanup": job, "report": job, "purge": job})
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if got := b.Scheduled(); len(s.specs) != 2 || !reflect.DeepEqual(got, map[string]string{"cleanup": "0 3 * * *", "report": "*/15 * * * *"}) {
+		t.Errorf("Jobs should be scheduled on load, got %v", got)
+	}
+	l.raw = "cron:\n  cleanup: \"0 4 * * *\"\n  purge: \"@daily\"\n  unknown: \"@hourly\"\n"
+	if res := cfg.TriggerReload("test"); res.Err != nil {
+		t.Fatalf("TriggerReload() returned %s", res.Err)
+	}
+	expected := map[string]string{"cleanup": "0 4 * * *", "purge": "@daily"}
+	if got := b.Scheduled(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Jobs should be rescheduled, added and removed on reload, expected %v, got %v", expected, got)
+	}
+	if len(s.specs) != 2 {
+		t.Errorf("Rescheduled and removed jobs should be unscheduled, got %v", s.specs)
+	}
+	l.raw = "cron:\n  cleanup: \"0 4 * * *\"\n  purge: invalid\n"
+	cfg.TriggerReload("test")
+	if got := b.Scheduled(); !reflect.DeepEqual(got, map[string]string{"cleanup": "0 4 * * *"}) {
+		t.Errorf("Jobs that cannot be scheduled should be skipped, got %v", got)
+	}
+}
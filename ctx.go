@@ -0,0 +1,61 @@
+package autoconfig
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// ReconfigurableCtx defines the interface of instances needing a deadline to reconfigure themselves, e.g. to reconnect pools.
+// ctx is done once the listener timeout has expired (see WithListenerTimeout).
+type ReconfigurableCtx interface {
+	Reconfigure(ctx context.Context, cfg interface{})
+}
+
+// WithListenerTimeout sets the time each ReconfigurableCtx listener has to reconfigure itself.
+// Listeners that have not returned in time are reported as hung, and the reload proceeds without waiting for them.
+func WithListenerTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.listenerTimeout = timeout
+	}
+}
+
+// ReconfigureCtx registers an instance, as Reconfigure does, r.Reconfigure() being called with a context
+// whose deadline is set by WithListenerTimeout.
+func (c *Config) ReconfigureCtx(name string, r ReconfigurableCtx) bool {
+	return c.Reconfigure(name, &ctxListener{c: c, r: r})
+}
+
+// ReconfigureCtx registers an instance to the default config, r.Reconfigure() being called with a context.
+func ReconfigureCtx(name string, r ReconfigurableCtx) bool {
+	return globalConfig.ReconfigureCtx(name, r)
+}
+
+type ctxListener struct {
+	c *Config
+	r ReconfigurableCtx
+}
+
+func (l *ctxListener) Reconfigure(cfg interface{}) {
+	l.ReconfigureEvent(Event{Config: cfg})
+}
+
+func (l *ctxListener) ReconfigureEvent(ev Event) {
+	timeout := l.c.listenerTimeout
+	if timeout <= 0 {
+		l.r.Reconfigure(context.Background(), ev.Config)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.r.Reconfigure(ctx, ev.Config)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("Config: listener of section %s has not reconfigured itself after %s", ev.Section, timeout)
+	}
+}
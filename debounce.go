@@ -0,0 +1,97 @@
+package autoconfig
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// WithDebounce coalesces reload requests (signals, see ReloadOn, polled or watched file changes, see Poll and RequestReload)
+// received within window into a single reload, run once no request has been received for window.
+// Editors and config management tools often write files several times in quick succession.
+func WithDebounce(window time.Duration) Option {
+	return func(c *Config) {
+		c.debounce = &debounce{window: window}
+	}
+}
+
+type debounce struct {
+	sync.Mutex
+	window  time.Duration
+	pending Timer
+}
+
+// RequestReload reloads the config, as TriggerReload does, unless it has been set up with WithDebounce :
+// the reload is then delayed until no other request has been received for the debounce window, and run asynchronously.
+// Requests received while a reload is running are coalesced into a single follow-up reload.
+// Reload errors are reported to OnReloadError handlers, or logged. It is meant to be used by watchers.
+func (c *Config) RequestReload(reason string) {
+	if c.debounce == nil || c.debounce.window <= 0 {
+		c.coalesce(reason)
+		return
+	}
+	d := c.debounce
+	d.Lock()
+	defer d.Unlock()
+	if d.pending != nil {
+		d.pending.Stop()
+	}
+	d.pending = c.clock.AfterFunc(d.window, func() {
+		c.coalesce(reason)
+	})
+}
+
+// RequestReload reloads the default config, debounced if it has been set up with WithDebounce.
+func RequestReload(reason string) {
+	globalConfig.RequestReload(reason)
+}
+
+// requests tracks reload requests, see coalesce.
+type requests struct {
+	sync.Mutex
+	running bool
+	pending bool
+	reason  string
+}
+
+// coalesce reloads the config, unless a reload requested by coalesce is already running :
+// a single follow-up reload is then run once it is done, whatever the number of requests received meanwhile.
+func (c *Config) coalesce(reason string) {
+	r := &c.requests
+	r.Lock()
+	if r.running {
+		r.pending, r.reason = true, reason
+		r.Unlock()
+		return
+	}
+	r.running = true
+	r.Unlock()
+	for {
+		c.reloadAndLog(reason)
+		r.Lock()
+		if !r.pending {
+			r.running = false
+			r.Unlock()
+			return
+		}
+		r.pending, reason = false, r.reason
+		r.Unlock()
+	}
+}
+
+// reloadAndLog reloads the config, and reports errors to the OnReloadError handlers, or logs them.
+func (c *Config) reloadAndLog(reason string) {
+	res := c.TriggerReload(reason)
+	if res.Err == nil {
+		return
+	}
+	c.reloadErrorsMu.Lock()
+	handlers := c.reloadErrors
+	c.reloadErrorsMu.Unlock()
+	if len(handlers) == 0 {
+		log.Printf("Config: %s", res)
+	}
+	for _, fn := range handlers {
+		fn(res.Err)
+	}
+}
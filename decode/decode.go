@@ -0,0 +1,102 @@
+// Package decode is the decoding layer shared by loaders : documents are decoded by each format into generic maps, slices and scalars,
+// then decoded to section structs by Into, so that field names, conversions (e.g. durations, URLs or CIDRs) and hooks are the same for all formats.
+// It does not depend on autoconfig, so that any loader can use it.
+package decode
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// JSON decodes the JSON document data to v using Into, with the json tags of struct fields.
+func JSON(data []byte, v interface{}) error {
+	return Options{}.JSON(data, v)
+}
+
+// JSON decodes the JSON document data to v as the JSON function does, using o.
+func (o Options) JSON(data []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	// Numbers are kept as is, so that large integers are not rounded to float64
+	d.UseNumber()
+	var doc interface{}
+	if err := d.Decode(&doc); err != nil {
+		return err
+	}
+	return o.Into(numbers(doc), v, "json")
+}
+
+// numbers converts the json.Number values of doc to int64, uint64 or float64 values, as decoded by other formats.
+func numbers(doc interface{}) interface{} {
+	switch t := doc.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(t.String(), 10, 64); err == nil {
+			return u
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		for k, v := range t {
+			t[k] = numbers(v)
+		}
+	case []interface{}:
+		for i, v := range t {
+			t[i] = numbers(v)
+		}
+	}
+	return doc
+}
+
+// YAML decodes the YAML document data to v using Into, with the yaml tags of struct fields.
+func YAML(data []byte, v interface{}) error {
+	return Options{}.YAML(data, v)
+}
+
+// YAML decodes the YAML document data to v as the YAML function does, using o.
+func (o Options) YAML(data []byte, v interface{}) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	return o.Into(doc, v, "yaml")
+}
+
+// stringKeys converts the map[interface{}]interface{} maps produced by yaml (or msgpack) to map[string]interface{}
+func stringKeys(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprint(k)] = stringKeys(v)
+		}
+		return m
+	case []interface{}:
+		for i := range t {
+			t[i] = stringKeys(t[i])
+		}
+	}
+	return v
+}
+
+// tagName returns the name set by the config tag of field, or by its tag.
+func tagName(field reflect.StructField, tag string) string {
+	if name := strings.Split(field.Tag.Get("config"), ",")[0]; name != "" {
+		return name
+	}
+	return strings.Split(field.Tag.Get(tag), ",")[0]
+}
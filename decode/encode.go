@@ -0,0 +1,133 @@
+package decode
+
+import (
+	"encoding"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"time"
+)
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// Encode converts v to a generic document of maps, slices and scalars, the reverse of Into : structs are converted to maps
+// keyed as Into matches them using tag and o (config tag, tag, then name), fields of untagged embedded structs being promoted,
+// and types decoded from strings by Text are converted to strings, so that the document can be decoded again by Into,
+// e.g. after being written as JSON.
+func Encode(v interface{}, tag string, o Options) interface{} {
+	return o.encode(reflect.ValueOf(v), tag, "")
+}
+
+func (o Options) encode(v reflect.Value, tag, layout string) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Ptr && IsText(v.Type()) {
+			break
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	if IsText(v.Type()) {
+		if s, ok := text(v, layout); ok {
+			return s
+		}
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		m := map[string]interface{}{}
+		o.encodeFields(m, v, tag)
+		return m
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		m := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			m[fmt.Sprint(k.Interface())] = o.encode(v.MapIndex(k), tag, layout)
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		s := make([]interface{}, v.Len())
+		for i := range s {
+			s[i] = o.encode(v.Index(i), tag, layout)
+		}
+		return s
+	}
+	return v.Interface()
+}
+
+// encodeFields adds the exported fields of v, a struct, to m.
+func (o Options) encodeFields(m map[string]interface{}, v reflect.Value, tag string) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name := tagName(f, tag)
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if name == "" && f.Anonymous {
+			for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct && !IsText(fv.Type()) {
+				// Fields of embedded structs are promoted
+				o.encodeFields(m, fv, tag)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		m[o.Key(f, tag)] = o.encode(fv, tag, f.Tag.Get("layout"))
+	}
+}
+
+// text returns the string representation of v, a value of a type decoded by Text, as Text parses it.
+func text(v reflect.Value, layout string) (string, bool) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	switch v.Type() {
+	case urlType:
+		u := v.Interface().(url.URL)
+		return u.String(), true
+	case ipType:
+		return v.Interface().(net.IP).String(), true
+	case ipNetType:
+		n := v.Interface().(net.IPNet)
+		return n.String(), true
+	case regexpType:
+		re := v.Interface().(regexp.Regexp)
+		return re.String(), true
+	case timeType:
+		if layout == "" {
+			layout = time.RFC3339Nano
+		}
+		return v.Interface().(time.Time).Format(layout), true
+	case durationType:
+		return time.Duration(v.Int()).String(), true
+	}
+	if reflect.PtrTo(v.Type()).Implements(textMarshalerType) || v.Type().Implements(textMarshalerType) {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		if b, err := p.Interface().(encoding.TextMarshaler).MarshalText(); err == nil {
+			return string(b), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,362 @@
+package decode
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Hook converts data, a value of a decoded document of type from, before it is decoded to a value of type to,
+// e.g. to decode a custom type from a string, or to support a legacy format. Hooks return data as is for types they do not handle.
+//
+// 	func(from, to reflect.Type, data interface{}) (interface{}, error) {
+// 		if to != reflect.TypeOf(Level(0)) || from.Kind() != reflect.String {
+// 			return data, nil
+// 		}
+// 		return ParseLevel(data.(string))
+// 	}
+type Hook func(from, to reflect.Type, data interface{}) (interface{}, error)
+
+// Into decodes doc, a document decoded into generic maps, slices and scalars (e.g. by encoding/json, yaml or msgpack), to v, a pointer,
+// using the default options (see Options.Into).
+// Keys are matched case-sensitively (see Options) against the config tag of struct fields, their tag (e.g. "yaml"),
+// or their names, as is or lowercased as yaml does, fields of embedded structs being promoted. A single config tag can then be used for all formats :
+//
+// 	type JobConf struct {
+// 		Endpoint *url.URL       `config:"endpoint"`
+// 		Allowed  []net.IPNet    `config:"allowed"`
+// 		Start    time.Time      `config:"start" layout:"2006-01-02"`
+// 		Filter   *regexp.Regexp `config:"filter"`
+// 		Workers  int            `config:"workers"`
+// 	}
+//
+// Hooks (see Options) are called first. Strings are then decoded using Text (time.Time fields being parsed using the layout of their layout tag),
+// or parsed for numbers and booleans, strings being split on commas for slices (e.g. INI values).
+// Types implementing yaml.Unmarshaler or json.Unmarshaler are decoded using them.
+func Into(doc interface{}, v interface{}, tag string) error {
+	return Options{}.Into(doc, v, tag)
+}
+
+// Into decodes doc to v as the Into function does, using o.
+func (o Options) Into(doc interface{}, v interface{}, tag string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cannot decode to %T", v)
+	}
+	d := decoder{tag: tag, matcher: matcher{Options: o}}
+	return d.into("", doc, rv.Elem(), "")
+}
+
+// Hooked calls the hooks of o with s, the string representation of a value of the type of v (e.g. an environment variable),
+// and decodes the value returned by hooks to v, which must be settable. It returns false if no hook has converted s.
+func (o Options) Hooked(v reflect.Value, s string) (bool, error) {
+	var doc interface{} = s
+	for _, h := range o.Hooks {
+		var err error
+		if doc, err = h(reflect.TypeOf(doc), v.Type(), doc); err != nil {
+			return true, err
+		}
+		if doc == nil {
+			return true, nil
+		}
+	}
+	if _, ok := doc.(string); ok {
+		return false, nil
+	}
+	// Values returned by hooks are not passed to hooks again
+	d := decoder{matcher: matcher{Options: Options{Naming: o.Naming, CaseInsensitive: o.CaseInsensitive, DisallowUnknownKeys: o.DisallowUnknownKeys}}}
+	return true, d.into("", doc, v, "")
+}
+
+type decoder struct {
+	matcher
+	tag string
+}
+
+func (d decoder) into(path string, doc interface{}, v reflect.Value, layout string) error {
+	if doc == nil {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+			// Explicit nulls unset optional values, as encoding/json does
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.into(path, doc, v.Elem(), layout)
+	}
+	doc = stringKeys(doc)
+	for _, h := range d.Hooks {
+		var err error
+		if doc, err = h(reflect.TypeOf(doc), v.Type(), doc); err != nil {
+			return fmt.Errorf("invalid value for %s: %s", strings.TrimPrefix(path, "."), err)
+		}
+		if doc == nil {
+			return nil
+		}
+	}
+	if dv := reflect.ValueOf(doc); dv.Type() == v.Type() && v.Kind() != reflect.Map {
+		// e.g. values converted by hooks, or times decoded by yaml or msgpack
+		v.Set(dv)
+		return nil
+	}
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		v.Set(reflect.ValueOf(doc))
+		return nil
+	}
+	if s, ok := doc.(string); ok {
+		if ok, err := Text(v, s, layout); ok {
+			if err != nil {
+				return fmt.Errorf("invalid value %q for %s: %s", s, strings.TrimPrefix(path, "."), err)
+			}
+			return nil
+		}
+	}
+	if handled, err := d.unmarshaler(path, doc, v); handled {
+		return err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		switch s := doc.(type) {
+		case string:
+			v.SetString(s)
+			return nil
+		case bool, int, int64, uint64, float64:
+			v.SetString(fmt.Sprint(s))
+			return nil
+		}
+	case reflect.Bool:
+		switch b := doc.(type) {
+		case bool:
+			v.SetBool(b)
+			return nil
+		case string:
+			pb, err := parseBool(b)
+			if err != nil {
+				return fmt.Errorf("invalid value %q for %s: %s", b, strings.TrimPrefix(path, "."), err)
+			}
+			v.SetBool(pb)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, ok := number(doc); ok {
+			i, err := strconv.ParseInt(n, 0, v.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("invalid value %s for %s: %s", n, strings.TrimPrefix(path, "."), err)
+			}
+			v.SetInt(i)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, ok := number(doc); ok {
+			u, err := strconv.ParseUint(n, 0, v.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("invalid value %s for %s: %s", n, strings.TrimPrefix(path, "."), err)
+			}
+			v.SetUint(u)
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, ok := number(doc); ok {
+			f, err := strconv.ParseFloat(n, v.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("invalid value %s for %s: %s", n, strings.TrimPrefix(path, "."), err)
+			}
+			v.SetFloat(f)
+			return nil
+		}
+	case reflect.Slice, reflect.Array:
+		var s []interface{}
+		switch e := doc.(type) {
+		case []interface{}:
+			s = e
+		case string:
+			for _, p := range strings.Split(e, ",") {
+				s = append(s, strings.TrimSpace(p))
+			}
+		}
+		if s == nil {
+			break
+		}
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.MakeSlice(v.Type(), len(s), len(s)))
+		}
+		for i := 0; i < v.Len() && i < len(s); i++ {
+			if err := d.into(fmt.Sprintf("%s[%d]", path, i), s[i], v.Index(i), layout); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if m, ok := doc.(map[string]interface{}); ok {
+			if v.IsNil() {
+				v.Set(reflect.MakeMapWithSize(v.Type(), len(m)))
+			}
+			for k, e := range m {
+				key := reflect.New(v.Type().Key()).Elem()
+				if err := d.into(path, k, key, ""); err != nil {
+					return err
+				}
+				// Values are decoded to a copy of the existing one, if any, as map values are not addressable
+				val := reflect.New(v.Type().Elem()).Elem()
+				if cur := v.MapIndex(key); cur.IsValid() {
+					val.Set(cur)
+				}
+				if err := d.into(path+"."+k, e, val, layout); err != nil {
+					return err
+				}
+				v.SetMapIndex(key, val)
+			}
+			return nil
+		}
+	case reflect.Struct:
+		if m, ok := doc.(map[string]interface{}); ok {
+			for k, e := range m {
+				f, sf, found := d.field(v, k)
+				if !found {
+					if d.DisallowUnknownKeys {
+						return fmt.Errorf("unknown key %s", strings.TrimPrefix(path+"."+k, "."))
+					}
+					continue
+				}
+				if err := d.into(path+"."+k, e, f, sf.Tag.Get("layout")); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot decode %T to %s for %s", doc, v.Type(), strings.TrimPrefix(path, "."))
+}
+
+// unmarshaler decodes doc to v using the yaml.Unmarshaler or json.Unmarshaler implementation of v, if any.
+func (d decoder) unmarshaler(path string, doc interface{}, v reflect.Value) (bool, error) {
+	if !v.CanAddr() {
+		return false, nil
+	}
+	switch u := v.Addr().Interface().(type) {
+	case yaml.Unmarshaler:
+		return true, u.UnmarshalYAML(func(out interface{}) error {
+			ov := reflect.ValueOf(out)
+			if ov.Kind() != reflect.Ptr || ov.IsNil() {
+				return fmt.Errorf("cannot decode to %T", out)
+			}
+			return d.into(path, doc, ov.Elem(), "")
+		})
+	case json.Unmarshaler:
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return true, err
+		}
+		if err := u.UnmarshalJSON(data); err != nil {
+			return true, fmt.Errorf("invalid value for %s: %s", strings.TrimPrefix(path, "."), err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// number returns the representation of doc if it is a number, or a string (e.g. INI values).
+func number(doc interface{}) (string, bool) {
+	switch n := doc.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(n), true
+	case float32:
+		return strconv.FormatFloat(float64(n), 'f', -1, 32), true
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64), true
+	case string:
+		return strings.TrimSpace(n), true
+	}
+	return "", false
+}
+
+// parseBool parses a boolean, also accepting yes/no and on/off.
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "y", "on":
+		return true, nil
+	case "no", "n", "off":
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
+// field returns the field of v (a struct) named key, fields of embedded structs being promoted.
+// Nil embedded pointers are allocated only when one of their fields matches.
+func (d decoder) field(v reflect.Value, key string) (reflect.Value, reflect.StructField, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name := tagName(f, d.tag)
+		if name == "-" {
+			continue
+		}
+		if name == "" && f.Anonymous {
+			et := f.Type
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem()
+			}
+			if et.Kind() != reflect.Struct {
+				continue
+			}
+			if _, found := d.fieldType(et, key); !found {
+				continue
+			}
+			ev := v.Field(i)
+			if ev.Kind() == reflect.Ptr {
+				if ev.IsNil() {
+					if !ev.CanSet() {
+						continue
+					}
+					ev.Set(reflect.New(et))
+				}
+				ev = ev.Elem()
+			}
+			return d.field(ev, key)
+		}
+		if f.PkgPath == "" && d.match(key, name, f.Name) {
+			return v.Field(i), f, true
+		}
+	}
+	return reflect.Value{}, reflect.StructField{}, false
+}
+
+// fieldType returns the type of the field of t named key, fields of embedded structs being promoted.
+func (d decoder) fieldType(t reflect.Type, key string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name := tagName(f, d.tag)
+		if name == "-" {
+			continue
+		}
+		if name == "" && f.Anonymous {
+			et := f.Type
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				if ft, found := d.fieldType(et, key); found {
+					return ft, true
+				}
+			}
+			continue
+		}
+		if f.PkgPath == "" && d.match(key, name, f.Name) {
+			return f.Type, true
+		}
+	}
+	return nil, false
+}
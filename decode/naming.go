@@ -0,0 +1,102 @@
+package decode
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Naming converts the name of an untagged struct field to its key in documents (see Options).
+type Naming func(field string) string
+
+var (
+	// SnakeCase names fields as snake_case keys (e.g. MaxConns -> max_conns, HTTPPort -> http_port)
+	SnakeCase Naming = func(field string) string { return strings.Join(words(field), "_") }
+	// KebabCase names fields as kebab-case keys (e.g. MaxConns -> max-conns)
+	KebabCase Naming = func(field string) string { return strings.Join(words(field), "-") }
+	// CamelCase names fields as camelCase keys (e.g. MaxConns -> maxConns, HTTPPort -> httpPort)
+	CamelCase Naming = func(field string) string {
+		w := words(field)
+		for i := 1; i < len(w); i++ {
+			w[i] = strings.ToUpper(w[i][:1]) + w[i][1:]
+		}
+		return strings.Join(w, "")
+	}
+)
+
+// Options sets how documents are decoded : how keys are matched against struct fields, and the hooks converting values.
+// The zero value matches keys case-sensitively, untagged fields being named after their Go name, and has no hooks.
+type Options struct {
+	// Naming names untagged fields (e.g. SnakeCase), fields still matching their Go name
+	Naming Naming
+	// CaseInsensitive matches keys regardless of their case
+	CaseInsensitive bool
+	// Hooks are called, in order, with each value of decoded documents before it is decoded
+	Hooks []Hook
+	// DisallowUnknownKeys makes Into return an error when a key does not match any field, instead of ignoring it
+	DisallowUnknownKeys bool
+}
+
+// Name returns the key of an untagged field named field, using the naming strategy of o.
+func (o Options) Name(field string) string {
+	if o.Naming == nil {
+		return field
+	}
+	return o.Naming(field)
+}
+
+// Key returns the key of field in documents decoded using tag (e.g. "yaml") : its config tag, its tag,
+// or its name (see Options.Name). It returns "-" for ignored fields.
+func (o Options) Key(field reflect.StructField, tag string) string {
+	if name := tagName(field, tag); name != "" {
+		return name
+	}
+	return o.Name(field.Name)
+}
+
+// matcher matches document keys against struct fields.
+type matcher struct {
+	Options
+}
+
+// match returns true if key is the key of field, named name by tags ("" if untagged).
+// Untagged fields also match their lowercased name, as yaml names them.
+func (m matcher) match(key, name, field string) bool {
+	eq := func(a, b string) bool { return a == b }
+	if m.CaseInsensitive {
+		eq = strings.EqualFold
+	}
+	if name != "" {
+		return eq(key, name)
+	}
+	return eq(key, field) || eq(key, strings.ToLower(field)) || (m.Naming != nil && eq(key, m.Naming(field)))
+}
+
+// words splits a Go identifier into lowercase words, acronyms being kept together (e.g. HTTPPort -> http, port).
+func words(name string) []string {
+	var (
+		out []string
+		cur []rune
+	)
+	runes := []rune(name)
+	for i, r := range runes {
+		if r == '_' || r == '-' {
+			if len(cur) > 0 {
+				out, cur = append(out, string(cur)), nil
+			}
+			continue
+		}
+		if i > 0 && len(cur) > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			next := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || next {
+				out, cur = append(out, string(cur)), nil
+			}
+		}
+		cur = append(cur, unicode.ToLower(r))
+	}
+	if len(cur) > 0 {
+		out = append(out, string(cur))
+	}
+	return out
+}
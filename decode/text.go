@@ -0,0 +1,103 @@
+package decode
+
+import (
+	"encoding"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"time"
+)
+
+var (
+	urlType    = reflect.TypeOf(url.URL{})
+	ipType     = reflect.TypeOf(net.IP{})
+	ipNetType  = reflect.TypeOf(net.IPNet{})
+	regexpType = reflect.TypeOf(regexp.Regexp{})
+	timeType   = reflect.TypeOf(time.Time{})
+)
+
+// IsText returns true if values of type t (or pointed to by t) are decoded from strings by Text.
+func IsText(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return isRich(t) || t == durationType || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// isRich returns true for the standard types Text decodes itself (net.IP and time.Time implement encoding.TextUnmarshaler,
+// but without error details or layouts).
+func isRich(t reflect.Type) bool {
+	switch t {
+	case urlType, ipType, ipNetType, regexpType, timeType:
+		return true
+	}
+	return false
+}
+
+// Text sets v, which must be settable, from its string representation s, and returns true, if v is of a type decoded from text :
+//   - url.URL (e.g. "https://example.com/path"),
+//   - net.IP (e.g. "10.0.0.1" or "::1"),
+//   - net.IPNet, as a CIDR (e.g. "10.0.0.0/8"),
+//   - regexp.Regexp (e.g. "^[a-z]+$"),
+//   - time.Time, parsed using layout (RFC 3339 if empty),
+//   - time.Duration (e.g. "30s"),
+//   - any type implementing encoding.TextUnmarshaler.
+//
+// Pointers to these types are allocated as needed. Text returns false for other types.
+func Text(v reflect.Value, s, layout string) (bool, error) {
+	if !IsText(v.Type()) {
+		return false, nil
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch v.Type() {
+	case urlType:
+		u, err := url.Parse(s)
+		if err != nil {
+			return true, err
+		}
+		v.Set(reflect.ValueOf(*u))
+	case ipType:
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return true, fmt.Errorf("invalid IP address %q", s)
+		}
+		v.Set(reflect.ValueOf(ip))
+	case ipNetType:
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return true, err
+		}
+		v.Set(reflect.ValueOf(*n))
+	case regexpType:
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return true, err
+		}
+		v.Set(reflect.ValueOf(*re))
+	case timeType:
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return true, err
+		}
+		v.Set(reflect.ValueOf(t))
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return true, err
+		}
+		v.SetInt(int64(d))
+	default:
+		return true, v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	return true, nil
+}
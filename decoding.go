@@ -0,0 +1,73 @@
+package autoconfig
+
+import (
+	"reflect"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// WithKeyNaming sets the naming strategy of the keys of untagged struct fields, for all loaders, environment variables,
+// command-line arguments and paths (e.g. decode.SnakeCase maps MaxConns to max_conns). Fields still match their Go name,
+// and tagged fields are not renamed.
+//
+// 	autoconfig.Load(yaml.New(filename), autoconfig.WithKeyNaming(decode.SnakeCase))
+func WithKeyNaming(n decode.Naming) Option {
+	return func(c *Config) {
+		c.decoding.Naming = n
+	}
+}
+
+// WithCaseInsensitiveKeys matches the keys of config files against struct fields regardless of their case.
+// Keys are matched case-sensitively by default.
+func WithCaseInsensitiveKeys() Option {
+	return func(c *Config) {
+		c.decoding.CaseInsensitive = true
+	}
+}
+
+// RegisterDecodeHook registers a function teaching c how to decode a custom type (e.g. a Money or LogLevel type),
+// once for all loaders, environment variables, command-line arguments and `default` tags.
+// The hook is called with each value of decoded documents before it is decoded to a value of type to,
+// and must return data as is for types it does not handle (see decode.Hook) :
+//
+// 	cfg.RegisterDecodeHook(func(from, to reflect.Type, data interface{}) (interface{}, error) {
+// 		if to != reflect.TypeOf(LogLevel(0)) || from.Kind() != reflect.String {
+// 			return data, nil
+// 		}
+// 		return ParseLogLevel(data.(string))
+// 	})
+//
+// Hooks only apply to c, and must be registered before sections whose `default` tags use them.
+func (c *Config) RegisterDecodeHook(h func(from, to reflect.Type, data interface{}) (interface{}, error)) {
+	c.decoding.Hooks = append(c.decoding.Hooks, h)
+}
+
+// RegisterDecodeHook registers a function teaching the default config how to decode a custom type.
+func RegisterDecodeHook(h func(from, to reflect.Type, data interface{}) (interface{}, error)) {
+	globalConfig.RegisterDecodeHook(h)
+}
+
+// OptionsLoader defines loaders decoding sections using the decoding options of the config loading them
+// (see WithKeyNaming, WithCaseInsensitiveKeys and RegisterDecodeHook). Loaders only implementing Loader decode sections
+// using the default options. Loaders wrapping other loaders pass the options down using LoadWith.
+type OptionsLoader interface {
+	LoadWith(cfg map[string]interface{}, o decode.Options) error
+}
+
+// LoadWith loads cfg using l, decoding sections using o if l implements OptionsLoader.
+func LoadWith(l Loader, cfg map[string]interface{}, o decode.Options) error {
+	if ol, ok := l.(OptionsLoader); ok {
+		return ol.LoadWith(cfg, o)
+	}
+	return l.Load(cfg)
+}
+
+// optionsLoader loads sections using l with the decoding options o, e.g. when l is passed to a function only calling Load.
+type optionsLoader struct {
+	l Loader
+	o decode.Options
+}
+
+func (ol optionsLoader) Load(cfg map[string]interface{}) error {
+	return LoadWith(ol.l, cfg, ol.o)
+}
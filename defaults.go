@@ -0,0 +1,95 @@
+package autoconfig
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// applyDefaultTags sets the zero fields of v tagged with `default:"..."` to the value of the tag, parsed as environment variables are
+// (see WithEnv), so that defaults can be declared next to fields, even when the registered value is a zero struct :
+//
+// 	type ServerConf struct {
+// 		Port    int           `yaml:"port" default:"8080"`
+// 		Timeout time.Duration `yaml:"timeout" default:"30s"`
+// 	}
+//
+// Fields of nested and embedded structs are set recursively, as well as the ones of the entries of maps of structs.
+func applyDefaultTags(path string, v reflect.Value, dec decode.Options) Errors {
+	v = reflect.Indirect(v)
+	if v.Kind() == reflect.Map {
+		return applyEntryDefaultTags(path, v, reflect.Value{}, dec)
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	errs := Errors{}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if (field.PkgPath != "" && !field.Anonymous) || isLock(field.Type) {
+			continue
+		}
+		f := v.Field(i)
+		fpath := path + "." + field.Name
+		if field.Anonymous {
+			// Fields of embedded structs are promoted
+			fpath = path
+		}
+		if field.PkgPath != "" {
+			if f.Kind() == reflect.Struct {
+				errs = append(errs, applyDefaultTags(fpath, f, dec)...)
+			}
+			continue
+		}
+		if tag, ok := field.Tag.Lookup("default"); ok && f.IsZero() {
+			if err := setFromLayout(f, tag, field.Tag.Get("layout"), dec); err != nil {
+				errs = append(errs, fmt.Errorf("Config: invalid default value %q for %s: %s", tag, fpath, err))
+			}
+			continue
+		}
+		if (f.Kind() == reflect.Struct || f.Kind() == reflect.Map || (f.Kind() == reflect.Ptr && !f.IsNil())) && !decode.IsText(f.Type()) {
+			// Nil pointers are left unset
+			errs = append(errs, applyDefaultTags(fpath, f, dec)...)
+		}
+	}
+	return errs
+}
+
+// applyEntryDefaultTags applies the default tags of the entries of the maps of structs of v (a decoded section) missing in defaults,
+// so that entries only present in config files get the default values of their fields.
+// Entries present in defaults already hold them, and are left untouched.
+func applyEntryDefaultTags(path string, v, defaults reflect.Value, dec decode.Options) Errors {
+	v = reflect.Indirect(v)
+	defaults = reflect.Indirect(defaults)
+	errs := Errors{}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() || !isNestedStruct(v.Type().Elem()) {
+			return nil
+		}
+		for _, key := range v.MapKeys() {
+			if defaults.Kind() == reflect.Map && defaults.MapIndex(key).IsValid() {
+				continue
+			}
+			// Map values are not addressable : tags are applied to a copy
+			e := reflect.New(v.Type().Elem()).Elem()
+			e.Set(v.MapIndex(key))
+			errs = append(errs, applyDefaultTags(fmt.Sprintf("%s[%v]", path, key), e, dec)...)
+			v.SetMapIndex(key, e)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" && !field.Anonymous || decode.IsText(field.Type) {
+				continue
+			}
+			var df reflect.Value
+			if defaults.Kind() == reflect.Struct && defaults.Type() == v.Type() {
+				df = defaults.Field(i)
+			}
+			errs = append(errs, applyEntryDefaultTags(path+"."+field.Name, v.Field(i), df, dec)...)
+		}
+	}
+	return errs
+}
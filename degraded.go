@@ -0,0 +1,64 @@
+package autoconfig
+
+import "log"
+
+// degraded holds the degradation policy of a config (see WithDegraded).
+type degraded struct {
+	after    int
+	loader   Loader
+	failures int
+	active   bool
+}
+
+// WithDegraded switches the config to a degraded section set after a number of consecutive failed reloads,
+// e.g. conservative limits loaded from a file embedded in the binary, so that services fail safe during prolonged
+// outages of a remote config source. The degraded values are loaded by l on top of the current values,
+// and listeners are notified with the ReasonDegraded reason.
+// The config leaves the degraded mode on the next successful reload.
+//
+// 	cfg := autoconfig.New(remoteLoader, autoconfig.WithDegraded(5, yaml.NewFS(embedded, "degraded.yaml")))
+func WithDegraded(after int, l Loader) Option {
+	return func(c *Config) {
+		c.degraded = &degraded{after: after, loader: l}
+	}
+}
+
+// Degraded returns true if the config is in degraded mode (see WithDegraded).
+func (c *Config) Degraded() bool {
+	return c.degraded != nil && c.degraded.active
+}
+
+// Degraded returns true if the default config is in degraded mode.
+func Degraded() bool {
+	return globalConfig.Degraded()
+}
+
+// checkDegraded counts consecutive failed reloads, and switches to the degraded section set when needed.
+func (c *Config) checkDegraded(res *ReloadResult) {
+	d := c.degraded
+	if d == nil {
+		return
+	}
+	if res.Err == nil {
+		if d.active {
+			log.Printf("Config: reload succeeded, leaving degraded mode")
+		}
+		d.failures = 0
+		d.active = false
+		return
+	}
+	d.failures++
+	if d.active || d.failures < d.after {
+		return
+	}
+	log.Printf("Config: %d consecutive failed reloads, switching to degraded mode", d.failures)
+	dres := c.loadFrom(d.loader, ReasonDegraded)
+	if dres.Err != nil {
+		log.Printf("Config: cannot load degraded config: %s", dres.Err)
+		return
+	}
+	d.active = true
+	res.Degraded = true
+	res.Changed = dres.Changed
+	res.Unchanged = dres.Unchanged
+}
@@ -0,0 +1,80 @@
+package autoconfig
+
+import "sort"
+
+// SectionOption defines an option of a section, to be passed to Register.
+type SectionOption func(*section)
+
+// DependsOn declares that a section depends on other sections.
+// When several sections change during a reload, listeners of the dependencies are notified before listeners of the section.
+//
+// 	autoconfig.Register("db", &dbConf, autoconfig.DependsOn("log"))
+func DependsOn(deps ...string) SectionOption {
+	return func(s *section) {
+		s.deps = append(s.deps, deps...)
+	}
+}
+
+// Priority sets the notification priority of a section : when several sections change during a reload,
+// listeners of sections with a higher priority are notified first (the default priority is 0), dependencies being still notified first
+// (see DependsOn). Sections having the same priority are notified in name order, and listeners of a section in registration order.
+//
+// 	autoconfig.Register("log", &logConf, autoconfig.Priority(100))
+func Priority(p int) SectionOption {
+	return func(s *section) {
+		s.priority = p
+	}
+}
+
+// order returns the names of all sections, dependencies first.
+// Sections are sorted by priority then name when they do not depend on each other, and sections involved in a cycle are returned last.
+func (c *Config) order() []string {
+	indegree := map[string]int{}
+	dependents := map[string][]string{}
+	for name, s := range c.sections {
+		indegree[name] += 0
+		for _, dep := range s.deps {
+			if _, found := c.sections[dep]; !found || dep == name {
+				continue
+			}
+			indegree[name]++
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+	ready := []string{}
+	for name, n := range indegree {
+		if n == 0 {
+			ready = append(ready, name)
+		}
+	}
+	order := make([]string, 0, len(c.sections))
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool {
+			pi, pj := c.sections[ready[i]].priority, c.sections[ready[j]].priority
+			if pi != pj {
+				return pi > pj
+			}
+			return ready[i] < ready[j]
+		})
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+		for _, d := range dependents[name] {
+			indegree[d]--
+			if indegree[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	if len(order) < len(c.sections) {
+		cycle := []string{}
+		for name, n := range indegree {
+			if n > 0 {
+				cycle = append(cycle, name)
+			}
+		}
+		sort.Strings(cycle)
+		order = append(order, cycle...)
+	}
+	return order
+}
@@ -0,0 +1,54 @@
+package autoconfig
+
+import "log"
+
+// DeriveFunc computes a derived section from the current values of its dependencies, passed in the order they were declared.
+type DeriveFunc func(deps ...interface{}) interface{}
+
+// RegisterDerived registers a section computed from other sections by fn.
+// The derived section is recomputed whenever one of its dependencies changes, and its listeners
+// (see Reconfigure) are notified if the computed value has changed.
+// Derived sections may depend on other derived sections, and are notified after their dependencies (see DependsOn).
+//
+// 	autoconfig.RegisterDerived("effective_limits", []string{"limits", "plan"}, func(deps ...interface{}) interface{} {
+// 		return computeLimits(deps[0].(*Limits), deps[1].(*Plan))
+// 	})
+func (c *Config) RegisterDerived(name string, deps []string, fn DeriveFunc) bool {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	if _, found := c.sections[name]; !found {
+		c.sectionsMu.Lock()
+		c.sections[name] = &section{name: name, onchange: []Reconfigurable{}}
+		c.sectionsMu.Unlock()
+	}
+	c.sections[name].deps = deps
+	c.sections[name].derive = fn
+	if c.loaded.Load() {
+		if _, errs := c.notify(map[string]bool{name: true}, ReasonManual); len(errs) > 0 {
+			log.Printf("Config: Cannot compute section %s: %s", name, errs)
+		}
+	}
+	return true
+}
+
+// RegisterDerived registers a section of the default config, computed from other sections by fn.
+func RegisterDerived(name string, deps []string, fn DeriveFunc) bool {
+	return globalConfig.RegisterDerived(name, deps, fn)
+}
+
+// recompute computes s again if needed, and returns true if it did.
+func (c *Config) recompute(s *section, force bool, changed map[string]bool) bool {
+	recompute := force || s.current == nil
+	for _, dep := range s.deps {
+		recompute = recompute || changed[dep]
+	}
+	if !recompute {
+		return false
+	}
+	values := make([]interface{}, len(s.deps))
+	for i, dep := range s.deps {
+		values[i], _ = c.Get(dep)
+	}
+	s.set(s.derive(values...))
+	return true
+}
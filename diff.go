@@ -0,0 +1,133 @@
+package autoconfig
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ReconfigurableFields can be implemented by Reconfigurable instances needing to know which fields of a section have changed,
+// e.g. to only run expensive actions when the relevant fields changed. changed holds the paths returned by Diff.
+// When implemented, ReconfigureFields is called instead of Reconfigure.
+//
+// 	func (c *PkgClass) ReconfigureFields(cfg interface{}, changed []string) {
+// 		for _, f := range changed {
+// 			if f == "Addr" {
+// 				c.reconnect(cfg.(*PkgConf).Addr)
+// 			}
+// 		}
+// 	}
+type ReconfigurableFields interface {
+	ReconfigureFields(cfg interface{}, changed []string)
+}
+
+// Diff returns the paths of the exported fields that differ between old and new, two values of the same type.
+// Paths use field names, and are sorted in field order (e.g. "Pool.Size", "Hosts[1]", "Limits[api]").
+// Nested structs are compared field by field, slices of the same length and maps item by item, other values as a whole.
+// If old is nil (e.g. for the first notification of a section), all top-level fields are returned.
+func Diff(old, new interface{}) []string {
+	n := reflect.Indirect(reflect.ValueOf(new))
+	if old == nil {
+		return diffValues("", reflect.Value{}, n)
+	}
+	return diffValues("", reflect.Indirect(reflect.ValueOf(old)), n)
+}
+
+// ChangedFields returns the paths of the fields that changed, see Diff.
+func (ev Event) ChangedFields() []string {
+	return Diff(ev.Previous, ev.Config)
+}
+
+func diffValues(path string, o, n reflect.Value) []string {
+	if !o.IsValid() {
+		switch n.Kind() {
+		case reflect.Struct:
+			changed := []string{}
+			for i := 0; i < n.NumField(); i++ {
+				if f := n.Type().Field(i); f.PkgPath == "" && !isLock(f.Type) {
+					changed = append(changed, joinPath(path, f.Name))
+				}
+			}
+			return changed
+		case reflect.Map:
+			changed := []string{}
+			for _, k := range sortedKeys(n) {
+				changed = append(changed, mapPath(path, k))
+			}
+			return changed
+		}
+		return []string{path}
+	}
+	switch n.Kind() {
+	case reflect.Ptr:
+		if o.IsNil() || n.IsNil() {
+			if o.IsNil() != n.IsNil() {
+				return []string{path}
+			}
+			return nil
+		}
+		return diffValues(path, o.Elem(), n.Elem())
+	case reflect.Struct:
+		changed := []string{}
+		for i := 0; i < n.NumField(); i++ {
+			if f := n.Type().Field(i); f.PkgPath == "" && !isLock(f.Type) {
+				changed = append(changed, diffValues(joinPath(path, f.Name), o.Field(i), n.Field(i))...)
+			}
+		}
+		return changed
+	case reflect.Slice, reflect.Array:
+		if o.Len() != n.Len() {
+			return []string{path}
+		}
+		changed := []string{}
+		for i := 0; i < n.Len(); i++ {
+			changed = append(changed, diffValues(fmt.Sprintf("%s[%d]", path, i), o.Index(i), n.Index(i))...)
+		}
+		return changed
+	case reflect.Map:
+		changed := []string{}
+		keys := sortedKeys(n)
+		for _, k := range sortedKeys(o) {
+			if !n.MapIndex(k).IsValid() {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			ov, nv := o.MapIndex(k), n.MapIndex(k)
+			if !ov.IsValid() || !nv.IsValid() {
+				changed = append(changed, mapPath(path, k))
+				continue
+			}
+			changed = append(changed, diffValues(mapPath(path, k), ov, nv)...)
+		}
+		return changed
+	}
+	if !reflect.DeepEqual(o.Interface(), n.Interface()) {
+		return []string{path}
+	}
+	return nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// mapPath returns the path of an item of a map. Items of map sections are named after their key.
+func mapPath(path string, k reflect.Value) string {
+	if path == "" {
+		return fmt.Sprint(k.Interface())
+	}
+	return fmt.Sprintf("%s[%v]", path, k.Interface())
+}
+
+// sortedKeys returns the keys of the map m, sorted by their string representation.
+func sortedKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
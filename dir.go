@@ -0,0 +1,62 @@
+package autoconfig
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+type dirLoader struct {
+	pattern string
+	format  func(filename string) Loader
+}
+
+// Dir creates a loader loading every file matching pattern (e.g. /etc/myapp/conf.d/*.yaml) in lexical order,
+// values of later files overriding earlier ones key by key (see Multi).
+// Files are decoded by the loader returned by format. The directory is read again on each reload,
+// so that config fragments can be dropped in or removed without editing a shared file.
+//
+// 	autoconfig.Load(autoconfig.Dir("/etc/myapp/conf.d/*.yaml", func(f string) autoconfig.Loader {
+// 		return yaml.New(f)
+// 	}))
+func Dir(pattern string, format func(filename string) Loader) Loader {
+	return dirLoader{pattern: pattern, format: format}
+}
+
+func (d dirLoader) Load(cfg map[string]interface{}) error {
+	return d.LoadWith(cfg, decode.Options{})
+}
+
+func (d dirLoader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	m, err := d.loaders()
+	if err != nil {
+		return err
+	}
+	return m.LoadWith(cfg, o)
+}
+
+func (d dirLoader) Raw() (map[string]interface{}, error) {
+	m, err := d.loaders()
+	if err != nil {
+		return nil, err
+	}
+	return m.Raw()
+}
+
+func (d dirLoader) loaders() (multiLoader, error) {
+	files, err := filepath.Glob(d.pattern)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	m := multiLoader{}
+	for _, f := range files {
+		if fi, err := os.Stat(f); err != nil || fi.IsDir() {
+			continue
+		}
+		m = append(m, d.format(f))
+	}
+	return m, nil
+}
@@ -0,0 +1,40 @@
+package autoconfig
+
+import "github.com/jfbus/autoconfig/decode"
+
+// DocumentLoader defines loaders able to read their source once, and to return a function decoding sections from what has been read
+// (e.g. the decoded config file). Their source is read once per reload, instead of once per overlay (see WithProfile and WithApp),
+// and once per section when sections are decoded one by one after a load has failed (e.g. a section cannot be decoded).
+// Sections are decoded using the decoding options passed to load (see OptionsLoader).
+type DocumentLoader interface {
+	Document() (load func(cfg map[string]interface{}, o decode.Options) error, err error)
+}
+
+// documentLoader decodes sections from a document read by a DocumentLoader.
+type documentLoader func(cfg map[string]interface{}, o decode.Options) error
+
+func (d documentLoader) Load(cfg map[string]interface{}) error {
+	return d(cfg, decode.Options{})
+}
+
+func (d documentLoader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	return d(cfg, o)
+}
+
+// document returns a loader decoding sections from a single read of the source of l if l implements DocumentLoader,
+// so that the source is read once per reload, whatever the number of overlays (see WithProfile) and of isolated sections.
+// Other loaders are returned as is. Panics of l are returned as errors.
+func document(l Loader) (Loader, error) {
+	dl, ok := l.(DocumentLoader)
+	if !ok {
+		return l, nil
+	}
+	var load func(map[string]interface{}, decode.Options) error
+	if err := protect("loader", func() (err error) {
+		load, err = dl.Document()
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return documentLoader(load), nil
+}
@@ -0,0 +1,55 @@
+package autoconfig
+
+import "sync"
+
+// Check loads the config into scratch copies of all registered sections, and returns the errors that a reload would return
+// (lint rules, decoding, enums, frozen sections), without applying anything nor notifying listeners.
+// It allows operators to verify a new config file before reloading it.
+// Check waits for a running reload to complete, it must not be called by listeners.
+func (c *Config) Check() error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	l := c.mainLoader()
+	if l == nil {
+		return ErrNoLoader
+	}
+	if _, err := c.lint(l); err != nil {
+		return err
+	}
+	targets := map[string]interface{}{}
+	for name, s := range c.sections {
+		if s.derive == nil && s.current != nil {
+			targets[name] = s.copy()
+		}
+	}
+	if err := c.loadTargets(l, targets); err != nil {
+		return err
+	}
+	if err := c.applyEnv(targets); err != nil {
+		return err
+	}
+	if err := c.applyArgs(targets); err != nil {
+		return err
+	}
+	if err := c.applyOverrides(targets); err != nil {
+		return err
+	}
+	if err := c.check(targets); err != nil {
+		return err
+	}
+	return c.checkFrozen(targets)
+}
+
+// Check loads the default config into scratch copies of all registered sections, and returns the errors that a reload would return.
+func Check() error {
+	return globalConfig.Check()
+}
+
+// copy returns a deep copy of the current value of the section, locking it if it implements sync.Locker.
+func (s *section) copy() interface{} {
+	if l, ok := s.current.(sync.Locker); ok && !s.fresh {
+		l.Lock()
+		defer l.Unlock()
+	}
+	return deepCopy(s.current)
+}
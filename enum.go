@@ -0,0 +1,72 @@
+package autoconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// EnumError is returned when a field tagged with `enum:"..."` has a value that is not in the declared set.
+type EnumError struct {
+	Field   string
+	Value   string
+	Allowed []string
+}
+
+func (e *EnumError) Error() string {
+	return fmt.Sprintf("Config: invalid value %q for %s, expected one of %s", e.Value, e.Field, strings.Join(e.Allowed, ", "))
+}
+
+// checkEnums checks that string (and []string) fields tagged with `enum:"a,b,c"` have one of the declared values.
+// Values are matched case-insensitively, and normalized to the declared case.
+//
+// 	type LogConf struct {
+// 		Level string `yaml:"level" enum:"debug,info,warn,error"`
+// 	}
+func checkEnums(path string, v reflect.Value) Errors {
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	errs := Errors{}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		f := v.Field(i)
+		fpath := path + "." + field.Name
+		tag := field.Tag.Get("enum")
+		switch {
+		case tag != "" && f.Kind() == reflect.String:
+			if err := checkEnum(fpath, f, strings.Split(tag, ",")); err != nil {
+				errs = append(errs, err)
+			}
+		case tag != "" && f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.String:
+			for j := 0; j < f.Len(); j++ {
+				if err := checkEnum(fmt.Sprintf("%s[%d]", fpath, j), f.Index(j), strings.Split(tag, ",")); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		case f.Kind() == reflect.Struct || (f.Kind() == reflect.Ptr && !f.IsNil()):
+			errs = append(errs, checkEnums(fpath, f)...)
+		}
+	}
+	return errs
+}
+
+func checkEnum(path string, f reflect.Value, allowed []string) error {
+	// Unset values are left to defaults/required checks
+	if f.String() == "" {
+		return nil
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(f.String(), a) {
+			if f.CanSet() {
+				f.SetString(a)
+			}
+			return nil
+		}
+	}
+	return &EnumError{Field: path, Value: f.String(), Allowed: allowed}
+}
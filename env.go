@@ -0,0 +1,172 @@
+package autoconfig
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// WithEnv overrides the values loaded by the loader with environment variables, e.g. to vary a few values of a config file
+// baked into a container image. Each field can be set using the <PREFIX>_<SECTION>_<FIELD> variable, nested struct fields
+// using <PREFIX>_<SECTION>_<FIELD>_<SUBFIELD>. Fields are named after their `env` tag, or their yaml/ini/json tag, or their name.
+// Names are uppercased, and characters other than letters and digits replaced by underscores.
+//
+// 	autoconfig.Load(yaml.New(filename), autoconfig.WithEnv("MYAPP"))
+//
+// 	MYAPP_DB_POOL_SIZE=50 ./myapp
+//
+// Slices are set from comma-separated values.
+func WithEnv(prefix string) Option {
+	return func(c *Config) {
+		c.envPrefix = prefix
+	}
+}
+
+// applyEnv sets the fields of targets having an environment variable.
+func (c *Config) applyEnv(targets map[string]interface{}) error {
+	if c.envPrefix == "" {
+		return nil
+	}
+	errs := Errors{}
+	for name, t := range targets {
+		errs = append(errs, setFromEnv(envName(c.envPrefix, name), reflect.ValueOf(t), c.decoding)...)
+	}
+	return errs.errorOrNil()
+}
+
+func setFromEnv(prefix string, v reflect.Value, dec decode.Options) Errors {
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	errs := Errors{}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Tag.Get("env")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = envName(prefix, fieldKey(field, dec))
+		}
+		f := v.Field(i)
+		if f.Kind() == reflect.Struct && !decode.IsText(f.Type()) {
+			errs = append(errs, setFromEnv(name, f, dec)...)
+			continue
+		}
+		if f.Kind() == reflect.Ptr && f.Type().Elem().Kind() == reflect.Struct && !decode.IsText(f.Type()) {
+			// Nil pointers to structs are only allocated if one of their fields is set
+			p := f
+			if f.IsNil() {
+				p = reflect.New(f.Type().Elem())
+			}
+			errs = append(errs, setFromEnv(name, p, dec)...)
+			if f.IsNil() && !p.Elem().IsZero() {
+				f.Set(p)
+			}
+			continue
+		}
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setFromLayout(f, val, field.Tag.Get("layout"), dec); err != nil {
+			errs = append(errs, fmt.Errorf("Config: invalid value %q for %s: %s", val, name, err))
+		}
+	}
+	return errs
+}
+
+// fieldKey returns the name of a field in config files : its config, yaml, ini or json tag, or its name (see WithKeyNaming).
+func fieldKey(field reflect.StructField, dec decode.Options) string {
+	for _, tag := range []string{"config", "yaml", "ini", "json"} {
+		if name := strings.Split(field.Tag.Get(tag), ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return dec.Name(field.Name)
+}
+
+// envName returns the name of the environment variable of a section or field.
+func envName(prefix, name string) string {
+	return prefix + "_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// setFromString sets a value from its string representation.
+func setFromString(v reflect.Value, s string, dec decode.Options) error {
+	return setFromLayout(v, s, "", dec)
+}
+
+// setFromLayout sets a value from its string representation, using the decode hooks of dec first (see RegisterDecodeHook),
+// time.Time values being parsed using layout (see decode.Text).
+func setFromLayout(v reflect.Value, s, layout string, dec decode.Options) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setFromLayout(v.Elem(), s, layout, dec)
+	}
+	if ok, err := dec.Hooked(v, s); ok {
+		return err
+	}
+	if v.CanAddr() {
+		if ok, err := decode.Text(v, s, layout); ok {
+			return err
+		}
+	}
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		i, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+		u, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case v.Kind() == reflect.Slice:
+		parts := strings.Split(s, ",")
+		sl := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setFromLayout(sl.Index(i), strings.TrimSpace(p), layout, dec); err != nil {
+				return err
+			}
+		}
+		v.Set(sl)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package autoconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Errors aggregates the errors of several sections or fields.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the aggregated errors, so that errors.Is and errors.As can match any of them.
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// add appends err to e, flattening aggregated errors.
+func (e Errors) add(err error) Errors {
+	if errs, ok := err.(Errors); ok {
+		return append(e, errs...)
+	}
+	return append(e, err)
+}
+
+// errorOrNil returns nil if errs is empty, errs otherwise.
+func (e Errors) errorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// DecodeError is returned when a section cannot be decoded. The section keeps its previous value, while other sections are still updated.
+type DecodeError struct {
+	Section string
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("Config: cannot decode section %s : %s", e.Section, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
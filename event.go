@@ -0,0 +1,84 @@
+package autoconfig
+
+// Reason describes why a section has been notified.
+type Reason string
+
+const (
+	// ReasonInitialLoad is the reason of the first notification of each section
+	ReasonInitialLoad Reason = "initial-load"
+	// ReasonFileChange is used when a watcher has detected a change of the config source
+	ReasonFileChange Reason = "file-change"
+	// ReasonSignal is used for reloads triggered by a signal (see ReloadOn)
+	ReasonSignal Reason = "signal"
+	// ReasonTrigger is used for reloads triggered using ReloadOnChan
+	ReasonTrigger Reason = "trigger"
+	// ReasonManual is used for reloads triggered by the application (Load, Reload, SetSectionFromJSON...)
+	ReasonManual Reason = "manual"
+	// ReasonRollback is used when a section is reverted to a previous value
+	ReasonRollback Reason = "rollback"
+	// ReasonOverrideExpired is used when a temporary override has expired
+	ReasonOverrideExpired Reason = "override-expired"
+	// ReasonDegraded is used when the config switches to degraded mode after repeated reload failures (see WithDegraded)
+	ReasonDegraded Reason = "degraded"
+)
+
+// Event describes a change of a section.
+// Previous is a copy of the value previously notified to listeners, nil for the first notification of a section.
+type Event struct {
+	Section  string
+	Reason   Reason
+	Config   interface{}
+	Previous interface{}
+}
+
+// ReconfigurableEvent can be implemented by Reconfigurable instances needing to know why they are notified.
+// When implemented, ReconfigureEvent is called instead of Reconfigure.
+//
+// 	func (c *PkgClass) ReconfigureEvent(ev autoconfig.Event) {
+// 		if ev.Reason != autoconfig.ReasonInitialLoad {
+// 			c.cache.Flush()
+// 		}
+// 	}
+type ReconfigurableEvent interface {
+	ReconfigureEvent(Event)
+}
+
+// ReconfigurableDiff can be implemented by Reconfigurable instances needing both the previous and the new values of a section,
+// e.g. to only reopen connections when the address has changed. old is a copy of the value previously notified, nil for the first notification.
+// When implemented, ReconfigureDiff is called instead of Reconfigure.
+//
+// 	func (c *PkgClass) ReconfigureDiff(old, new interface{}) {
+// 		if old == nil || old.(*PkgConf).Addr != new.(*PkgConf).Addr {
+// 			c.reconnect(new.(*PkgConf).Addr)
+// 		}
+// 	}
+type ReconfigurableDiff interface {
+	ReconfigureDiff(old, new interface{})
+}
+
+// UpdatableConfigEvent can be implemented by UpdatableConfig section structs needing to know why they are notified.
+// When implemented, ChangedEvent is called instead of Changed.
+type UpdatableConfigEvent interface {
+	ChangedEvent(Event)
+}
+
+// deliver notifies r of ev, using ReconfigureErr, ReconfigureDiff, ReconfigureFields or ReconfigureEvent if implemented, and returns the error of listeners rejecting ev.
+func deliver(r Reconfigurable, ev Event) error {
+	switch l := r.(type) {
+	case *reconfigurableCfg:
+		return l.changed(ev)
+	case *Handle:
+		return deliver(l.r, ev)
+	case ReconfigurableErr:
+		return l.ReconfigureErr(ev.Config)
+	case ReconfigurableDiff:
+		l.ReconfigureDiff(ev.Previous, ev.Config)
+	case ReconfigurableFields:
+		l.ReconfigureFields(ev.Config, ev.ChangedFields())
+	case ReconfigurableEvent:
+		l.ReconfigureEvent(ev)
+	default:
+		r.Reconfigure(ev.Config)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package autoconfig
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// Sample returns a sample document of all registered sections, e.g. to generate a sample config file or documentation.
+// Field values are taken from the `example` tag if set, or from the current value of the section otherwise
+// (i.e. its defaults when called before Load).
+// Keys are named after the config tag of fields, the tag of the target format (e.g. "yaml"), or field names.
+//
+// 	type ServerConf struct {
+// 		Listen string `yaml:"listen" example:"0.0.0.0:8080"`
+// 		Peers  []string `yaml:"peers" example:"[\"10.0.0.1\", \"10.0.0.2\"]"`
+// 	}
+//
+// 	out, _ := yaml.Marshal(autoconfig.Sample("yaml"))
+//
+// Example values of non-string fields are decoded as JSON, and kept as strings if they cannot be.
+func (c *Config) Sample(tag string) map[string]interface{} {
+	doc := map[string]interface{}{}
+	for name, s := range c.registered() {
+		v := s.get()
+		if v == nil || s.derive != nil {
+			continue
+		}
+		doc[name] = sampleValue(reflect.ValueOf(v), tag, c.decoding)
+	}
+	return doc
+}
+
+// Sample returns a sample document of all sections of the default config.
+func Sample(tag string) map[string]interface{} {
+	return globalConfig.Sample(tag)
+}
+
+func sampleValue(v reflect.Value, tag string, dec decode.Options) interface{} {
+	v = reflect.Indirect(v)
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Kind() != reflect.Struct {
+		return v.Interface()
+	}
+	out := map[string]interface{}{}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		key := dec.Key(field, tag)
+		if key == "-" {
+			continue
+		}
+		if ex, ok := field.Tag.Lookup("example"); ok {
+			out[key] = exampleValue(field.Type, ex)
+		} else {
+			out[key] = sampleValue(v.Field(i), tag, dec)
+		}
+	}
+	return out
+}
+
+func exampleValue(t reflect.Type, ex string) interface{} {
+	if t.Kind() == reflect.String {
+		return ex
+	}
+	p := reflect.New(t)
+	if err := json.Unmarshal([]byte(ex), p.Interface()); err != nil {
+		return ex
+	}
+	return p.Elem().Interface()
+}
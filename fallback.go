@@ -0,0 +1,126 @@
+package autoconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+type fallbackLoader struct {
+	primary, secondary Loader
+}
+
+// Fallback creates a loader loading sections from primary, or from secondary if primary fails,
+// e.g. to use a local file when a remote backend is unreachable :
+//
+// 	autoconfig.Load(autoconfig.Fallback(remoteLoader, yaml.New("/etc/myapp/config.yaml")))
+//
+// Sections are left untouched by a failing primary loader.
+func Fallback(primary, secondary Loader) Loader {
+	return fallbackLoader{primary: primary, secondary: secondary}
+}
+
+func (f fallbackLoader) Load(cfg map[string]interface{}) error {
+	return f.LoadWith(cfg, decode.Options{})
+}
+
+func (f fallbackLoader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	copies := map[string]interface{}{}
+	for name, t := range cfg {
+		copies[name] = deepCopy(t)
+	}
+	err := protect("loader", func() error { return LoadWith(f.primary, copies, o) })
+	if err == nil {
+		for name, t := range cfg {
+			assign(t, copies[name])
+		}
+		return nil
+	}
+	log.Printf("Config: primary loader failed, using fallback : %s", err)
+	if ferr := LoadWith(f.secondary, cfg, o); ferr != nil {
+		return fmt.Errorf("Config: primary loader failed (%s), fallback failed : %w", err, ferr)
+	}
+	return nil
+}
+
+// Raw returns the raw document of primary, or of secondary if primary fails.
+func (f fallbackLoader) Raw() (map[string]interface{}, error) {
+	if rl, ok := f.primary.(RawLoader); ok {
+		if doc, err := rl.Raw(); err == nil {
+			return doc, nil
+		}
+	}
+	if rl, ok := f.secondary.(RawLoader); ok {
+		return rl.Raw()
+	}
+	return map[string]interface{}{}, nil
+}
+
+// Cache creates a middleware saving the sections to filename after each successful load,
+// and loading the last known good sections from filename when the wrapped loader fails,
+// instead of returning an error on every reload while a remote backend is down :
+//
+// 	autoconfig.Load(remoteLoader, autoconfig.WithMiddleware(autoconfig.Cache("/var/cache/myapp/config.json")))
+//
+// The cache file uses the Export format. It is an error if the wrapped loader fails and there is no cache file yet.
+func Cache(filename string) LoaderMiddleware {
+	return loaderFunc(func(next Loader, cfg map[string]interface{}, o decode.Options) error {
+		if err := LoadWith(next, cfg, o); err != nil {
+			if _, serr := os.Stat(filename); serr != nil {
+				return err
+			}
+			snap := readCache(filename)
+			if len(snap.Sections) == 0 {
+				log.Printf("Config: ignoring invalid cache file %s", filename)
+				return err
+			}
+			log.Printf("Config: loader failed, using cache file %s : %s", filename, err)
+			return snapshotLoader(snap).LoadWith(cfg, o)
+		}
+		if err := writeCache(filename, cfg, o); err != nil {
+			log.Printf("Config: cannot write cache file %s : %s", filename, err)
+		}
+		return nil
+	})
+}
+
+// writeCache atomically writes the snapshot of cfg, encoded using the key options o, to filename.
+// The file may contain secrets, it is only readable by its owner.
+// The sections of cfg are merged into the sections already cached, as single sections may be loaded (e.g. sections registered after
+// the config has been loaded).
+func writeCache(filename string, cfg map[string]interface{}, o decode.Options) error {
+	snap := readCache(filename)
+	for name, t := range cfg {
+		raw, err := encodeJSON(t, o)
+		if err != nil {
+			return err
+		}
+		snap.Sections[name] = snapshotSection{Type: typeName(t), Value: raw}
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// readCache reads the snapshot cached in filename. It returns an empty snapshot if the file is missing or invalid.
+func readCache(filename string) snapshot {
+	snap := snapshot{}
+	data, err := ioutil.ReadFile(filename)
+	if err == nil {
+		err = json.Unmarshal(data, &snap)
+	}
+	if err != nil || snap.Version != snapshotVersion || snap.Sections == nil {
+		return snapshot{Version: snapshotVersion, Sections: map[string]snapshotSection{}}
+	}
+	return snap
+}
@@ -0,0 +1,115 @@
+package autoconfig
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// BindFlags defines a flag on fs for each field of the registered sections, named section.key (see WithArgs for key names).
+// Values of parsed flags override values from the loader and environment variables, on each load.
+// Flag usages are taken from the `usage` tag of fields.
+// BindFlags must be called after sections are registered, and before fs is parsed :
+//
+// 	autoconfig.BindFlags(flag.CommandLine)
+// 	flag.Parse()
+// 	autoconfig.Load(yaml.New(filename))
+func (c *Config) BindFlags(fs *flag.FlagSet) {
+	sections := c.registered()
+	names := make([]string, 0, len(sections))
+	for name, s := range sections {
+		if s.get() != nil && s.derive == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.bindFlags(fs, name, reflect.ValueOf(sections[name].get()))
+	}
+}
+
+// BindFlags defines a flag on fs for each field of the sections of the default config.
+func BindFlags(fs *flag.FlagSet) {
+	globalConfig.BindFlags(fs)
+}
+
+func (c *Config) bindFlags(fs *flag.FlagSet, prefix string, v reflect.Value) {
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := prefix + "." + fieldKey(field, c.decoding)
+		f := reflect.Indirect(v.Field(i))
+		unset := !f.IsValid()
+		if unset {
+			// Nil pointer : the flag sets the pointed value, and has no default
+			f = reflect.Zero(field.Type.Elem())
+		}
+		if f.Kind() == reflect.Struct && !decode.IsText(f.Type()) {
+			c.bindFlags(fs, name, f)
+			continue
+		}
+		if fs.Lookup(name) != nil {
+			continue
+		}
+		def := formatValue(f)
+		if setFromString(reflect.New(f.Type()).Elem(), def, c.decoding) != nil {
+			// Unsupported type
+			continue
+		}
+		if unset {
+			def = ""
+		}
+		fs.Var(&argFlag{c: c, name: name, typ: f.Type(), value: def}, name, field.Tag.Get("usage"))
+	}
+}
+
+// formatValue formats a value as parsed by setFromString
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Slice {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = formatValue(v.Index(i))
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// argFlag is a flag setting a command-line override.
+type argFlag struct {
+	c     *Config
+	name  string
+	typ   reflect.Type
+	value string
+}
+
+func (a *argFlag) String() string {
+	if a == nil {
+		return ""
+	}
+	return a.value
+}
+
+func (a *argFlag) Set(s string) error {
+	if err := setFromString(reflect.New(a.typ).Elem(), s, a.c.decoding); err != nil {
+		return err
+	}
+	a.value = s
+	a.c.args[a.name] = s
+	return nil
+}
+
+// IsBoolFlag allows boolean flags to be set without a value
+func (a *argFlag) IsBoolFlag() bool {
+	return a.typ.Kind() == reflect.Bool
+}
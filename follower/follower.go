@@ -0,0 +1,267 @@
+// Package follower allows a single process on a host (the leader) to load, validate and watch the config,
+// and to serve it over a local unix socket to other processes (the followers), instead of all of them parsing and watching the same files.
+//
+// Leader :
+// 	autoconfig.Load(yaml.New(filename))
+// 	autoconfig.ReloadOn(syscall.SIGHUP)
+// 	srv, err := follower.Serve(autoconfig.Default(), "/run/myapp/config.sock", time.Second)
+//
+// Followers :
+// 	l := follower.New("/run/myapp/config.sock")
+// 	cfg := autoconfig.New(l)
+// 	cfg.Load()
+// 	l.Watch(cfg)
+//
+// The leader streams snapshots written by Config.ExportRedacted, sections are keyed as config files are (see decode.Encode), and decoded by followers as loaders decode them.
+// Secret fields are written according to the secret policy of the leader, and are not served if no policy has been set.
+// The socket is only accessible by the user running the leader.
+package follower
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jfbus/autoconfig"
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// retryDelay is the delay before connecting again to the leader when the connection has been lost
+var retryDelay = time.Second
+
+// writeTimeout is the delay after which followers not reading the config are disconnected
+var writeTimeout = 5 * time.Second
+
+// Leader defines the interface of configs that can be served to followers (e.g. *autoconfig.Config).
+type Leader interface {
+	ExportRedacted(w io.Writer) error
+	Every(interval time.Duration, fn func()) (stop func())
+}
+
+// Reloader defines the interface of configs that can be reloaded (e.g. *autoconfig.Config).
+type Reloader interface {
+	TriggerReload(reason string) *autoconfig.ReloadResult
+}
+
+// Server serves the config of the leader to followers.
+type Server struct {
+	l    net.Listener
+	stop func()
+
+	mu    sync.Mutex
+	last  []byte
+	conns map[net.Conn]bool
+
+	// sendMu serializes sends, so that followers receive snapshots in order
+	sendMu sync.Mutex
+}
+
+// Serve listens on the unix socket path, and sends the config of c to followers when they connect,
+// then each time it has changed. c is checked for changes every interval.
+// It is an error if another leader is already serving on path, or if path is not a socket.
+func Serve(c Leader, path string, interval time.Duration) (*Server, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("follower: a leader is already serving on %s", path)
+	}
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("follower: %s exists and is not a socket", path)
+		}
+		// Remove the socket left by a previous leader
+		os.Remove(path)
+	}
+	l, err := listen(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{l: l, conns: map[net.Conn]bool{}}
+	s.publish(c)
+	s.stop = c.Every(interval, func() {
+		s.publish(c)
+	})
+	go s.accept()
+	return s, nil
+}
+
+// Close stops serving the config, and disconnects followers
+func (s *Server) Close() error {
+	s.stop()
+	err := s.l.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	return err
+}
+
+func (s *Server) publish(c Leader) {
+	buf := &bytes.Buffer{}
+	if err := c.ExportRedacted(buf); err != nil {
+		log.Printf("follower: cannot export config: %s", err)
+		return
+	}
+	buf.WriteByte('\n')
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	s.mu.Lock()
+	if bytes.Equal(buf.Bytes(), s.last) {
+		s.mu.Unlock()
+		return
+	}
+	s.last = buf.Bytes()
+	conns := make([]net.Conn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.mu.Unlock()
+	for _, conn := range conns {
+		s.send(conn, buf.Bytes())
+	}
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		s.sendMu.Lock()
+		s.mu.Lock()
+		last := s.last
+		s.conns[conn] = true
+		s.mu.Unlock()
+		s.send(conn, last)
+		s.sendMu.Unlock()
+	}
+}
+
+// send writes data to conn, which is disconnected if it cannot be written before writeTimeout.
+func (s *Server) send(conn net.Conn, data []byte) {
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := conn.Write(data); err != nil {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}
+}
+
+// snapshot is the format written by Config.ExportRedacted
+type snapshot struct {
+	Version  int `json:"version"`
+	Sections map[string]struct {
+		Value json.RawMessage `json:"value"`
+	} `json:"sections"`
+}
+
+// Loader loads the config served by the leader.
+type Loader struct {
+	path string
+
+	mu   sync.Mutex
+	last *snapshot
+}
+
+// New creates a Loader for the config served on the unix socket path
+func New(path string) *Loader {
+	return &Loader{path: path}
+}
+
+// Load unmarshals the last config received from the leader (see Watch), or fetches the current one.
+func (l *Loader) Load(cfg map[string]interface{}) error {
+	return l.LoadWith(cfg, decode.Options{})
+}
+
+// LoadWith unmarshals the last config received from the leader to cfg using the decoding options o
+func (l *Loader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	l.mu.Lock()
+	snap := l.last
+	l.mu.Unlock()
+	if snap == nil {
+		conn, err := net.Dial("unix", l.path)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		snap = &snapshot{}
+		if err := json.NewDecoder(conn).Decode(snap); err != nil {
+			return fmt.Errorf("follower: cannot read config from %s: %s", l.path, err)
+		}
+	}
+	for name, scfg := range cfg {
+		ss, ok := snap.Sections[name]
+		if !ok {
+			continue
+		}
+		if err := o.JSON(ss.Value, scfg); err != nil {
+			return fmt.Errorf("follower: section %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// Watch receives the config each time it changes on the leader, and reloads r.
+// Followers connect again when the leader restarts. Calling the returned function stops watching.
+func (l *Loader) Watch(r Reloader) (stop func()) {
+	done := make(chan struct{})
+	var (
+		mu   sync.Mutex
+		conn net.Conn
+	)
+	go func() {
+		for {
+			c, err := net.Dial("unix", l.path)
+			if err == nil {
+				mu.Lock()
+				conn = c
+				mu.Unlock()
+				select {
+				case <-done:
+					c.Close()
+					return
+				default:
+				}
+				l.watch(c, r)
+				c.Close()
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(retryDelay):
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			mu.Lock()
+			if conn != nil {
+				conn.Close()
+			}
+			mu.Unlock()
+		})
+	}
+}
+
+func (l *Loader) watch(conn net.Conn, r Reloader) {
+	dec := json.NewDecoder(conn)
+	for {
+		snap := &snapshot{}
+		if err := dec.Decode(snap); err != nil {
+			return
+		}
+		l.mu.Lock()
+		l.last = snap
+		l.mu.Unlock()
+		r.TriggerReload(string(autoconfig.ReasonFileChange))
+	}
+}
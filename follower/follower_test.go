@@ -0,0 +1,106 @@
+package follower
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jfbus/autoconfig"
+	"github.com/jfbus/autoconfig/yaml"
+)
+
+type testCfg struct {
+	Key      string `json:"key" yaml:"key"`
+	Password string `json:"password" yaml:"password" secret:"true"`
+}
+
+// testLoader loads its raw yaml document, which can be changed between reloads
+type testLoader struct {
+	mu  sync.Mutex
+	raw string
+}
+
+func (l *testLoader) Load(cfg map[string]interface{}) error {
+	l.mu.Lock()
+	raw := l.raw
+	l.mu.Unlock()
+	return yaml.NewFromBytes([]byte(raw)).Load(cfg)
+}
+
+func (l *testLoader) update(raw string) {
+	l.mu.Lock()
+	l.raw = raw
+	l.mu.Unlock()
+}
+
+// testListener receives the keys of the reloaded section
+type testListener chan string
+
+func (l testListener) Reconfigure(cfg interface{}) {
+	l <- cfg.(*testCfg).Key
+}
+
+func TestServe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.sock")
+	ll := &testLoader{raw: "section:\n  key: one\n  password: p4ss\n"}
+	// Sections are exported while being reloaded
+	leader := autoconfig.New(ll, autoconfig.WithCopyOnWrite())
+	leader.Register("section", &testCfg{})
+	if err := leader.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	srv, err := Serve(leader, path, 5*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Serve() returned %s", err)
+	}
+	defer srv.Close()
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("The socket should only be accessible by its owner, got %v", fi.Mode())
+	}
+	if _, err := Serve(leader, path, time.Second); err == nil {
+		t.Error("Serve() should fail when a leader is already serving")
+	}
+
+	l := New(path)
+	follower := autoconfig.New(l)
+	c := &testCfg{}
+	follower.Register("section", c)
+	if err := follower.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "one" || c.Password != "" {
+		t.Errorf("Followers should load the redacted config of the leader, got %+v", *c)
+	}
+	changes := make(testListener, 10)
+	follower.Reconfigure("section", changes)
+	stop := l.Watch(follower)
+	defer stop()
+	ll.update("section:\n  key: two\n")
+	leader.TriggerReload("test")
+	for {
+		select {
+		case key := <-changes:
+			if key == "two" {
+				return
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Followers should be reloaded when the config of the leader changes")
+		}
+	}
+}
+
+func TestServeNotSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(path, []byte("section: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Serve(autoconfig.New(nil), path, time.Second); err == nil {
+		t.Error("Serve() should not replace a file that is not a socket")
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "section: {}\n" {
+		t.Errorf("The file should be left untouched, got %q", data)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !unix
+
+package follower
+
+import (
+	"net"
+	"os"
+)
+
+// listen listens on the unix socket path, and restricts it to the current user.
+func listen(path string) (net.Listener, error) {
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
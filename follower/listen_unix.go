@@ -0,0 +1,16 @@
+//go:build unix
+
+package follower
+
+import (
+	"net"
+	"syscall"
+)
+
+// listen listens on the unix socket path, created with the 0600 mode, so that other users cannot connect to it
+// before its mode is set. The umask of the process is changed while the socket is created.
+func listen(path string) (net.Listener, error) {
+	mask := syscall.Umask(0177)
+	defer syscall.Umask(mask)
+	return net.Listen("unix", path)
+}
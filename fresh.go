@@ -0,0 +1,67 @@
+package autoconfig
+
+import "reflect"
+
+// Fresh makes the section decoded into a new instance on each load, instead of updating the registered value in place.
+// The registered value is only used as the initial value, and is never modified by autoconfig : listeners receive
+// the new instance, and Get returns the latest one. Use it for structs holding mutexes or derived state.
+//
+// 	autoconfig.Register("pool", &PoolConf{Size: 10}, autoconfig.Fresh())
+func Fresh() SectionOption {
+	return func(s *section) {
+		s.fresh = true
+	}
+}
+
+// WithCopyOnWrite makes all sections Fresh : each reload decodes sections into new instances, which are published atomically,
+// so that readers using Get never block on reloads nor race with them, whether sections implement sync.Locker or not.
+// Registered values are only used as defaults, the current values must be read using Get (or Section handles, see Section).
+func WithCopyOnWrite() Option {
+	return func(c *Config) {
+		c.copyOnWrite = true
+	}
+}
+
+type box struct {
+	v interface{}
+}
+
+// set sets the current value of the section, and publishes it to readers.
+func (s *section) set(v interface{}) {
+	s.current = v
+	s.published.Store(box{v})
+}
+
+// get returns the last published value of the section, nil if none.
+func (s *section) get() interface{} {
+	b, _ := s.published.Load().(box)
+	return b.v
+}
+
+// target returns the value loaders should decode the section into : a copy of the current value,
+// so that the section is left untouched if its new value cannot be decoded, or is rejected,
+// the exported fields of structs (and maps of structs) being reset to the defaults, so that values removed from config files revert to their defaults.
+// Sections implementing sync.Locker must be locked by the caller.
+func (s *section) target() interface{} {
+	if s.derive != nil || s.current == nil {
+		return nil
+	}
+	t := deepCopy(s.current)
+	if !s.defaults.IsValid() || s.defaults.Type() != reflect.TypeOf(t) {
+		return t
+	}
+	// Entries of maps of structs are decoded on top of the default ones, entries removed from config files being dropped
+	if d := s.defaults.Elem(); d.Kind() == reflect.Struct || (d.Kind() == reflect.Map && isNestedStruct(d.Type().Elem())) {
+		assign(t, deepCopy(s.defaults.Interface()))
+	}
+	return t
+}
+
+// commit publishes t, decoded from target(). Non-fresh sections are updated in place.
+func (s *section) commit(t interface{}) {
+	if s.fresh {
+		s.set(t)
+	} else if t != s.current {
+		assign(s.current, t)
+	}
+}
@@ -0,0 +1,43 @@
+package autoconfig
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Frozen makes a section immutable once it has been loaded, for values that cannot change without a restart
+// (e.g. listener addresses or data directories). A reload changing a frozen section is rejected for this section
+// with a FrozenError, while other sections are still updated.
+//
+// 	autoconfig.Register("listener", &listenerConf, autoconfig.Frozen())
+func Frozen() SectionOption {
+	return func(s *section) {
+		s.frozen = true
+	}
+}
+
+// FrozenError is returned when a reload tries to change a frozen section.
+type FrozenError struct {
+	Section string
+}
+
+func (e *FrozenError) Error() string {
+	return fmt.Sprintf("Config: section %s is frozen and cannot be changed without a restart", e.Section)
+}
+
+// checkFrozen removes from targets the frozen sections that have changed, and returns an error for each of them.
+func (c *Config) checkFrozen(targets map[string]interface{}) error {
+	errs := Errors{}
+	for name, t := range targets {
+		s := c.sections[name]
+		if !s.frozen || s.signature == "" {
+			continue
+		}
+		if sig, err := json.Marshal(t); err == nil && string(sig) == s.signature {
+			continue
+		}
+		delete(targets, name)
+		errs = append(errs, &FrozenError{Section: name})
+	}
+	return errs.errorOrNil()
+}
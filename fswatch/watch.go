@@ -0,0 +1,128 @@
+// Package fswatch reloads a config when its files are written, renamed or symlink-swapped, using fsnotify,
+// removing the need for SIGHUP plumbing :
+// 	autoconfig.Load(yaml.New(filename))
+// 	w, err := fswatch.File(autoconfig.Default(), filename)
+// 	defer w.Close()
+//
+// The directories of the files are watched, so that files replaced by editors (write to a temporary file, then rename)
+// and Kubernetes volumes (symlink swap) are detected. Reloads are debounced if the config has been set up with autoconfig.WithDebounce.
+package fswatch
+
+import (
+	"log"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/jfbus/autoconfig"
+)
+
+// Reloader defines the interface of configs that can be reloaded (e.g. *autoconfig.Config).
+type Reloader interface {
+	TriggerReload(reason string) *autoconfig.ReloadResult
+}
+
+type requester interface {
+	RequestReload(reason string)
+}
+
+// Watcher watches config files.
+type Watcher struct {
+	w     *fsnotify.Watcher
+	r     Reloader
+	files map[string]string
+	done  chan struct{}
+	once  sync.Once
+}
+
+// File watches filenames, and reloads r when one of them changes.
+func File(r Reloader, filenames ...string) (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{w: fw, r: r, files: map[string]string{}, done: make(chan struct{})}
+	dirs := map[string]bool{}
+	for _, f := range filenames {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			fw.Close()
+			return nil, err
+		}
+		w.files[abs] = target(abs)
+		dirs[filepath.Dir(abs)] = true
+	}
+	for dir := range dirs {
+		if err := fw.Add(dir); err != nil {
+			fw.Close()
+			return nil, err
+		}
+	}
+	go w.run()
+	return w, nil
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		err = w.w.Close()
+		<-w.done
+	})
+	return err
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+	for {
+		select {
+		case ev, ok := <-w.w.Events:
+			if !ok {
+				return
+			}
+			if ev.Op == fsnotify.Chmod || !w.changed(filepath.Clean(ev.Name)) {
+				continue
+			}
+			w.reload()
+		case err, ok := <-w.w.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("fswatch: %s", err)
+		}
+	}
+}
+
+// reload reloads the config, debounced if the config supports it (see autoconfig.WithDebounce).
+func (w *Watcher) reload() {
+	if rr, ok := w.r.(requester); ok {
+		rr.RequestReload(string(autoconfig.ReasonFileChange))
+		return
+	}
+	if res := w.r.TriggerReload(string(autoconfig.ReasonFileChange)); res.Err != nil {
+		log.Printf("fswatch: reload failed : %s", res.Err)
+	}
+}
+
+// changed returns whether the event on name changes one of the watched files,
+// either directly or by swapping a symlink the file resolves through.
+func (w *Watcher) changed(name string) bool {
+	changed := false
+	for f, last := range w.files {
+		t := target(f)
+		if f == name || t != last {
+			changed = true
+		}
+		w.files[f] = t
+	}
+	return changed
+}
+
+// target returns the file filename resolves to, following symlinks.
+func target(filename string) string {
+	t, err := filepath.EvalSymlinks(filename)
+	if err != nil {
+		return ""
+	}
+	return t
+}
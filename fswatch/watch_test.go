@@ -0,0 +1,87 @@
+package fswatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jfbus/autoconfig"
+)
+
+// testReloader sends the reasons of reloads
+type testReloader chan string
+
+func (r testReloader) TriggerReload(reason string) *autoconfig.ReloadResult {
+	r <- reason
+	return &autoconfig.ReloadResult{}
+}
+
+func (r testReloader) expect(t *testing.T, msg string) {
+	t.Helper()
+	select {
+	case reason := <-r:
+		if reason != string(autoconfig.ReasonFileChange) {
+			t.Errorf("Unexpected reload reason %s", reason)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal(msg)
+	}
+	// Drain the other events of the same change
+	for {
+		select {
+		case <-r:
+		case <-time.After(50 * time.Millisecond):
+			return
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(filename, []byte("section:\n  key: one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := make(testReloader, 100)
+	w, err := File(r, filename)
+	if err != nil {
+		t.Fatalf("File() returned %s", err)
+	}
+	defer w.Close()
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "other.yaml"), []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case reason := <-r:
+		t.Errorf("Changes to other files should not trigger a reload, got %s", reason)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := ioutil.WriteFile(filename, []byte("section:\n  key: two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r.expect(t, "Writing the file should trigger a reload")
+
+	tmp := filepath.Join(dir, "config.yaml.tmp")
+	if err := ioutil.WriteFile(tmp, []byte("section:\n  key: three\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		t.Fatal(err)
+	}
+	r.expect(t, "Renaming a file over the watched file should trigger a reload")
+
+	if err := w.Close(); err != nil {
+		t.Errorf("Close() returned %s", err)
+	}
+	w.Close()
+	ioutil.WriteFile(filename, []byte("section:\n  key: four\n"), 0644)
+	select {
+	case reason := <-r:
+		t.Errorf("Closed watchers should not trigger reloads, got %s", reason)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,64 @@
+//go:build go1.18
+// +build go1.18
+
+package autoconfig
+
+import (
+	"testing"
+
+	"github.com/jfbus/autoconfig/ini"
+	"github.com/jfbus/autoconfig/jsonc"
+	"github.com/jfbus/autoconfig/msgpack"
+	"github.com/jfbus/autoconfig/yaml"
+)
+
+// fuzzLoad loads data using l into sections of various types, one of them having a fuzzed name.
+// Load may fail, but must not panic.
+func fuzzLoad(t *testing.T, l Loader, name string) {
+	c := New(l, WithApp("app"))
+	c.Register("section", &testCfg{})
+	c.Register("deep", &testDeepCfg{})
+	c.Register("slice", &testSliceCfg{})
+	c.Register("map", &testCfgMap{})
+	c.Register(name, &testCfg{})
+	c.Load()
+	c.Reload()
+}
+
+func FuzzYAML(f *testing.F) {
+	f.Add([]byte("section:\n  key: foo\n"), "section")
+	f.Add([]byte("deep:\n  deeper:\n    key: foo\nslice:\n  key: [a, b]\n"), "sèction")
+	f.Add([]byte("apps:\n  app:\n    section:\n      key: bar\n"), "apps.app")
+	f.Add([]byte("a: &a [*a, *a]\nmap: {x: 1}\n"), "a.b")
+	f.Fuzz(func(t *testing.T, data []byte, name string) {
+		fuzzLoad(t, yaml.NewFromBytes(data), name)
+	})
+}
+
+func FuzzINI(f *testing.F) {
+	f.Add([]byte("[section]\nkey=foo\n"), "section")
+	f.Add([]byte("[apps.app.section]\nkey=bar\n[sèction]\nkey=é\n"), "sèction")
+	f.Add([]byte("[[]]\n=\nkey=\"\"\"\n"), "")
+	f.Fuzz(func(t *testing.T, data []byte, name string) {
+		fuzzLoad(t, ini.NewFromBytes(data), name)
+	})
+}
+
+func FuzzJSONC(f *testing.F) {
+	f.Add([]byte(`{"section": {"key": "foo",}, // comment
+}`), "section")
+	f.Add([]byte(`{"apps": {"app": {"section": {"key": "bar"}}}, "map": {"x": 1}}`), "apps.app")
+	f.Add([]byte(`{"a": "\`), "a")
+	f.Add([]byte(`/* {"section": 1`), "section")
+	f.Fuzz(func(t *testing.T, data []byte, name string) {
+		fuzzLoad(t, jsonc.NewFromBytes(data), name)
+	})
+}
+
+func FuzzMsgpack(f *testing.F) {
+	f.Add([]byte("\x81\xa7section\x81\xa3key\xa3foo"), "section")
+	f.Add([]byte("\x81\xa4apps\x81\xa3app\x80"), "apps.app")
+	f.Fuzz(func(t *testing.T, data []byte, name string) {
+		fuzzLoad(t, msgpack.NewFromBytes(data), name)
+	})
+}
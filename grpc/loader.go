@@ -0,0 +1,138 @@
+// Package grpc defines a loader consuming a central config service over gRPC (see configservice.proto),
+// which can push new versions of the config.
+// 	l := grpc.New(conn, "billing-api")
+// 	cfg := autoconfig.New(l)
+// 	cfg.Load()
+// 	l.Watch(cfg)
+//
+// The server side can be implemented using a Publisher :
+// 	p := grpc.NewPublisher()
+// 	grpc.RegisterConfigServiceServer(server, p)
+// 	p.Publish("billing-api", &grpc.ConfigSnapshot{Version: "42", Sections: sections})
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jfbus/autoconfig"
+	"github.com/jfbus/autoconfig/decode"
+	"google.golang.org/grpc"
+)
+
+// retryDelay is the delay before watching again when the stream has been interrupted
+var retryDelay = 5 * time.Second
+
+// Reloader defines the interface of configs that can be reloaded and schedule retries (e.g. *autoconfig.Config).
+type Reloader interface {
+	TriggerReload(reason string) *autoconfig.ReloadResult
+	Every(interval time.Duration, fn func()) (stop func())
+}
+
+type Loader struct {
+	conn *grpc.ClientConn
+	app  string
+	// Timeout is the timeout of GetConfig calls
+	Timeout time.Duration
+
+	mu     sync.Mutex
+	pushed *ConfigSnapshot
+}
+
+// New creates a Loader for the config of app
+func New(conn *grpc.ClientConn, app string) *Loader {
+	return &Loader{conn: conn, app: app, Timeout: 10 * time.Second}
+}
+
+// Load unmarshals the last snapshot pushed by the service (see Watch), or fetches the current one.
+// Sections are decoded using the yaml tags of the section structs, as for the yaml loader.
+func (l *Loader) Load(cfg map[string]interface{}) error {
+	return l.LoadWith(cfg, decode.Options{})
+}
+
+// LoadWith unmarshals the last snapshot to cfg using the decoding options o
+func (l *Loader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	snap, err := l.snapshot()
+	if err != nil {
+		return err
+	}
+	for name, scfg := range cfg {
+		raw, ok := snap.Sections[name]
+		if !ok {
+			continue
+		}
+		if err := o.YAML(raw, scfg); err != nil {
+			return fmt.Errorf("grpc: section %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// Watch subscribes to new versions of the config, and reloads r each time one is pushed.
+// If the stream is interrupted, the error is logged and the subscription is restarted within retryDelay, using the clock of r.
+// Calling the returned function stops watching.
+func (l *Loader) Watch(r Reloader) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var running int32
+	start := func() {
+		if !atomic.CompareAndSwapInt32(&running, 0, 1) {
+			return
+		}
+		go func() {
+			defer atomic.StoreInt32(&running, 0)
+			if err := l.watch(ctx, r); err != nil && ctx.Err() == nil {
+				log.Printf("grpc: watching %s failed : %s", l.app, err)
+			}
+		}()
+	}
+	start()
+	stopRetry := r.Every(retryDelay, start)
+	return func() {
+		stopRetry()
+		cancel()
+	}
+}
+
+func (l *Loader) watch(ctx context.Context, r Reloader) error {
+	stream, err := l.conn.NewStream(ctx, &serviceDesc.Streams[0], watchMethod)
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(&ConfigRequest{App: l.app}); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	for {
+		snap := &ConfigSnapshot{}
+		if err := stream.RecvMsg(snap); err != nil {
+			return err
+		}
+		l.mu.Lock()
+		changed := l.pushed == nil || l.pushed.Version != snap.Version
+		l.pushed = snap
+		l.mu.Unlock()
+		if changed {
+			r.TriggerReload(string(autoconfig.ReasonFileChange))
+		}
+	}
+}
+
+func (l *Loader) snapshot() (*ConfigSnapshot, error) {
+	l.mu.Lock()
+	pushed := l.pushed
+	l.mu.Unlock()
+	if pushed != nil {
+		return pushed, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), l.Timeout)
+	defer cancel()
+	snap := &ConfigSnapshot{}
+	err := l.conn.Invoke(ctx, getMethod, &ConfigRequest{App: l.app}, snap)
+	return snap, err
+}
@@ -0,0 +1,144 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jfbus/autoconfig"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+type testCfg struct {
+	Key  string `yaml:"key"`
+	Port int    `yaml:"port"`
+}
+
+// testListener receives the keys of the reloaded section
+type testListener chan string
+
+func (l testListener) Reconfigure(cfg interface{}) {
+	l <- cfg.(*testCfg).Key
+}
+
+// failingServer fails the first fails WatchConfig calls
+type failingServer struct {
+	*Publisher
+	fails int32
+}
+
+func (s *failingServer) WatchConfig(req *ConfigRequest, stream ConfigService_WatchConfigServer) error {
+	if atomic.AddInt32(&s.fails, -1) >= 0 {
+		return errors.New("unavailable")
+	}
+	return s.Publisher.WatchConfig(req, stream)
+}
+
+// dial serves srv over an in-memory connection
+func dial(t *testing.T, srv ConfigServiceServer) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	RegisterConfigServiceServer(s, srv)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func snapshot(version, key string) *ConfigSnapshot {
+	return &ConfigSnapshot{Version: version, Sections: map[string]json.RawMessage{"section": json.RawMessage(`{"key": "` + key + `", "port": 80}`)}}
+}
+
+func TestLoad(t *testing.T) {
+	p := NewPublisher()
+	p.Publish("app", snapshot("1", "one"))
+	conn := dial(t, p)
+	c := &testCfg{}
+	if err := New(conn, "app").Load(map[string]interface{}{"section": c}); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "one" || c.Port != 80 {
+		t.Errorf("Unexpected section %+v", *c)
+	}
+	if err := New(conn, "other").Load(map[string]interface{}{"section": c}); err == nil {
+		t.Error("Load() should fail for unknown applications")
+	}
+	p.Publish("app", &ConfigSnapshot{Version: "2", Sections: map[string]json.RawMessage{"section": json.RawMessage(`{"port": "eighty"}`)}})
+	if err := New(conn, "app").Load(map[string]interface{}{"section": c}); err == nil {
+		t.Error("Load() should fail for invalid sections")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	retryDelay = 10 * time.Millisecond
+	srv := &failingServer{Publisher: NewPublisher(), fails: 2}
+	srv.Publish("app", snapshot("1", "one"))
+	l := New(dial(t, srv), "app")
+	cfg := autoconfig.New(l)
+	defer cfg.Close()
+	c := &testCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	changes := make(testListener, 10)
+	cfg.Reconfigure("section", changes)
+	stop := l.Watch(cfg)
+	defer stop()
+	srv.Publish("app", snapshot("2", "two"))
+	for {
+		select {
+		case key := <-changes:
+			if key == "two" {
+				if atomic.LoadInt32(&srv.fails) >= 0 {
+					t.Error("The stream should have been retried")
+				}
+				return
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Pushed snapshots should reload the config once the stream has been restarted")
+		}
+	}
+}
+
+// TestWire checks that messages are encoded as defined by configservice.proto
+func TestWire(t *testing.T) {
+	req := protowire.AppendTag(nil, 1, protowire.BytesType)
+	req = protowire.AppendString(req, "app")
+	if data, err := proto.Marshal(protoadapt.MessageV2Of(&ConfigRequest{App: "app"})); err != nil || string(data) != string(req) {
+		t.Errorf("Unexpected ConfigRequest encoding %x, %v", data, err)
+	}
+
+	var entry []byte
+	entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+	entry = protowire.AppendString(entry, "section")
+	entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+	entry = protowire.AppendBytes(entry, []byte(`{"key": "one"}`))
+	var snap []byte
+	snap = protowire.AppendTag(snap, 1, protowire.BytesType)
+	snap = protowire.AppendString(snap, "42")
+	snap = protowire.AppendTag(snap, 2, protowire.BytesType)
+	snap = protowire.AppendBytes(snap, entry)
+	s := &ConfigSnapshot{}
+	if err := proto.Unmarshal(snap, protoadapt.MessageV2Of(s)); err != nil {
+		t.Fatalf("Unmarshal() returned %s", err)
+	}
+	if s.Version != "42" || string(s.Sections["section"]) != `{"key": "one"}` {
+		t.Errorf("Unexpected ConfigSnapshot %+v", s)
+	}
+}
@@ -0,0 +1,94 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+const (
+	serviceName = "autoconfig.ConfigService"
+	getMethod   = "/" + serviceName + "/GetConfig"
+	watchMethod = "/" + serviceName + "/WatchConfig"
+)
+
+// ConfigRequest is the request of both GetConfig and WatchConfig.
+// Messages are encoded by the standard proto codec of grpc, using the field numbers of configservice.proto.
+type ConfigRequest struct {
+	App string `protobuf:"bytes,1,opt,name=app,proto3" json:"app"`
+}
+
+func (m *ConfigRequest) Reset()         { *m = ConfigRequest{} }
+func (m *ConfigRequest) String() string { return prototext.Format(protoadapt.MessageV2Of(m)) }
+func (*ConfigRequest) ProtoMessage()    {}
+
+// ConfigSnapshot is a version of the config of an application
+type ConfigSnapshot struct {
+	Version  string                     `protobuf:"bytes,1,opt,name=version,proto3" json:"version"`
+	Sections map[string]json.RawMessage `protobuf:"bytes,2,rep,name=sections,proto3" json:"sections" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *ConfigSnapshot) Reset()         { *m = ConfigSnapshot{} }
+func (m *ConfigSnapshot) String() string { return prototext.Format(protoadapt.MessageV2Of(m)) }
+func (*ConfigSnapshot) ProtoMessage()    {}
+
+// ConfigServiceServer defines the server side of the protocol (see configservice.proto).
+type ConfigServiceServer interface {
+	GetConfig(context.Context, *ConfigRequest) (*ConfigSnapshot, error)
+	WatchConfig(*ConfigRequest, ConfigService_WatchConfigServer) error
+}
+
+// ConfigService_WatchConfigServer is the stream of snapshots sent by WatchConfig
+type ConfigService_WatchConfigServer interface {
+	Send(*ConfigSnapshot) error
+	grpc.ServerStream
+}
+
+// RegisterConfigServiceServer registers srv on a grpc server
+func RegisterConfigServiceServer(s *grpc.Server, srv ConfigServiceServer) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*ConfigServiceServer)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "GetConfig",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := &ConfigRequest{}
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return srv.(ConfigServiceServer).GetConfig(ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: getMethod}
+			return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.(ConfigServiceServer).GetConfig(ctx, req.(*ConfigRequest))
+			})
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "WatchConfig",
+		ServerStreams: true,
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			req := &ConfigRequest{}
+			if err := stream.RecvMsg(req); err != nil {
+				return err
+			}
+			return srv.(ConfigServiceServer).WatchConfig(req, watchServer{stream})
+		},
+	}},
+	Metadata: "configservice.proto",
+}
+
+type watchServer struct {
+	grpc.ServerStream
+}
+
+func (w watchServer) Send(s *ConfigSnapshot) error {
+	return w.ServerStream.SendMsg(s)
+}
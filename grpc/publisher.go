@@ -0,0 +1,76 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Publisher is a ConfigServiceServer serving the snapshots published by the application.
+type Publisher struct {
+	mu        sync.Mutex
+	snapshots map[string]*ConfigSnapshot
+	watchers  map[string]map[chan *ConfigSnapshot]bool
+}
+
+// NewPublisher creates a Publisher without any snapshot
+func NewPublisher() *Publisher {
+	return &Publisher{snapshots: map[string]*ConfigSnapshot{}, watchers: map[string]map[chan *ConfigSnapshot]bool{}}
+}
+
+// Publish sets the current snapshot of app, and pushes it to watchers
+func (p *Publisher) Publish(app string, s *ConfigSnapshot) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.snapshots[app] = s
+	for ch := range p.watchers[app] {
+		// Only the latest snapshot matters to slow watchers
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- s
+	}
+}
+
+// GetConfig returns the current snapshot of the requested app
+func (p *Publisher) GetConfig(ctx context.Context, req *ConfigRequest) (*ConfigSnapshot, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.snapshots[req.App]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no config for %s", req.App)
+	}
+	return s, nil
+}
+
+// WatchConfig sends the current snapshot of the requested app, then each published one
+func (p *Publisher) WatchConfig(req *ConfigRequest, stream ConfigService_WatchConfigServer) error {
+	ch := make(chan *ConfigSnapshot, 1)
+	p.mu.Lock()
+	if s, ok := p.snapshots[req.App]; ok {
+		ch <- s
+	}
+	if p.watchers[req.App] == nil {
+		p.watchers[req.App] = map[chan *ConfigSnapshot]bool{}
+	}
+	p.watchers[req.App][ch] = true
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.watchers[req.App], ch)
+		p.mu.Unlock()
+	}()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case s := <-ch:
+			if err := stream.Send(s); err != nil {
+				return err
+			}
+		}
+	}
+}
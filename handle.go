@@ -0,0 +1,48 @@
+package autoconfig
+
+import "sync"
+
+// Handle is returned by ReconfigureHandle and Once. Releasing it unsubscribes the instance.
+type Handle struct {
+	c    *Config
+	name string
+	r    Reconfigurable
+	once sync.Once
+}
+
+// ReconfigureHandle registers an instance, as Reconfigure does, and returns a handle that must be released once the instance
+// is no longer used, so that instances created per request or per connection do not accumulate in the listeners of the section.
+// Instances of any type can be released, including the ones that cannot be removed using Unsubscribe.
+//
+// 	func NewConn() *Conn {
+// 		c := &Conn{}
+// 		c.handle = autoconfig.ReconfigureHandle("conn", c)
+// 		return c
+// 	}
+//
+// 	func (c *Conn) Close() error {
+// 		c.handle.Release()
+// 		...
+// 	}
+func (c *Config) ReconfigureHandle(name string, r Reconfigurable) *Handle {
+	h := &Handle{c: c, name: name, r: r}
+	c.Reconfigure(name, h)
+	return h
+}
+
+// ReconfigureHandle registers an instance to the default config, and returns a handle that must be released once the instance is no longer used.
+func ReconfigureHandle(name string, r Reconfigurable) *Handle {
+	return globalConfig.ReconfigureHandle(name, r)
+}
+
+// Reconfigure notifies the instance.
+func (h *Handle) Reconfigure(cfg interface{}) {
+	h.r.Reconfigure(cfg)
+}
+
+// Release unsubscribes the instance. It can be called several times.
+func (h *Handle) Release() {
+	h.once.Do(func() {
+		h.c.Unsubscribe(h.name, h)
+	})
+}
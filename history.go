@@ -0,0 +1,113 @@
+package autoconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// WithHistory keeps the last size applied values of each section, so that a bad change can be reverted using Rollback.
+func WithHistory(size int) Option {
+	return func(c *Config) {
+		c.history = &history{size: size, sections: map[string][]Generation{}, last: map[string]int{}}
+	}
+}
+
+// Generation is a value applied to a section.
+type Generation struct {
+	// Generation is the number of the generation, starting at 1 for the initial load
+	Generation int `json:"generation"`
+	// Time is the time the value has been applied at
+	Time time.Time `json:"time"`
+	// Reason is the reason of the reload that applied the value
+	Reason Reason `json:"reason"`
+	// Value is the JSON encoded value of the section, keyed as Export does
+	Value json.RawMessage `json:"value"`
+}
+
+type history struct {
+	sync.Mutex
+	size     int
+	sections map[string][]Generation
+	last     map[string]int
+}
+
+func (h *history) add(name string, g Generation) {
+	h.Lock()
+	defer h.Unlock()
+	h.last[name]++
+	g.Generation = h.last[name]
+	if g.Generation == 1 {
+		// As for notifications, see section.change
+		g.Reason = ReasonInitialLoad
+	}
+	gens := append(h.sections[name], g)
+	if len(gens) > h.size {
+		gens = gens[len(gens)-h.size:]
+	}
+	h.sections[name] = gens
+}
+
+func (h *history) get(name string) []Generation {
+	h.Lock()
+	defer h.Unlock()
+	return append([]Generation(nil), h.sections[name]...)
+}
+
+// record adds the current value of a section that has changed to its history.
+func (c *Config) record(s *section, reason Reason) {
+	if c.history == nil || s.derive != nil {
+		return
+	}
+	value, err := encodeJSON(s.current, c.decoding)
+	if err != nil {
+		value = []byte(s.signature)
+	}
+	c.history.add(s.name, Generation{Time: c.clock.Now(), Reason: reason, Value: value})
+}
+
+// History returns the last values applied to a section, oldest first. It is empty unless the config has been set up with WithHistory.
+func (c *Config) History(name string) []Generation {
+	if c.history == nil {
+		return nil
+	}
+	return c.history.get(name)
+}
+
+// History returns the last values applied to a section of the default config.
+func History(name string) []Generation {
+	return globalConfig.History(name)
+}
+
+// Rollback applies a previous value of a section, checks it and notifies listeners with the ReasonRollback reason.
+// The rolled back value is not kept as an override : it is replaced by the value loaded on the next reload,
+// once the bad config has been fixed.
+func (c *Config) Rollback(name string, generation int) error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	s, found := c.sections[name]
+	if !found || s.current == nil || s.derive != nil {
+		return fmt.Errorf("Config: cannot rollback section %s: %s", name, ErrUnknownSection)
+	}
+	for _, g := range c.History(name) {
+		if g.Generation == generation {
+			scratch := s.target()
+			if t := reflect.TypeOf(scratch).Elem(); t.Kind() != reflect.Struct {
+				// Values are decoded on top of the scratch copy : maps would keep the keys added after the generation
+				scratch = reflect.New(t).Interface()
+			}
+			if err := c.decodeJSON(g.Value, scratch); err != nil {
+				return fmt.Errorf("Config: cannot decode generation %d of section %s: %s", generation, name, err)
+			}
+			return c.apply(name, scratch, ReasonRollback)
+		}
+	}
+	return fmt.Errorf("Config: cannot rollback section %s: unknown generation %d", name, generation)
+}
+
+// Rollback applies a previous value of a section of the default config.
+func Rollback(name string, generation int) error {
+	return globalConfig.Rollback(name, generation)
+}
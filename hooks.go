@@ -0,0 +1,76 @@
+package autoconfig
+
+import "sort"
+
+// OnBeforeReload registers a function called before each reload, e.g. to pause traffic or flush buffers.
+func (c *Config) OnBeforeReload(fn func()) {
+	c.beforeReload = append(c.beforeReload, fn)
+}
+
+// OnBeforeReload registers a function called before each reload of the default config.
+func OnBeforeReload(fn func()) {
+	globalConfig.OnBeforeReload(fn)
+}
+
+// OnAfterReload registers a function called after each reload, with its outcome, e.g. to resume traffic or emit audit events.
+// It is called after listeners have been notified, including when the reload has failed.
+func (c *Config) OnAfterReload(fn func(*ReloadResult)) {
+	c.afterReload = append(c.afterReload, fn)
+}
+
+// OnAfterReload registers a function called after each reload of the default config.
+func OnAfterReload(fn func(*ReloadResult)) {
+	globalConfig.OnAfterReload(fn)
+}
+
+// OnReloadError registers a function called when a reload triggered in the background (by a signal, see ReloadOn,
+// or a watcher, see RequestReload) fails, e.g. to alert. Errors are logged if no function has been registered.
+func (c *Config) OnReloadError(fn func(error)) {
+	c.reloadErrorsMu.Lock()
+	defer c.reloadErrorsMu.Unlock()
+	c.reloadErrors = append(c.reloadErrors, fn)
+}
+
+// OnReloadError registers a function called when a reload of the default config triggered in the background fails.
+func OnReloadError(fn func(error)) {
+	globalConfig.OnReloadError(fn)
+}
+
+// OnAnyChange registers a function called each time a section has changed, after its listeners have been notified
+// (or their notification scheduled, see WithAsyncNotify and Throttle), so that cross-cutting components (audit, metrics, cache invalidation...) can observe all sections, including the ones
+// registered later.
+func (c *Config) OnAnyChange(fn func(section string, cfg interface{})) {
+	c.anyChange = append(c.anyChange, fn)
+}
+
+// OnAnyChange registers a function called each time a section of the default config has changed.
+func OnAnyChange(fn func(section string, cfg interface{})) {
+	globalConfig.OnAnyChange(fn)
+}
+
+// OnRestartRequired registers a function called when a reload changes static fields (see ReloadResult.RestartRequired),
+// with the paths of the fields, e.g. to alert or to schedule a graceful restart. It is called once per section, in name order.
+func (c *Config) OnRestartRequired(fn func(section string, fields []string)) {
+	c.restartHooks = append(c.restartHooks, fn)
+}
+
+// OnRestartRequired registers a function called when a reload of the default config changes static fields.
+func OnRestartRequired(fn func(section string, fields []string)) {
+	globalConfig.OnRestartRequired(fn)
+}
+
+func (c *Config) restartRequired(res *ReloadResult) {
+	if len(c.restartHooks) == 0 || len(res.RestartRequired) == 0 {
+		return
+	}
+	names := make([]string, 0, len(res.RestartRequired))
+	for name := range res.RestartRequired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, fn := range c.restartHooks {
+			fn(name, res.RestartRequired[name])
+		}
+	}
+}
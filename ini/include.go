@@ -0,0 +1,102 @@
+package ini
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// includeKey is the key of the default section listing the files included by a document, separated by commas.
+// Relative paths are resolved from the directory of the including file.
+const includeKey = "include"
+
+// includer reads included files
+type includer interface {
+	// resolve returns the name of file, included by base
+	resolve(base, file string) string
+	readFile(name string) ([]byte, error)
+}
+
+type osIncluder struct{}
+
+func (osIncluder) resolve(base, file string) string {
+	if filepath.IsAbs(file) || base == "" {
+		return file
+	}
+	return filepath.Join(filepath.Dir(base), file)
+}
+
+func (osIncluder) readFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+type fsIncluder struct {
+	fsys fs.FS
+}
+
+func (fsIncluder) resolve(base, file string) string {
+	return path.Join(path.Dir(base), file)
+}
+
+func (f fsIncluder) readFile(name string) ([]byte, error) {
+	return fs.ReadFile(f.fsys, name)
+}
+
+// file reads and decodes the config file, merging the files it includes
+func (l *Loader) file() (*ini.File, error) {
+	data, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	var included []string
+	sources, err := l.sources(data, l.name, []string{l.name}, &included)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ini.Load(sources[0], sources[1:]...)
+	if err != nil {
+		return nil, err
+	}
+	f.Section("").DeleteKey(includeKey)
+	l.mu.Lock()
+	l.included = included
+	l.mu.Unlock()
+	return f, nil
+}
+
+// sources returns the data of the included files, in order, followed by data.
+// stack lists the files being included, to detect cycles. Included files are added to included.
+func (l *Loader) sources(data []byte, name string, stack []string, included *[]string) ([]interface{}, error) {
+	f, err := ini.Load(data)
+	if err != nil {
+		if len(stack) > 1 {
+			return nil, fmt.Errorf("ini: %s: %s", name, err)
+		}
+		return nil, err
+	}
+	var sources []interface{}
+	for _, file := range f.Section("").Key(includeKey).Strings(",") {
+		inc := l.includes.resolve(name, file)
+		for _, s := range stack {
+			if s == inc {
+				return nil, fmt.Errorf("ini: include cycle: %s", strings.Join(append(stack, inc), " -> "))
+			}
+		}
+		data, err := l.includes.readFile(inc)
+		if err != nil {
+			return nil, err
+		}
+		*included = append(*included, inc)
+		sub, err := l.sources(data, inc, append(stack[:len(stack):len(stack)], inc), included)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, sub...)
+	}
+	return append(sources, data), nil
+}
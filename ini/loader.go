@@ -1,34 +1,166 @@
 // Package ini defines a loader for ini config files
 // 	autoconfig.Load(ini.New(filename))
+//
+// A config file can include other files, listed by the include key of the default section.
+// Included files are merged in order, the including file overriding them :
+// 	include = common.ini, /etc/myapp/secrets.ini
 package ini
 
-import "gopkg.in/ini.v1"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jfbus/autoconfig/decode"
+	"gopkg.in/ini.v1"
+)
 
 type Loader struct {
-	filename string
+	read func() ([]byte, error)
+	// name is the name of the file read by read, included files are resolved relative to it
+	name     string
+	includes includer
+
+	mu sync.Mutex
+	// included lists the files included on the last load
+	included []string
 }
 
 // New creates a Loader for INI files
 func New(filename string) *Loader {
-	return &Loader{filename: filename}
+	return &Loader{read: func() ([]byte, error) {
+		return ioutil.ReadFile(filename)
+	}, name: filename, includes: osIncluder{}}
+}
+
+// NewFS creates a Loader for the INI file name of fsys, e.g. an embed.FS
+func NewFS(fsys fs.FS, name string) *Loader {
+	return &Loader{read: func() ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	}, name: name, includes: fsIncluder{fsys}}
+}
+
+// NewFromBytes creates a Loader for INI data, e.g. embedded in the binary
+func NewFromBytes(data []byte) *Loader {
+	return &Loader{read: func() ([]byte, error) {
+		return data, nil
+	}, includes: osIncluder{}}
+}
+
+// NewFromReader creates a Loader for INI data read from r. r is only read once, all reloads use the same data.
+func NewFromReader(r io.Reader) *Loader {
+	var (
+		once sync.Once
+		data []byte
+		err  error
+	)
+	return &Loader{read: func() ([]byte, error) {
+		once.Do(func() {
+			data, err = ioutil.ReadAll(r)
+		})
+		return data, err
+	}, includes: osIncluder{}}
 }
 
-// Load loads the config file and unmarshals it to cfg
+// Load loads the config file, merged with the files it includes, and unmarshals it to cfg
 func (l *Loader) Load(cfg map[string]interface{}) error {
-	f, err := ini.Load(l.filename)
+	return l.LoadWith(cfg, decode.Options{})
+}
+
+// LoadWith loads the config file, merged with the files it includes, and unmarshals it to cfg using the decoding options o
+func (l *Loader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	load, err := l.Document()
 	if err != nil {
 		return err
 	}
+	return load(cfg, o)
+}
+
+// Document loads the config file, merged with the files it includes, and returns a function unmarshaling it to sections
+func (l *Loader) Document() (func(cfg map[string]interface{}, o decode.Options) error, error) {
+	f, err := l.file()
+	if err != nil {
+		return nil, err
+	}
+	return func(cfg map[string]interface{}, o decode.Options) error {
+		return unmarshal(f, cfg, o)
+	}, nil
+}
+
+// unmarshal unmarshals the sections of f to cfg
+func unmarshal(f *ini.File, cfg map[string]interface{}, o decode.Options) error {
 	for name, sec := range cfg {
-		s := f.Section(name)
-		if s == nil {
-			// TODO: raise an error ?
-			continue
+		if v := reflect.ValueOf(sec); v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+			// INI sections are mapped to struct fields
+			return fmt.Errorf("ini: section %s: cannot map to %T, only pointers to structs are supported", name, sec)
 		}
-		err = s.MapTo(sec)
+		s, err := f.GetSection(name)
 		if err != nil {
-			return err
+			// No such section
+			continue
+		}
+		if err := o.Into(sectionDoc(s), sec, "ini"); err != nil {
+			return fmt.Errorf("ini: section %s: %s", name, err)
 		}
 	}
 	return nil
 }
+
+// sectionDoc returns the keys of s with their string values, nested struct fields being mapped to child sections (e.g. [server.tls]).
+func sectionDoc(s *ini.Section) map[string]interface{} {
+	doc := map[string]interface{}{}
+	for k, v := range s.KeysHash() {
+		doc[k] = v
+	}
+	for _, child := range s.ChildSections() {
+		if name := strings.TrimPrefix(child.Name(), s.Name()+"."); !strings.Contains(name, ".") {
+			doc[name] = sectionDoc(child)
+		}
+	}
+	return doc
+}
+
+// Raw loads the config file and returns the raw decoded document : sections, then keys with string values
+func (l *Loader) Raw() (map[string]interface{}, error) {
+	f, err := l.file()
+	if err != nil {
+		return nil, err
+	}
+	doc := map[string]interface{}{}
+	for _, s := range f.Sections() {
+		keys := map[string]interface{}{}
+		for k, v := range s.KeysHash() {
+			keys[k] = v
+		}
+		doc[s.Name()] = keys
+	}
+	return doc, nil
+}
+
+// Checksum returns a checksum of the config file and of the files it included on the last load,
+// so that autoconfig can skip reloads when they have not changed
+func (l *Loader) Checksum() (string, error) {
+	data, err := l.read()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write(data)
+	l.mu.Lock()
+	included := l.included
+	l.mu.Unlock()
+	for _, name := range included {
+		data, err := l.includes.readFile(name)
+		if err != nil {
+			return "", err
+		}
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,218 @@
+// Package jsonc defines a loader for JSON config files allowing comments (// and /* */) and trailing commas.
+// Each top-level key is a section, decoded using the json tags of the section struct. Durations can be written as strings (e.g. "30s").
+// 	autoconfig.Load(jsonc.New(filename))
+package jsonc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+type Loader struct {
+	read func() ([]byte, error)
+}
+
+// New creates a Loader for JSONC files
+func New(filename string) *Loader {
+	return &Loader{read: func() ([]byte, error) {
+		return ioutil.ReadFile(filename)
+	}}
+}
+
+// NewFS creates a Loader for the JSONC file name of fsys, e.g. an embed.FS
+func NewFS(fsys fs.FS, name string) *Loader {
+	return &Loader{read: func() ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	}}
+}
+
+// NewFromBytes creates a Loader for JSONC data, e.g. embedded in the binary
+func NewFromBytes(data []byte) *Loader {
+	return &Loader{read: func() ([]byte, error) {
+		return data, nil
+	}}
+}
+
+// NewFromReader creates a Loader for JSONC data read from r. r is only read once, all reloads use the same data.
+func NewFromReader(r io.Reader) *Loader {
+	var (
+		once sync.Once
+		data []byte
+		err  error
+	)
+	return &Loader{read: func() ([]byte, error) {
+		once.Do(func() {
+			data, err = ioutil.ReadAll(r)
+		})
+		return data, err
+	}}
+}
+
+// Load loads the config file and unmarshals it to cfg
+func (l *Loader) Load(cfg map[string]interface{}) error {
+	return l.LoadWith(cfg, decode.Options{})
+}
+
+// LoadWith loads the config file and unmarshals it to cfg using the decoding options o
+func (l *Loader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	load, err := l.Document()
+	if err != nil {
+		return err
+	}
+	return load(cfg, o)
+}
+
+// Document loads the config file, and returns a function unmarshaling it to sections
+func (l *Loader) Document() (func(cfg map[string]interface{}, o decode.Options) error, error) {
+	tmp, err := l.sections()
+	if err != nil {
+		return nil, err
+	}
+	return func(cfg map[string]interface{}, o decode.Options) error {
+		return unmarshal(tmp, cfg, o)
+	}, nil
+}
+
+// unmarshal unmarshals the sections of tmp to cfg
+func unmarshal(tmp map[string]json.RawMessage, cfg map[string]interface{}, o decode.Options) error {
+	for name, scfg := range cfg {
+		raw, ok := lookup(tmp, name)
+		if !ok || string(raw) == "null" {
+			continue
+		}
+		if err := o.JSON(raw, scfg); err != nil {
+			return fmt.Errorf("jsonc: section %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// Raw loads the config file and returns the raw decoded document
+func (l *Loader) Raw() (map[string]interface{}, error) {
+	data, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	doc := map[string]interface{}{}
+	err = json.Unmarshal(Standardize(data), &doc)
+	return doc, err
+}
+
+func (l *Loader) sections() (map[string]json.RawMessage, error) {
+	data, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	tmp := map[string]json.RawMessage{}
+	err = json.Unmarshal(Standardize(data), &tmp)
+	return tmp, err
+}
+
+// Standardize converts JSONC data to standard JSON, by removing comments and trailing commas.
+// Offsets are not preserved, but line numbers are.
+func Standardize(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	// pending holds a comma, and the whitespace/newlines following it, until we know whether it is trailing
+	var pending []byte
+	flush := func() {
+		out = append(out, pending...)
+		pending = nil
+	}
+	emit := func(b ...byte) {
+		if pending != nil {
+			pending = append(pending, b...)
+		} else {
+			out = append(out, b...)
+		}
+	}
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '"':
+			flush()
+			j := i + 1
+			for ; j < len(data) && data[j] != '"'; j++ {
+				if data[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(data) {
+				j = len(data) - 1
+			}
+			out = append(out, data[i:j+1]...)
+			i = j
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				emit('\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i < len(data) && !(data[i] == '*' && i+1 < len(data) && data[i+1] == '/') {
+				if data[i] == '\n' {
+					emit('\n')
+				}
+				i++
+			}
+			i++
+		case c == ',':
+			flush()
+			pending = []byte{','}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			emit(c)
+		case (c == '}' || c == ']') && pending != nil:
+			// trailing comma: drop it, keep the whitespace
+			out = append(out, pending[1:]...)
+			pending = nil
+			out = append(out, c)
+		default:
+			flush()
+			out = append(out, c)
+		}
+	}
+	flush()
+	return out
+}
+
+// lookup returns the value of a top-level key, or of a nested one using a dotted path (e.g. apps.api.section)
+func lookup(tmp map[string]json.RawMessage, name string) (json.RawMessage, bool) {
+	if raw, ok := tmp[name]; ok {
+		return raw, true
+	}
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		raw, ok := tmp[p]
+		if !ok {
+			return nil, false
+		}
+		if i == len(parts)-1 {
+			return raw, true
+		}
+		tmp = map[string]json.RawMessage{}
+		if err := json.Unmarshal(raw, &tmp); err != nil {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// Checksum returns a checksum of the data, so that autoconfig can skip reloads when it has not changed
+func (l *Loader) Checksum() (string, error) {
+	data, err := l.read()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
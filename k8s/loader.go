@@ -0,0 +1,202 @@
+// Package k8s defines a loader for Kubernetes ConfigMaps and Secrets.
+// Each key of the ConfigMap/Secret is a section, its value being the YAML (or JSON) content of the section.
+// 	l := k8s.New("/etc/config")
+// 	cfg := autoconfig.New(l)
+// 	cfg.Load()
+// 	l.Watch(cfg, 10*time.Second)
+package k8s
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/jfbus/autoconfig"
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// dataLink is the symlink Kubernetes atomically swaps when a mounted ConfigMap/Secret is updated.
+const dataLink = "..data"
+
+// extensions are the suffixes tried when looking up the key of a section.
+var extensions = []string{"", ".yaml", ".yml", ".json"}
+
+// FetchFunc returns the data of a ConfigMap or Secret, e.g. fetched from the API server using client-go.
+type FetchFunc func() (map[string][]byte, error)
+
+// Reloader defines the interface of configs that can be reloaded and schedule polling (e.g. *autoconfig.Config).
+type Reloader interface {
+	TriggerReload(reason string) *autoconfig.ReloadResult
+	Every(interval time.Duration, fn func()) (stop func())
+}
+
+// Loader loads sections from the keys of a ConfigMap/Secret, either mounted as a volume or fetched from the API server.
+type Loader struct {
+	dir   string
+	fetch FetchFunc
+}
+
+// New creates a Loader reading a ConfigMap/Secret mounted as a volume in dir
+func New(dir string) *Loader {
+	return &Loader{dir: dir}
+}
+
+// NewFromAPI creates a Loader reading a ConfigMap/Secret using fetch
+func NewFromAPI(fetch FetchFunc) *Loader {
+	return &Loader{fetch: fetch}
+}
+
+// Load reads the ConfigMap/Secret and unmarshals each key to the matching section of cfg
+func (l *Loader) Load(cfg map[string]interface{}) error {
+	return l.LoadWith(cfg, decode.Options{})
+}
+
+// LoadWith reads the ConfigMap/Secret and unmarshals each key to cfg using the decoding options o
+func (l *Loader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	data, err := l.read()
+	if err != nil {
+		return err
+	}
+	for name, scfg := range cfg {
+		for _, ext := range extensions {
+			raw, ok := data[name+ext]
+			if !ok {
+				continue
+			}
+			if err := o.YAML(raw, scfg); err != nil {
+				return fmt.Errorf("k8s: key %s: %s", name+ext, err)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// Watch reloads r when the ConfigMap/Secret has been updated. Mounted volumes are watched using fsnotify, reloads being triggered
+// by the atomic swap of the ..data symlink performed by Kubernetes (they are checked every interval if they cannot be watched).
+// ConfigMaps/Secrets read from the API server are checked every interval.
+// Calling the returned function stops watching.
+func (l *Loader) Watch(r Reloader, interval time.Duration) (stop func()) {
+	if l.fetch == nil {
+		stop, err := l.watchDir(r)
+		if err == nil {
+			return stop
+		}
+		log.Printf("k8s: cannot watch %s, polling it instead: %s", l.dir, err)
+	}
+	last, _ := l.version()
+	return r.Every(interval, func() {
+		v, err := l.version()
+		if err != nil || v == last {
+			return
+		}
+		last = v
+		r.TriggerReload(string(autoconfig.ReasonFileChange))
+	})
+}
+
+// watchDir watches the mounted volume, and reloads r when its version has changed.
+// Kubernetes writes a new timestamped directory, then renames a ..data_tmp symlink to ..data : intermediate events are ignored,
+// as the version of the volume is the target of ..data.
+func (l *Loader) watchDir(r Reloader) (stop func(), err error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Add(l.dir); err != nil {
+		w.Close()
+		return nil, err
+	}
+	last, _ := l.version()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if ev.Op == fsnotify.Chmod {
+					continue
+				}
+				v, err := l.version()
+				if err != nil || v == last {
+					continue
+				}
+				last = v
+				r.TriggerReload(string(autoconfig.ReasonFileChange))
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("k8s: %s", err)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			w.Close()
+			<-done
+		})
+	}, nil
+}
+
+func (l *Loader) read() (map[string][]byte, error) {
+	if l.fetch != nil {
+		return l.fetch()
+	}
+	files, err := ioutil.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string][]byte{}
+	for _, f := range files {
+		// Skip ..data & timestamped directories managed by Kubernetes
+		if strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(l.dir, f.Name())
+		if fi, err := os.Stat(path); err != nil || fi.IsDir() {
+			continue
+		}
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		data[f.Name()] = raw
+	}
+	return data, nil
+}
+
+func (l *Loader) version() (string, error) {
+	if l.fetch == nil {
+		if target, err := os.Readlink(filepath.Join(l.dir, dataLink)); err == nil {
+			return target, nil
+		}
+	}
+	data, err := l.read()
+	if err != nil {
+		return "", err
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s\x00%d\x00", k, len(data[k]))
+		h.Write(data[k])
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
@@ -0,0 +1,75 @@
+package k8s
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jfbus/autoconfig"
+)
+
+type testCfg struct {
+	Key string `yaml:"key"`
+}
+
+// testListener receives the keys of the reloaded section
+type testListener chan string
+
+func (l testListener) Reconfigure(cfg interface{}) {
+	l <- cfg.(*testCfg).Key
+}
+
+// writeVolume writes data to a new timestamped directory of dir, and swaps the ..data symlink to it, as Kubernetes does
+func writeVolume(t *testing.T, dir, version, data string) {
+	t.Helper()
+	ts := filepath.Join(dir, "..2026_10_16_"+version)
+	if err := os.Mkdir(ts, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(ts, "section.yaml"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmp := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink(filepath.Base(ts), tmp); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, dataLink)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeVolume(t, dir, "1", "key: one\n")
+	if err := os.Symlink(filepath.Join(dataLink, "section.yaml"), filepath.Join(dir, "section.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	l := New(dir)
+	cfg := autoconfig.New(l)
+	c := &testCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "one" {
+		t.Errorf("Expected one, got %s", c.Key)
+	}
+	changes := make(testListener, 10)
+	cfg.Reconfigure("section", changes)
+	// The volume is not polled
+	stop := l.Watch(cfg, time.Hour)
+	defer stop()
+	writeVolume(t, dir, "2", "key: two\n")
+	for {
+		select {
+		case got := <-changes:
+			if got == "two" {
+				return
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("The config should be reloaded when ..data is swapped")
+		}
+	}
+}
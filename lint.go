@@ -0,0 +1,97 @@
+package autoconfig
+
+import "fmt"
+
+// RawLoader is implemented by loaders able to return the raw decoded document (sections, then keys), which lint rules are run against.
+type RawLoader interface {
+	Loader
+	Raw() (map[string]interface{}, error)
+}
+
+// LintLevel defines what happens when a lint rule fails.
+type LintLevel int
+
+const (
+	// LintWarning reports problems in ReloadResult.Warnings, the config is still applied
+	LintWarning LintLevel = iota
+	// LintError reports problems as errors, the config is not applied
+	LintError
+)
+
+// LintRule checks a raw document, and returns the problems found.
+//
+// 	func(doc map[string]interface{}) []error {
+// 		errs := []error{}
+// 		for name, upstream := range doc {
+// 			if u, ok := upstream.(map[string]interface{}); ok && u["timeout"] == nil {
+// 				errs = append(errs, fmt.Errorf("upstream %s has no timeout", name))
+// 			}
+// 		}
+// 		return errs
+// 	}
+type LintRule func(doc map[string]interface{}) []error
+
+// LintProblem is a problem found by a lint rule.
+type LintProblem struct {
+	Rule string
+	Err  error
+}
+
+func (e *LintProblem) Error() string {
+	return fmt.Sprintf("Config: lint rule %s: %s", e.Rule, e.Err)
+}
+
+type lintRule struct {
+	name  string
+	level LintLevel
+	rule  LintRule
+}
+
+// AddLintRule adds a project-specific rule, run against the raw document before each load.
+// Rules are only run for loaders implementing RawLoader.
+func (c *Config) AddLintRule(name string, level LintLevel, rule LintRule) {
+	c.lintRules = append(c.lintRules, lintRule{name: name, level: level, rule: rule})
+}
+
+// AddLintRule adds a project-specific rule to the default config.
+func AddLintRule(name string, level LintLevel, rule LintRule) {
+	globalConfig.AddLintRule(name, level, rule)
+}
+
+// lint runs all rules against the raw document of l, and returns warnings and errors.
+// The sections found in the document are recorded (see Sections).
+func (c *Config) lint(l Loader) (warnings []error, err error) {
+	rl, ok := l.(RawLoader)
+	if !ok {
+		c.present = nil
+		return nil, nil
+	}
+	var doc map[string]interface{}
+	err = protect("loader", func() (err error) {
+		doc, err = rl.Raw()
+		return err
+	})
+	c.present = map[string]bool{}
+	for name := range doc {
+		c.present[name] = true
+	}
+	if len(c.lintRules) == 0 {
+		// Errors are left to the loader
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	errs := Errors{}
+	for _, r := range c.lintRules {
+		for _, problem := range r.rule(doc) {
+			lerr := &LintProblem{Rule: r.name, Err: problem}
+			if r.level == LintError {
+				errs = append(errs, lerr)
+			} else {
+				warnings = append(warnings, lerr)
+			}
+		}
+	}
+	return warnings, errs.errorOrNil()
+}
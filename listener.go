@@ -0,0 +1,70 @@
+package autoconfig
+
+import (
+	"fmt"
+	"log"
+)
+
+// ReconfigurableErr can be implemented by Reconfigurable instances able to reject a new config (e.g. a pool that cannot be resized).
+// When implemented, ReconfigureErr is called instead of Reconfigure. If it returns an error, listeners registered after it are not notified,
+// the section is rolled back to its previous value, listeners notified before are notified again of the previous value (with the ReasonRollback reason),
+// and Reload returns a ListenerError.
+//
+// 	func (c *PkgClass) ReconfigureErr(n interface{}) error {
+// 		return c.pool.Resize(n.(*PkgConf).Size)
+// 	}
+//
+// Rejections are only possible when listeners are notified synchronously : errors of throttled sections, or of configs using WithAsyncNotify,
+// are logged. The initial load of a section cannot be rolled back either.
+type ReconfigurableErr interface {
+	ReconfigureErr(interface{}) error
+}
+
+// UpdatableConfigErr can be implemented by section structs able to reject a new config, see ReconfigurableErr.
+// When implemented, ChangedErr is called instead of Changed.
+type UpdatableConfigErr interface {
+	ChangedErr() error
+}
+
+// ListenerError is returned when a listener has rejected the new value of a section. The section has been rolled back to its previous value.
+type ListenerError struct {
+	Section string
+	Err     error
+}
+
+func (e *ListenerError) Error() string {
+	return fmt.Sprintf("Config: section %s rejected by a listener : %s", e.Section, e.Err)
+}
+
+func (e *ListenerError) Unwrap() error {
+	return e.Err
+}
+
+// notifySync notifies the listeners of s of ev. If a listener rejects ev, s is rolled back to the last value accepted by listeners,
+// and its signature to prev.
+func (s *section) notifySync(ev Event, prev string) (bool, error) {
+	// Listeners may unsubscribe while being notified (e.g. Once listeners) : the listeners notified are rolled back
+	listeners := s.listeners()
+	n, err := notifyAll(listeners, ev)
+	if err == nil {
+		s.accepted = deepCopy(s.current)
+		return true, nil
+	}
+	lerr := &ListenerError{Section: s.name, Err: err}
+	if s.accepted == nil {
+		return true, lerr
+	}
+	rejected := deepCopy(s.current)
+	s.commit(deepCopy(s.accepted))
+	s.signature = prev
+	if cc, ok := s.current.(CommittableConfig); ok {
+		cc.CommitConfig()
+	}
+	rev := Event{Section: s.name, Reason: ReasonRollback, Config: s.current, Previous: rejected}
+	for _, r := range listeners[:n] {
+		if err := deliver(r, rev); err != nil {
+			log.Printf("Config: section %s: listener rejected the rollback : %s", s.name, err)
+		}
+	}
+	return false, lerr
+}
@@ -0,0 +1,124 @@
+package autoconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// Merge strategies, set using the `merge` tag of slice and map fields, define how values of successive layers
+// of Multi and Dir are combined :
+//
+// 	type ServerConf struct {
+// 		// Listeners of all layers are kept
+// 		Listeners []string `yaml:"listeners" merge:"append"`
+// 		// Backends of later layers replace the ones of earlier layers having the same name, others are appended
+// 		Backends []Backend `yaml:"backends" merge:"key=name"`
+// 		// Maps of later layers replace the maps of earlier layers, instead of being merged
+// 		Headers map[string]string `yaml:"headers" merge:"replace"`
+// 	}
+//
+// Without a merge tag, values are combined by the decoder of the format (e.g. YAML replaces slices and merges maps).
+const (
+	mergeAppend  = "append"
+	mergeReplace = "replace"
+	mergeKey     = "key="
+)
+
+// hasMergeTags returns true if values of type t have fields with a merge strategy.
+func hasMergeTags(t reflect.Type) bool {
+	return hasMergeTagsSeen(t, map[reflect.Type]bool{})
+}
+
+func hasMergeTagsSeen(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return false
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("merge"); ok || hasMergeTagsSeen(t.Field(i).Type, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyMerge applies the merge strategies of the fields of after, the result of loading a layer over before,
+// layer holding the values of the layer only.
+func applyMerge(before, after, layer reflect.Value) error {
+	before, after, layer = reflect.Indirect(before), reflect.Indirect(after), reflect.Indirect(layer)
+	if after.Kind() != reflect.Struct || !before.IsValid() || !layer.IsValid() {
+		return nil
+	}
+	for i := 0; i < after.NumField(); i++ {
+		field := after.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		b, a, l := before.Field(i), after.Field(i), layer.Field(i)
+		strategy := field.Tag.Get("merge")
+		switch {
+		case strategy == "":
+			if err := applyMerge(b, a, l); err != nil {
+				return err
+			}
+		case l.Kind() == reflect.Slice && l.Len() == 0, l.Kind() == reflect.Map && l.IsNil():
+			// Not set by the layer
+		case strategy == mergeAppend && a.Kind() == reflect.Slice:
+			a.Set(reflect.AppendSlice(reflect.AppendSlice(reflect.MakeSlice(b.Type(), 0, b.Len()+l.Len()), b), l))
+		case strategy == mergeReplace && a.Kind() == reflect.Map:
+			a.Set(l)
+		case strategy == mergeReplace && a.Kind() == reflect.Slice:
+			// Default behaviour of decoders
+		case strings.HasPrefix(strategy, mergeKey) && a.Kind() == reflect.Slice:
+			merged, err := mergeByKey(b, l, strings.TrimPrefix(strategy, mergeKey))
+			if err != nil {
+				return fmt.Errorf("Config: cannot merge %s: %s", field.Name, err)
+			}
+			a.Set(merged)
+		default:
+			return fmt.Errorf("Config: unsupported merge strategy %q for %s", strategy, field.Name)
+		}
+	}
+	return nil
+}
+
+// mergeByKey returns the elements of before, replaced by the elements of layer having the same key, followed by the other elements of layer.
+func mergeByKey(before, layer reflect.Value, key string) (reflect.Value, error) {
+	out := reflect.MakeSlice(before.Type(), 0, before.Len()+layer.Len())
+	out = reflect.AppendSlice(out, before)
+	index := map[interface{}]int{}
+	for i := 0; i < out.Len(); i++ {
+		k, err := keyOf(out.Index(i), key)
+		if err != nil {
+			return out, err
+		}
+		index[k] = i
+	}
+	for i := 0; i < layer.Len(); i++ {
+		k, err := keyOf(layer.Index(i), key)
+		if err != nil {
+			return out, err
+		}
+		if j, found := index[k]; found {
+			out.Index(j).Set(layer.Index(i))
+		} else {
+			index[k] = out.Len()
+			out = reflect.Append(out, layer.Index(i))
+		}
+	}
+	return out, nil
+}
+
+func keyOf(v reflect.Value, key string) (interface{}, error) {
+	f, ok := fieldByPath(reflect.Indirect(v), []string{key}, decode.Options{})
+	if !ok || !f.Type().Comparable() {
+		return nil, fmt.Errorf("no key %s in %s", key, v.Type())
+	}
+	return f.Interface(), nil
+}
@@ -0,0 +1,146 @@
+package autoconfig
+
+import (
+	"log"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// LoaderMiddleware wraps a loader, e.g. to decrypt values, expand environment variables, log or cache loads,
+// without each loader reimplementing them.
+type LoaderMiddleware func(Loader) Loader
+
+// Chain wraps l with middlewares, the first one being the outermost.
+func Chain(l Loader, middlewares ...LoaderMiddleware) Loader {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		l = middlewares[i](l)
+	}
+	return l
+}
+
+// WithMiddleware wraps the loaders of the config (including per-section loaders) with middlewares, the first one being the outermost.
+func WithMiddleware(middlewares ...LoaderMiddleware) Option {
+	return func(c *Config) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// LoaderFunc creates a middleware from a function called instead of the Load function of the wrapped loader.
+// Raw documents, used by lint rules, are passed through, and next decodes sections using the decoding options of the config (see OptionsLoader).
+func LoaderFunc(fn func(next Loader, cfg map[string]interface{}) error) LoaderMiddleware {
+	return loaderFunc(func(next Loader, cfg map[string]interface{}, o decode.Options) error {
+		return fn(optionsLoader{l: next, o: o}, cfg)
+	})
+}
+
+// loaderFunc creates a middleware from a function called with the decoding options of the config.
+func loaderFunc(fn func(next Loader, cfg map[string]interface{}, o decode.Options) error) LoaderMiddleware {
+	return func(next Loader) Loader {
+		w := wrappedLoader{next: next, load: fn}
+		if rl, ok := next.(RawLoader); ok {
+			return wrappedRawLoader{wrappedLoader: w, raw: rl}
+		}
+		return w
+	}
+}
+
+type wrappedLoader struct {
+	next Loader
+	load func(next Loader, cfg map[string]interface{}, o decode.Options) error
+}
+
+func (w wrappedLoader) Load(cfg map[string]interface{}) error {
+	return w.load(w.next, cfg, decode.Options{})
+}
+
+func (w wrappedLoader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	return w.load(w.next, cfg, o)
+}
+
+type wrappedRawLoader struct {
+	wrappedLoader
+	raw RawLoader
+}
+
+func (w wrappedRawLoader) Raw() (map[string]interface{}, error) {
+	return w.raw.Raw()
+}
+
+// LogLoads logs the duration and the outcome of each load.
+var LogLoads = LoaderFunc(func(next Loader, cfg map[string]interface{}) error {
+	start := time.Now()
+	err := next.Load(cfg)
+	if err != nil {
+		log.Printf("Config: load failed after %s: %s", time.Since(start), err)
+	} else {
+		log.Printf("Config: loaded %d sections in %s", len(cfg), time.Since(start))
+	}
+	return err
+})
+
+// ExpandEnv replaces ${VAR} and $VAR in string values by the value of the environment variable.
+var ExpandEnv = TransformStrings(func(s string) (string, error) {
+	return os.ExpandEnv(s), nil
+})
+
+// TransformStrings transforms all string values (including strings in slices and maps) after they have been loaded,
+// e.g. to decrypt encrypted values.
+func TransformStrings(fn func(string) (string, error)) LoaderMiddleware {
+	return LoaderFunc(func(next Loader, cfg map[string]interface{}) error {
+		if err := next.Load(cfg); err != nil {
+			return err
+		}
+		for _, t := range cfg {
+			if err := transformStrings(reflect.ValueOf(t), fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func transformStrings(v reflect.Value, fn func(string) (string, error)) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			return transformStrings(v.Elem(), fn)
+		}
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		s, err := fn(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				if err := transformStrings(v.Field(i), fn); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := transformStrings(v.Index(i), fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			// Map values are not addressable
+			e := reflect.New(v.Type().Elem()).Elem()
+			e.Set(v.MapIndex(k))
+			if err := transformStrings(e, fn); err != nil {
+				return err
+			}
+			v.SetMapIndex(k, e)
+		}
+	}
+	return nil
+}
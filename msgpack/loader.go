@@ -0,0 +1,162 @@
+// Package msgpack defines a loader for MessagePack config blobs (using https://github.com/vmihailenco/msgpack).
+// The blob must be a top-level map of sections, each section being decoded using the msgpack tags of the section struct.
+// Durations can be written as strings (e.g. "30s"), see the decode package.
+// 	autoconfig.Load(msgpack.New(filename))
+package msgpack
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/jfbus/autoconfig/decode"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type Loader struct {
+	read func() ([]byte, error)
+}
+
+// New creates a Loader for MessagePack files
+func New(filename string) *Loader {
+	return &Loader{read: func() ([]byte, error) {
+		return ioutil.ReadFile(filename)
+	}}
+}
+
+// NewFS creates a Loader for the MessagePack file name of fsys, e.g. an embed.FS
+func NewFS(fsys fs.FS, name string) *Loader {
+	return &Loader{read: func() ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	}}
+}
+
+// NewFromBytes creates a Loader for MessagePack data, e.g. embedded in the binary
+func NewFromBytes(data []byte) *Loader {
+	return &Loader{read: func() ([]byte, error) {
+		return data, nil
+	}}
+}
+
+// NewFromReader creates a Loader for MessagePack data read from r. r is only read once, all reloads use the same data.
+func NewFromReader(r io.Reader) *Loader {
+	var (
+		once sync.Once
+		data []byte
+		err  error
+	)
+	return &Loader{read: func() ([]byte, error) {
+		once.Do(func() {
+			data, err = ioutil.ReadAll(r)
+		})
+		return data, err
+	}}
+}
+
+// Load decodes the blob and unmarshals it to cfg
+func (l *Loader) Load(cfg map[string]interface{}) error {
+	return l.LoadWith(cfg, decode.Options{})
+}
+
+// LoadWith decodes the blob and unmarshals it to cfg using the decoding options o
+func (l *Loader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	load, err := l.Document()
+	if err != nil {
+		return err
+	}
+	return load(cfg, o)
+}
+
+// Document decodes the blob, and returns a function unmarshaling it to sections
+func (l *Loader) Document() (func(cfg map[string]interface{}, o decode.Options) error, error) {
+	data, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	tmp := map[string]msgpack.RawMessage{}
+	if err := unmarshal(data, &tmp); err != nil {
+		return nil, err
+	}
+	return func(cfg map[string]interface{}, o decode.Options) error {
+		return unmarshalSections(tmp, cfg, o)
+	}, nil
+}
+
+// unmarshalSections unmarshals the sections of tmp to cfg
+func unmarshalSections(tmp map[string]msgpack.RawMessage, cfg map[string]interface{}, o decode.Options) error {
+	for name, scfg := range cfg {
+		raw, ok := lookup(tmp, name)
+		if !ok {
+			continue
+		}
+		if err := unmarshalSection(raw, scfg, o); err != nil {
+			return fmt.Errorf("msgpack: section %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// Raw decodes the blob and returns the raw decoded document
+func (l *Loader) Raw() (map[string]interface{}, error) {
+	data, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	doc := map[string]interface{}{}
+	err = unmarshal(data, &doc)
+	return doc, err
+}
+
+// lookup returns the value of a top-level key, or of a nested one using a dotted path (e.g. apps.api.section)
+func lookup(tmp map[string]msgpack.RawMessage, name string) (msgpack.RawMessage, bool) {
+	if raw, ok := tmp[name]; ok {
+		return raw, true
+	}
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		raw, ok := tmp[p]
+		if !ok {
+			return nil, false
+		}
+		if i == len(parts)-1 {
+			return raw, true
+		}
+		tmp = map[string]msgpack.RawMessage{}
+		if err := unmarshal(raw, &tmp); err != nil {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// unmarshal decodes data using a new decoder.
+// msgpack.Unmarshal uses pooled decoders, whose buffer keeps growing when decoding truncated data :
+// reloading a malformed blob would leak memory.
+func unmarshal(data []byte, v interface{}) error {
+	return msgpack.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// unmarshalSection decodes a section using decode.Into, with the msgpack tags of the section struct.
+func unmarshalSection(data []byte, v interface{}, o decode.Options) error {
+	var doc interface{}
+	if err := unmarshal(data, &doc); err != nil {
+		return err
+	}
+	return o.Into(doc, v, "msgpack")
+}
+
+// Checksum returns a checksum of the data, so that autoconfig can skip reloads when it has not changed
+func (l *Loader) Checksum() (string, error) {
+	data, err := l.read()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
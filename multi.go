@@ -0,0 +1,102 @@
+package autoconfig
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+type multiLoader []Loader
+
+// Multi creates a loader loading sections from each loader in order, values of later loaders overriding earlier ones key by key,
+// e.g. to layer embedded defaults, a system file and a user file :
+//
+// 	autoconfig.Load(autoconfig.Multi(
+// 		yaml.NewFS(defaults, "config.yaml"),
+// 		yaml.New("/etc/myapp/config.yaml"),
+// 		yaml.New(filepath.Join(home, ".myapp.yaml")),
+// 	))
+//
+// The first loader is required, later loaders whose file does not exist are skipped. Slices and maps are merged as the format decoder does
+// (e.g. YAML replaces slices and merges maps), unless they have a merge strategy (see the merge tag).
+func Multi(loaders ...Loader) Loader {
+	return multiLoader(loaders)
+}
+
+// localSuffix is the suffix of local override files
+const localSuffix = "local"
+
+// Local creates a loader for filename, merged with its optional local override file (e.g. config.local.yaml for config.yaml),
+// typically git-ignored, so that developers can customize local runs. The local file is skipped if it does not exist,
+// filename is required.
+//
+// 	autoconfig.Load(autoconfig.Local("config.yaml", func(f string) autoconfig.Loader {
+// 		return yaml.New(f)
+// 	}))
+func Local(filename string, format func(filename string) Loader) Loader {
+	return Multi(format(filename), format(siblingFilename(filename, localSuffix)))
+}
+
+func (m multiLoader) Load(cfg map[string]interface{}) error {
+	return m.LoadWith(cfg, decode.Options{})
+}
+
+func (m multiLoader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	// Sections having merge strategies are also loaded from each layer alone
+	merged := map[string]interface{}{}
+	for name, t := range cfg {
+		if hasMergeTags(reflect.TypeOf(t)) {
+			merged[name] = t
+		}
+	}
+	for i, l := range m {
+		before := map[string]interface{}{}
+		layer := map[string]interface{}{}
+		for name, t := range merged {
+			before[name] = deepCopy(t)
+			layer[name] = reflect.New(reflect.TypeOf(t).Elem()).Interface()
+		}
+		if err := LoadWith(l, cfg, o); err != nil {
+			if i > 0 && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		if len(layer) == 0 {
+			continue
+		}
+		if err := LoadWith(l, layer, o); err != nil {
+			return err
+		}
+		for name, t := range merged {
+			if err := applyMerge(reflect.ValueOf(before[name]), reflect.ValueOf(t), reflect.ValueOf(layer[name])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Raw merges the raw documents of all loaders implementing RawLoader, so that lint rules see the merged document.
+func (m multiLoader) Raw() (map[string]interface{}, error) {
+	doc := map[string]interface{}{}
+	for i, l := range m {
+		rl, ok := l.(RawLoader)
+		if !ok {
+			continue
+		}
+		d, err := rl.Raw()
+		if i > 0 && errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range d {
+			doc[k] = mergeValues(doc[k], v)
+		}
+	}
+	return doc, nil
+}
@@ -0,0 +1,40 @@
+package autoconfig
+
+import "sync"
+
+var (
+	namedMu sync.Mutex
+	named   = map[string]*Config{}
+)
+
+// Named returns the global config registered under name, creating it if needed, so that packages can register sections
+// in another config file than the default one without passing a *Config around. Named("") returns the default config.
+// The loader of a named config is defined by LoadNamed, usually in main().
+//
+// 	var _ = autoconfig.Named("tuning").Register("cache", &cacheConf)
+func Named(name string) *Config {
+	if name == "" {
+		return globalConfig
+	}
+	namedMu.Lock()
+	defer namedMu.Unlock()
+	c, found := named[name]
+	if !found {
+		c = New(nil)
+		named[name] = c
+	}
+	return c
+}
+
+// LoadNamed defines the loader (and options) of a named config, and loads it (see Load).
+//
+// 	autoconfig.Load(yaml.New("/etc/myapp/app.yml"))
+// 	autoconfig.LoadNamed("tuning", yaml.New("/etc/myapp/tuning.yml"))
+func LoadNamed(name string, l Loader, opts ...Option) error {
+	c := Named(name)
+	c.loader = l
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c.Load()
+}
@@ -0,0 +1,80 @@
+package autoconfig
+
+import (
+	"log"
+	"sync"
+)
+
+// ReconfigureFunc adapts a function to the Reconfigurable interface.
+type ReconfigureFunc func(cfg interface{})
+
+// Reconfigure calls f(cfg).
+func (f ReconfigureFunc) Reconfigure(cfg interface{}) {
+	f(cfg)
+}
+
+// OnChange registers fn, which will be called with the new value of a section each time it changes (see Reconfigure).
+//
+// 	cfg.OnChange("db", func(c interface{}) {
+// 		pool.Resize(c.(*DBConf).Size)
+// 	})
+func (c *Config) OnChange(name string, fn func(cfg interface{})) bool {
+	return c.Reconfigure(name, ReconfigureFunc(fn))
+}
+
+// OnChange registers fn to the default config, which will be called with the new value of a section each time it changes.
+func OnChange(name string, fn func(cfg interface{})) bool {
+	return globalConfig.OnChange(name, fn)
+}
+
+// Once registers fn, which will be called once, with the current value of a section if the config has already been loaded,
+// or with its value after the next change otherwise, e.g. to wait for the config at startup.
+// fn is unsubscribed once called, or when the returned handle is released.
+//
+// 	ready := make(chan struct{})
+// 	cfg.Once("db", func(c interface{}) {
+// 		close(ready)
+// 	})
+func (c *Config) Once(name string, fn func(cfg interface{})) *Handle {
+	var once sync.Once
+	h := &Handle{c: c, name: name}
+	h.r = ReconfigureFunc(func(cfg interface{}) {
+		once.Do(func() {
+			h.Release()
+			fn(cfg)
+		})
+	})
+	c.Reconfigure(name, h)
+	return h
+}
+
+// Once registers fn to the default config, which will be called once with the current or next value of a section.
+func Once(name string, fn func(cfg interface{})) *Handle {
+	return globalConfig.Once(name, fn)
+}
+
+// OnChangeOf is the typed variant of OnChange, for sections registered as a *T.
+// Notifications of values of other types are logged and dropped.
+//
+// 	autoconfig.OnChangeOf(cfg, "db", func(c *DBConf) {
+// 		pool.Resize(c.Size)
+// 	})
+func OnChangeOf[T any](c *Config, name string, fn func(*T)) bool {
+	return c.OnChange(name, func(cfg interface{}) {
+		if v, ok := cfg.(*T); ok {
+			fn(v)
+		} else {
+			log.Printf("Config: section %s is a %T, not a %T", name, cfg, (*T)(nil))
+		}
+	})
+}
+
+// Subscribe registers fn to the default config, which will be called with the typed new value of a section each time it changes.
+// The section must be registered as a *T, see OnChangeOf.
+//
+// 	var _ = autoconfig.Subscribe("db", func(c *DBConf) {
+// 		pool.Resize(c.Size)
+// 	})
+func Subscribe[T any](name string, fn func(*T)) bool {
+	return OnChangeOf(globalConfig, name, fn)
+}
@@ -0,0 +1,167 @@
+package autoconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// OverrideStore persists runtime overrides (see SetSectionFromJSON), so that they survive process restarts.
+type OverrideStore interface {
+	LoadOverrides() (map[string]json.RawMessage, error)
+	SaveOverrides(map[string]json.RawMessage) error
+}
+
+// WithOverrideStore persists runtime overrides to s. Overrides are read from s on the first load.
+func WithOverrideStore(s OverrideStore) Option {
+	return func(c *Config) {
+		c.overrideStore = s
+	}
+}
+
+// FileOverrideStore returns an OverrideStore using a JSON file. A missing file means no overrides.
+func FileOverrideStore(filename string) OverrideStore {
+	return fileOverrideStore(filename)
+}
+
+type fileOverrideStore string
+
+func (f fileOverrideStore) LoadOverrides() (map[string]json.RawMessage, error) {
+	data, err := ioutil.ReadFile(string(f))
+	if os.IsNotExist(err) {
+		return map[string]json.RawMessage{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	o := map[string]json.RawMessage{}
+	err = json.Unmarshal(data, &o)
+	return o, err
+}
+
+func (f fileOverrideStore) SaveOverrides(o map[string]json.RawMessage) error {
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := string(f) + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, string(f))
+}
+
+// Overrides returns the runtime overrides currently applied, by section.
+func (c *Config) Overrides() map[string]json.RawMessage {
+	o := make(map[string]json.RawMessage, len(c.overrides))
+	for name, data := range c.overrides {
+		o[name] = data
+	}
+	return o
+}
+
+// Overrides returns the runtime overrides currently applied to the default config, by section.
+func Overrides() map[string]json.RawMessage {
+	return globalConfig.Overrides()
+}
+
+// ClearOverride removes the runtime override of a section, and reloads the config.
+func (c *Config) ClearOverride(name string) error {
+	if _, found := c.overrides[name]; !found {
+		return nil
+	}
+	delete(c.overrides, name)
+	if err := c.saveOverrides(); err != nil {
+		return err
+	}
+	return c.Reload()
+}
+
+// ClearOverride removes the runtime override of a section of the default config, and reloads the config.
+func ClearOverride(name string) error {
+	return globalConfig.ClearOverride(name)
+}
+
+// addOverride merges data into the override of a section.
+func (c *Config) addOverride(name string, data []byte) error {
+	if err := c.readOverrides(); err != nil {
+		return err
+	}
+	merged, err := mergeJSON(c.overrides[name], data)
+	if err != nil {
+		return err
+	}
+	c.overrides[name] = merged
+	return c.saveOverrides()
+}
+
+// applyOverrides decodes overrides over the values decoded by loaders.
+func (c *Config) applyOverrides(targets map[string]interface{}) error {
+	if err := c.readOverrides(); err != nil {
+		return err
+	}
+	for name, data := range c.overrides {
+		t, found := targets[name]
+		if !found {
+			continue
+		}
+		if err := c.decodeJSON(data, t); err != nil {
+			return fmt.Errorf("Config: cannot apply override of section %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+func (c *Config) readOverrides() error {
+	if c.overrides != nil {
+		return nil
+	}
+	if c.overrideStore == nil {
+		c.overrides = map[string]json.RawMessage{}
+		return nil
+	}
+	o, err := c.overrideStore.LoadOverrides()
+	if err != nil {
+		return fmt.Errorf("Config: cannot read overrides: %s", err)
+	}
+	c.overrides = o
+	return nil
+}
+
+func (c *Config) saveOverrides() error {
+	if c.overrideStore == nil {
+		return nil
+	}
+	if err := c.overrideStore.SaveOverrides(c.overrides); err != nil {
+		return fmt.Errorf("Config: cannot save overrides: %s", err)
+	}
+	return nil
+}
+
+// mergeJSON deep merges two JSON objects, values of b taking precedence.
+func mergeJSON(a, b []byte) (json.RawMessage, error) {
+	if len(a) == 0 {
+		return b, nil
+	}
+	var av, bv interface{}
+	if err := json.Unmarshal(a, &av); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &bv); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeValues(av, bv))
+}
+
+func mergeValues(a, b interface{}) interface{} {
+	am, aok := a.(map[string]interface{})
+	bm, bok := b.(map[string]interface{})
+	if !aok || !bok {
+		return b
+	}
+	for k, v := range bm {
+		am[k] = mergeValues(am[k], v)
+	}
+	return am
+}
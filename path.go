@@ -0,0 +1,94 @@
+package autoconfig
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// OnChangePath registers fn, which will be called with the value of a single field of a section each time this value changes,
+// instead of on every change of the section. path is a dotted path, matched case-insensitively against the yaml/ini/json tags
+// (or names) of fields, items of maps being matched by key. fn is called with nil if the value no longer exists (e.g. a removed map item).
+//
+// 	cfg.OnChangePath("server", "tls.cert_file", func(v interface{}) {
+// 		srv.ReloadCert(v.(string))
+// 	})
+func (c *Config) OnChangePath(name, path string, fn func(value interface{})) bool {
+	return c.Reconfigure(name, &pathListener{path: strings.Split(path, "."), dec: c.decoding, fn: fn})
+}
+
+// OnChangePath registers fn to the default config, which will be called each time the value of a single field of a section changes.
+func OnChangePath(name, path string, fn func(value interface{})) bool {
+	return globalConfig.OnChangePath(name, path, fn)
+}
+
+type pathListener struct {
+	path []string
+	dec  decode.Options
+	fn   func(interface{})
+}
+
+func (l *pathListener) Reconfigure(cfg interface{}) {
+	l.ReconfigureEvent(Event{Config: cfg})
+}
+
+func (l *pathListener) ReconfigureEvent(ev Event) {
+	n, found := valueByPath(reflect.ValueOf(ev.Config), l.path, l.dec)
+	if ev.Previous != nil {
+		o, existed := valueByPath(reflect.ValueOf(ev.Previous), l.path, l.dec)
+		if found == existed && (!found || reflect.DeepEqual(o.Interface(), n.Interface())) {
+			return
+		}
+	} else if !found {
+		log.Printf("Config: section %s has no field %s", ev.Section, strings.Join(l.path, "."))
+		return
+	}
+	if !found {
+		l.fn(nil)
+		return
+	}
+	l.fn(n.Interface())
+}
+
+// valueByPath returns the value of v at path (see OnChangePath), without modifying v.
+func valueByPath(v reflect.Value, path []string, dec decode.Options) (reflect.Value, bool) {
+	for _, p := range path {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			found := false
+			for i := 0; i < v.NumField(); i++ {
+				field := v.Type().Field(i)
+				if field.PkgPath == "" && strings.EqualFold(fieldKey(field, dec), p) {
+					v, found = v.Field(i), true
+					break
+				}
+			}
+			if !found {
+				return v, false
+			}
+		case reflect.Map:
+			found := false
+			for _, k := range v.MapKeys() {
+				if strings.EqualFold(fmt.Sprint(k.Interface()), p) {
+					v, found = v.MapIndex(k), true
+					break
+				}
+			}
+			if !found {
+				return v, false
+			}
+		default:
+			return v, false
+		}
+	}
+	return v, true
+}
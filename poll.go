@@ -0,0 +1,61 @@
+package autoconfig
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Poll checks filenames every interval, and reloads the config when one of them has changed,
+// for environments where file notifications are unreliable (e.g. NFS, some containers).
+// Files are only hashed when their size or modification time has changed : touching a file without changing it does not reload the config.
+// Calling the returned function stops polling.
+func (c *Config) Poll(interval time.Duration, filenames ...string) (stop func()) {
+	states := make([]fileState, len(filenames))
+	for i, f := range filenames {
+		states[i] = statFile(f, fileState{})
+	}
+	return c.Every(interval, func() {
+		changed := false
+		for i, f := range filenames {
+			s := statFile(f, states[i])
+			if s.exists != states[i].exists || s.sum != states[i].sum {
+				changed = true
+			}
+			states[i] = s
+		}
+		if changed {
+			c.RequestReload(string(ReasonFileChange))
+		}
+	})
+}
+
+// Poll checks filenames every interval, and reloads the default config when one of them has changed.
+func Poll(interval time.Duration, filenames ...string) (stop func()) {
+	return globalConfig.Poll(interval, filenames...)
+}
+
+type fileState struct {
+	exists bool
+	size   int64
+	mod    time.Time
+	sum    [sha256.Size]byte
+}
+
+// statFile returns the state of filename, only reading it if its size or modification time differs from prev.
+func statFile(filename string, prev fileState) fileState {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return fileState{}
+	}
+	if prev.exists && fi.Size() == prev.size && fi.ModTime().Equal(prev.mod) {
+		return prev
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		// Keep the previous state, the file will be read again on the next check
+		return prev
+	}
+	return fileState{exists: true, size: fi.Size(), mod: fi.ModTime(), sum: sha256.Sum256(data)}
+}
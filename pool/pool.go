@@ -0,0 +1,144 @@
+// Package pool defines a worker pool sized by a config section, growing and shrinking live on reload.
+//
+// 	workers:
+// 	  workers: 8
+// 	  queue_size: 100
+//
+// 	p := pool.New(autoconfig.Default(), "workers", pool.Conf{Workers: 4, QueueSize: 10})
+// 	p.Submit(func() { ... })
+package pool
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/jfbus/autoconfig"
+)
+
+var ErrClosed = errors.New("Pool is closed")
+
+// Conf defines the config section of a pool
+type Conf struct {
+	Workers   int `yaml:"workers" ini:"workers" json:"workers"`
+	QueueSize int `yaml:"queue_size" ini:"queue_size" json:"queue_size"`
+}
+
+// Pool runs jobs using a number of workers defined by its config section.
+// When the section changes, workers are started or stopped (once their current job is done), and the queue is resized.
+type Pool struct {
+	mu        sync.Mutex
+	notEmpty  *sync.Cond
+	notFull   *sync.Cond
+	queue     []func()
+	queueSize int
+	workers   int
+	target    int
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// New creates a pool, registers its section with defaults, and starts the workers.
+func New(c *autoconfig.Config, section string, defaults Conf) *Pool {
+	p := &Pool{}
+	p.notEmpty = sync.NewCond(&p.mu)
+	p.notFull = sync.NewCond(&p.mu)
+	p.resize(defaults)
+	c.Register(section, &defaults, autoconfig.Fresh())
+	c.Reconfigure(section, p)
+	return p
+}
+
+// Reconfigure resizes the pool
+func (p *Pool) Reconfigure(cfg interface{}) {
+	if conf, ok := cfg.(*Conf); ok {
+		p.resize(*conf)
+	}
+}
+
+// Submit queues a job, waiting for room in the queue if it is full.
+func (p *Pool) Submit(job func()) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for !p.closed && len(p.queue) >= p.queueSize {
+		p.notFull.Wait()
+	}
+	if p.closed {
+		return ErrClosed
+	}
+	p.queue = append(p.queue, job)
+	p.notEmpty.Signal()
+	return nil
+}
+
+// TrySubmit queues a job if there is room in the queue, and returns false otherwise.
+func (p *Pool) TrySubmit(job func()) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || len(p.queue) >= p.queueSize {
+		return false
+	}
+	p.queue = append(p.queue, job)
+	p.notEmpty.Signal()
+	return true
+}
+
+// Size returns the current number of workers and queue size
+func (p *Pool) Size() (workers, queueSize int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.workers, p.queueSize
+}
+
+// Close stops accepting jobs, and waits for queued jobs to be done.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.notEmpty.Broadcast()
+	p.notFull.Broadcast()
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+func (p *Pool) resize(conf Conf) {
+	if conf.Workers < 1 {
+		conf.Workers = 1
+	}
+	if conf.QueueSize < 1 {
+		conf.QueueSize = 1
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.target = conf.Workers
+	p.queueSize = conf.QueueSize
+	for p.workers < p.target {
+		p.workers++
+		p.wg.Add(1)
+		go p.work()
+	}
+	// Wake up idle workers so that extra ones exit, and blocked submitters if the queue has grown
+	p.notEmpty.Broadcast()
+	p.notFull.Broadcast()
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for {
+		p.mu.Lock()
+		for len(p.queue) == 0 && !p.closed && p.workers <= p.target {
+			p.notEmpty.Wait()
+		}
+		if p.workers > p.target || len(p.queue) == 0 {
+			p.workers--
+			p.mu.Unlock()
+			return
+		}
+		job := p.queue[0]
+		p.queue = p.queue[1:]
+		p.notFull.Signal()
+		p.mu.Unlock()
+		job()
+	}
+}
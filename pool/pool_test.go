@@ -0,0 +1,79 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jfbus/autoconfig"
+	"github.com/jfbus/autoconfig/yaml"
+)
+
+// testLoader loads its raw yaml document, which can be changed between reloads
+type testLoader struct {
+	raw string
+}
+
+func (l *testLoader) Load(cfg map[string]interface{}) error {
+	return yaml.NewFromBytes([]byte(l.raw)).Load(cfg)
+}
+
+// waitWorkers waits for the pool to have n workers
+func waitWorkers(t *testing.T, p *Pool, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		workers, _ := p.Size()
+		if workers == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d workers, got %d", n, workers)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestResize(t *testing.T) {
+	l := &testLoader{raw: "workers:\n  workers: 3\n  queue_size: 2\n"}
+	cfg := autoconfig.New(l)
+	p := New(cfg, "workers", Conf{Workers: 1, QueueSize: 1})
+	defer p.Close()
+	if workers, queueSize := p.Size(); workers != 1 || queueSize != 1 {
+		t.Errorf("The pool should be sized by its defaults, got %d/%d", workers, queueSize)
+	}
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if workers, queueSize := p.Size(); workers != 3 || queueSize != 2 {
+		t.Errorf("The pool should grow on load, got %d/%d", workers, queueSize)
+	}
+	// All workers run jobs concurrently
+	started, release := make(chan struct{}), make(chan struct{})
+	for i := 0; i < 3; i++ {
+		if err := p.Submit(func() {
+			started <- struct{}{}
+			<-release
+		}); err != nil {
+			t.Fatalf("Submit() returned %s", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatalf("Only %d jobs are running concurrently", i)
+		}
+	}
+	if !p.TrySubmit(func() {}) || !p.TrySubmit(func() {}) || p.TrySubmit(func() {}) {
+		t.Error("Jobs should be queued up to the queue size")
+	}
+	l.raw = "workers:\n  workers: 1\n  queue_size: 3\n"
+	if res := cfg.TriggerReload("test"); res.Err != nil {
+		t.Fatalf("TriggerReload() returned %s", res.Err)
+	}
+	if !p.TrySubmit(func() {}) {
+		t.Error("The queue should grow on reload")
+	}
+	close(release)
+	waitWorkers(t, p, 1)
+}
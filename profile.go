@@ -0,0 +1,41 @@
+package autoconfig
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// profilesKey is the key under which per-profile sections are defined.
+const profilesKey = "profiles"
+
+// WithProfile sets the environment profile (e.g. dev, staging, prod), so that one artifact can ship the config of all environments.
+// Each section is loaded from the <section> key, then overlaid by the profiles.<profile>.<section> key if defined.
+// When an application is also set (see WithApp), its sections are overlaid last.
+//
+// 	db:
+// 	  pool: 5
+// 	profiles:
+// 	  prod:
+// 	    db:
+// 	      pool: 50
+//
+// INI files use [profiles.prod.db] sections.
+// Profiles defined in sibling files (e.g. config.prod.yaml) can be loaded using Multi and ProfileFilename.
+func WithProfile(profile string) Option {
+	return func(c *Config) {
+		c.profile = profile
+	}
+}
+
+// ProfileFilename returns the name of the file holding the profile overlay of filename, e.g. config.prod.yaml for config.yaml :
+//
+// 	autoconfig.Load(autoconfig.Multi(yaml.New(f), yaml.New(autoconfig.ProfileFilename(f, "prod"))))
+func ProfileFilename(filename, profile string) string {
+	return siblingFilename(filename, profile)
+}
+
+// siblingFilename inserts suffix before the extension of filename
+func siblingFilename(filename, suffix string) string {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + "." + suffix + ext
+}
@@ -0,0 +1,129 @@
+// Package redis defines a loader reading each section from a redis key, either as a JSON document or as a hash.
+// 	l := redis.New(client, "myapp:config:")
+// 	cfg := autoconfig.New(l)
+// 	cfg.Load()
+// 	l.Watch(cfg)
+package redis
+
+import (
+	"fmt"
+
+	"github.com/jfbus/autoconfig"
+	"github.com/jfbus/autoconfig/decode"
+	"gopkg.in/yaml.v2"
+)
+
+// Client defines the redis commands used by the loader. It can be implemented on top of go-redis, redigo...
+type Client interface {
+	// Get returns the value of a string key, found being false if the key does not exist
+	Get(key string) (value string, found bool, err error)
+	// HGetAll returns all the fields of a hash key, or an empty map if the key does not exist
+	HGetAll(key string) (map[string]string, error)
+	// PSubscribe subscribes to channels matching pattern. Calling close ends the subscription and closes messages.
+	PSubscribe(pattern string) (messages <-chan string, close func(), err error)
+}
+
+// Reloader defines the interface of configs that can be reloaded (e.g. *autoconfig.Config).
+type Reloader interface {
+	RequestReload(reason string)
+}
+
+type Loader struct {
+	client Client
+	prefix string
+	hash   bool
+	// DB is the redis database number, used to subscribe to keyspace notifications
+	DB int
+}
+
+// New creates a Loader reading each section as a JSON document stored in the prefix+section key
+func New(client Client, prefix string) *Loader {
+	return &Loader{client: client, prefix: prefix}
+}
+
+// NewHash creates a Loader reading each section from the fields of the prefix+section hash
+func NewHash(client Client, prefix string) *Loader {
+	return &Loader{client: client, prefix: prefix, hash: true}
+}
+
+// Load reads the keys of all sections and unmarshals them to cfg.
+// JSON documents are decoded using the yaml tags of the section structs, as for the yaml loader.
+func (l *Loader) Load(cfg map[string]interface{}) error {
+	return l.LoadWith(cfg, decode.Options{})
+}
+
+// LoadWith reads the keys of all sections and unmarshals them to cfg using the decoding options o
+func (l *Loader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	for name, scfg := range cfg {
+		doc, found, err := l.read(l.prefix + name)
+		if err != nil {
+			return fmt.Errorf("redis: section %s: %s", name, err)
+		}
+		if !found {
+			continue
+		}
+		if err := o.YAML(doc, scfg); err != nil {
+			return fmt.Errorf("redis: section %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// Watch subscribes to keyspace notifications of the section keys, and reloads r as soon as one of them is written.
+// Notifications received before the reload starts (e.g. for each key written by MSET) are coalesced into a single reload,
+// which is debounced if r has been set up with autoconfig.WithDebounce (see Config.RequestReload).
+// Keyspace notifications must be enabled on the redis server (e.g. notify-keyspace-events "K$h").
+// Calling the returned function stops watching.
+func (l *Loader) Watch(r Reloader) (stop func(), err error) {
+	pattern := fmt.Sprintf("__keyspace@%d__:%s*", l.DB, l.prefix)
+	messages, stop, err := l.client.PSubscribe(pattern)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for range messages {
+			open := drain(messages)
+			r.RequestReload(string(autoconfig.ReasonFileChange))
+			if !open {
+				return
+			}
+		}
+	}()
+	return stop, nil
+}
+
+// drain discards the messages already received, and returns false if messages has been closed.
+func drain(messages <-chan string) bool {
+	for {
+		select {
+		case _, ok := <-messages:
+			if !ok {
+				return false
+			}
+		default:
+			return true
+		}
+	}
+}
+
+func (l *Loader) read(key string) ([]byte, bool, error) {
+	if !l.hash {
+		v, found, err := l.client.Get(key)
+		return []byte(v), found, err
+	}
+	fields, err := l.client.HGetAll(key)
+	if err != nil || len(fields) == 0 {
+		return nil, false, err
+	}
+	m := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		// Parse the value as a YAML scalar, so that numbers & booleans are typed
+		var tv interface{}
+		if err := yaml.Unmarshal([]byte(v), &tv); err != nil {
+			tv = v
+		}
+		m[k] = tv
+	}
+	doc, err := yaml.Marshal(m)
+	return doc, true, err
+}
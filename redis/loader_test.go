@@ -0,0 +1,122 @@
+package redis
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jfbus/autoconfig"
+)
+
+// testClient serves string and hash keys from memory
+type testClient struct {
+	strings  map[string]string
+	hashes   map[string]map[string]string
+	messages chan string
+	pattern  string
+}
+
+func (c *testClient) Get(key string) (string, bool, error) {
+	v, found := c.strings[key]
+	return v, found, nil
+}
+
+func (c *testClient) HGetAll(key string) (map[string]string, error) {
+	return c.hashes[key], nil
+}
+
+func (c *testClient) PSubscribe(pattern string) (<-chan string, func(), error) {
+	c.pattern = pattern
+	var once sync.Once
+	return c.messages, func() { once.Do(func() { close(c.messages) }) }, nil
+}
+
+type testCfg struct {
+	Key   string `yaml:"key"`
+	Port  int    `yaml:"port"`
+	Debug bool   `yaml:"debug"`
+}
+
+func TestLoad(t *testing.T) {
+	client := &testClient{strings: map[string]string{"app:json": `{"key": "one", "port": 80}`}}
+	c := &testCfg{}
+	missing := &testCfg{Key: "default"}
+	if err := New(client, "app:").Load(map[string]interface{}{"json": c, "missing": missing}); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "one" || c.Port != 80 || missing.Key != "default" {
+		t.Errorf("Unexpected sections %+v and %+v", *c, *missing)
+	}
+	client.strings["app:json"] = `{"port": "eighty"}`
+	if err := New(client, "app:").Load(map[string]interface{}{"json": c}); err == nil || !strings.Contains(err.Error(), "section json") {
+		t.Errorf("Expected a decode error for section json, got %v", err)
+	}
+}
+
+func TestLoadHash(t *testing.T) {
+	client := &testClient{hashes: map[string]map[string]string{"app:hash": {"key": "two", "port": "8080", "debug": "true"}}}
+	c := &testCfg{}
+	if err := NewHash(client, "app:").Load(map[string]interface{}{"hash": c}); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "two" || c.Port != 8080 || !c.Debug {
+		t.Errorf("Hash fields should be typed, got %+v", *c)
+	}
+}
+
+// testReloader counts reload requests, blocking the first one until released
+type testReloader struct {
+	mu       sync.Mutex
+	requests int
+	started  chan struct{}
+	release  chan struct{}
+}
+
+func (r *testReloader) RequestReload(reason string) {
+	r.mu.Lock()
+	r.requests++
+	first := r.requests == 1
+	r.mu.Unlock()
+	if first {
+		close(r.started)
+		<-r.release
+	}
+}
+
+func TestWatch(t *testing.T) {
+	client := &testClient{messages: make(chan string, 10)}
+	l := New(client, "app:")
+	l.DB = 2
+	r := &testReloader{started: make(chan struct{}), release: make(chan struct{})}
+	stop, err := l.Watch(r)
+	if err != nil {
+		t.Fatalf("Watch() returned %s", err)
+	}
+	if client.pattern != "__keyspace@2__:app:*" {
+		t.Errorf("Unexpected pattern %s", client.pattern)
+	}
+	client.messages <- "set"
+	<-r.started
+	// A burst of writes while reloading
+	for i := 0; i < 5; i++ {
+		client.messages <- "set"
+	}
+	close(r.release)
+	stop()
+	deadline := time.Now().Add(time.Second)
+	for {
+		r.mu.Lock()
+		requests := r.requests
+		r.mu.Unlock()
+		if requests == 2 {
+			break
+		}
+		if requests > 2 || time.Now().After(deadline) {
+			t.Fatalf("A burst of notifications should be coalesced into a single reload, got %d reloads", requests)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+var _ Reloader = &autoconfig.Config{}
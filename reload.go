@@ -0,0 +1,261 @@
+package autoconfig
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReloadResult describes the outcome of a (re)load.
+type ReloadResult struct {
+	// Reason is the reason given by whoever triggered the reload
+	Reason string
+	// Time is the time the reload started at
+	Time time.Time
+	// Changed lists the sections that have changed, and whose listeners have been notified
+	Changed []string
+	// Unchanged lists the sections that have not changed
+	Unchanged []string
+	// Warnings lists the problems found by lint rules in warning mode
+	Warnings []error
+	// Drifted lists the sections having a different value in the shadow loader (see WithShadow)
+	Drifted []string
+	// Failed lists the errors of the sections that have not been applied (e.g. invalid, frozen or vetoed sections), by section name
+	Failed map[string]error
+	// RestartRequired lists the static fields whose change has been ignored, and requires a restart to be applied, by section name
+	RestartRequired map[string][]string
+	// Degraded is true if the reload has switched the config to degraded mode (see WithDegraded)
+	Degraded bool
+	// Err is the error returned by the loader, if any
+	Err error
+}
+
+// TriggerReload synchronously reloads the config, and returns the outcome of the reload.
+// reason is passed to listeners implementing ReconfigurableEvent : it can either be one of the predefined Reason values, or a custom one.
+// It allows frameworks embedding autoconfig to drive reloads from their own control plane.
+// Reloads are serialized : TriggerReload waits for a running reload to complete. It must not be called by listeners.
+func (c *Config) TriggerReload(reason string) *ReloadResult {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	c.loaded.Store(true)
+	for _, fn := range c.beforeReload {
+		fn()
+	}
+	res := c.load(Reason(reason))
+	res.Reason = reason
+	if res.Err != nil {
+		res.Failed = failedSections(res.Err)
+	}
+	c.setReport(res)
+	c.restartRequired(res)
+	for _, fn := range c.afterReload {
+		fn(res)
+	}
+	return res
+}
+
+// TriggerReload synchronously reloads the default config, and returns the outcome of the reload.
+func TriggerReload(reason string) *ReloadResult {
+	return globalConfig.TriggerReload(reason)
+}
+
+func (c *Config) load(reason Reason) *ReloadResult {
+	var prev map[string]interface{}
+	if c.shadow != nil {
+		prev = c.copies()
+	}
+	l := c.mainLoader()
+	// Explicit reloads always decode sections, e.g. to apply new environment variables
+	same, sum := c.unchanged(l)
+	if same && reason != ReasonManual {
+		return c.skipped()
+	}
+	res := c.loadFrom(l, reason)
+	if res.Err == nil && c.shadow != nil {
+		res.Drifted = c.compareShadow(prev)
+	}
+	c.checkDegraded(res)
+	if res.Err != nil {
+		sum = ""
+	}
+	c.checksum = sum
+	return res
+}
+
+func (c *Config) loadFrom(l Loader, reason Reason) *ReloadResult {
+	res := &ReloadResult{Time: c.clock.Now()}
+	if l == nil {
+		res.Err = ErrNoLoader
+		return res
+	}
+	for _, section := range c.sections {
+		if l, ok := section.current.(sync.Locker); ok && !section.fresh {
+			l.Lock()
+			defer l.Unlock()
+		}
+	}
+	if res.Warnings, res.Err = c.lint(l); res.Err != nil {
+		return res
+	}
+	if l, res.Err = document(l); res.Err != nil {
+		return res
+	}
+	targets := c.targets()
+	var errs Errors
+	if err := c.loadTargets(l, targets); err != nil {
+		// Sections that can be decoded are still applied
+		if targets, errs = c.loadIsolated(l); targets == nil {
+			res.Err = err
+			return res
+		}
+	}
+	if res.Err = c.applyEnv(targets); res.Err != nil {
+		return res
+	}
+	if res.Err = c.applyArgs(targets); res.Err != nil {
+		return res
+	}
+	if res.Err = c.applyOverrides(targets); res.Err != nil {
+		return res
+	}
+	if res.Err = c.check(targets); res.Err != nil {
+		return res
+	}
+	if res.Err = c.validate(targets); res.Err != nil {
+		return res
+	}
+	// Changes of frozen sections are rejected, other sections are still applied
+	if err := c.checkFrozen(targets); err != nil {
+		errs = errs.add(err)
+	}
+	res.RestartRequired = c.checkStatic(targets)
+	c.commit(targets)
+	c.commitConfig(targets)
+	changed, lerrs := c.notify(nil, reason)
+	res.Err = append(errs, lerrs...).errorOrNil()
+	for name, ch := range changed {
+		if ch {
+			res.Changed = append(res.Changed, name)
+		} else {
+			res.Unchanged = append(res.Unchanged, name)
+		}
+	}
+	sort.Strings(res.Changed)
+	sort.Strings(res.Unchanged)
+	return res
+}
+
+// loadIsolated loads each section alone, after the loader has failed to load all sections.
+// It returns the sections that have been loaded, and an error for each section that cannot be loaded,
+// or nil if no section can be loaded.
+func (c *Config) loadIsolated(l Loader) (map[string]interface{}, Errors) {
+	targets := map[string]interface{}{}
+	errs := Errors{}
+	for name, t := range c.targets() {
+		if err := c.loadTargets(l, map[string]interface{}{name: t}); err != nil {
+			errs = append(errs, &DecodeError{Section: name, Err: err})
+			continue
+		}
+		targets[name] = t
+	}
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	return targets, errs
+}
+
+// notify notifies, in dependency order, the listeners of the sections that have changed, and returns which sections have changed,
+// along with the errors of listeners having rejected a change (see ReconfigurableErr).
+// Derived sections are recomputed when one of their dependencies has changed, or when they are in force.
+func (c *Config) notify(force map[string]bool, reason Reason) (map[string]bool, Errors) {
+	changed := map[string]bool{}
+	var errs Errors
+	for _, name := range c.order() {
+		s := c.sections[name]
+		if s.derive != nil && !c.recompute(s, force[name], changed) {
+			changed[name] = false
+			continue
+		}
+		ch, err := s.change(reason, c.async)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if changed[name] = ch; ch {
+			c.record(s, reason)
+			for _, fn := range c.anyChange {
+				fn(name, s.current)
+			}
+		}
+	}
+	return changed, errs
+}
+
+// check checks the values of all sections after they have been loaded.
+func (c *Config) check(targets map[string]interface{}) error {
+	errs := Errors{}
+	for name, t := range targets {
+		errs = append(errs, checkEnums(name, reflect.ValueOf(t))...)
+		errs = append(errs, checkRequired(name, reflect.ValueOf(t))...)
+	}
+	return errs.errorOrNil()
+}
+
+// targets returns the values loaders should decode sections into, by section name (see section.target).
+func (c *Config) targets() map[string]interface{} {
+	targets := map[string]interface{}{}
+	for name, s := range c.sections {
+		if t := s.target(); t != nil {
+			targets[name] = t
+		}
+	}
+	return targets
+}
+
+// commit publishes the values decoded by loaders.
+func (c *Config) commit(targets map[string]interface{}) {
+	for name, t := range targets {
+		c.sections[name].commit(t)
+	}
+}
+
+// loadSection loads a single section, registered after the config has been loaded, and notifies its listeners.
+// Other sections are left untouched.
+func (c *Config) loadSection(name string) error {
+	s := c.sections[name]
+	var err error
+	if ld := c.mainLoader(); ld == nil {
+		err = ErrNoLoader
+	} else if s.derive == nil && s.current != nil {
+		if l, ok := s.current.(sync.Locker); ok && !s.fresh {
+			l.Lock()
+			defer l.Unlock()
+		}
+		t := s.target()
+		targets := map[string]interface{}{name: t}
+		err = c.loadTargets(ld, targets)
+		if err == nil {
+			err = c.applyEnv(targets)
+		}
+		if err == nil {
+			err = c.applyArgs(targets)
+		}
+		if err == nil {
+			err = c.applyOverrides(targets)
+		}
+		if err == nil {
+			err = c.check(targets)
+		}
+		if err == nil {
+			err = c.validate(targets)
+		}
+		if err == nil {
+			s.commit(t)
+			c.commitConfig(targets)
+		}
+	}
+	if _, errs := c.notify(nil, ReasonManual); err == nil {
+		err = errs.errorOrNil()
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+// Package remote defines a loader for remote config backends serving a JSON document along with a version token
+// (e.g. Firebase Remote Config, LaunchDarkly-style SaaS backends, or a plain HTTP endpoint returning an ETag).
+// Each top-level key of the document is a section, decoded using the json tags of the section struct.
+// The document is only decoded, and listeners notified, when its version changes.
+// 	l := remote.New(remote.HTTP(http.DefaultClient, "https://config.example.com/myapp"))
+// 	cfg := autoconfig.New(l)
+// 	cfg.Load()
+// 	l.Watch(cfg, time.Minute)
+//
+// New versions can also be pushed by the backend, using Server-Sent Events (WatchSSE) or long polling (WatchLongPoll).
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jfbus/autoconfig"
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// FetchFunc fetches the remote document, and returns it along with its version.
+// known is the version of the last fetched document ("" if none) : implementations may skip downloading the document
+// when it has not changed (e.g. using conditional requests), and return a nil document with the known version.
+type FetchFunc func(known string) (data []byte, version string, err error)
+
+// Reloader defines the interface of configs that can be reloaded and schedule polling (e.g. *autoconfig.Config).
+type Reloader interface {
+	TriggerReload(reason string) *autoconfig.ReloadResult
+	Every(interval time.Duration, fn func()) (stop func())
+}
+
+type Loader struct {
+	fetch FetchFunc
+
+	mu       sync.Mutex
+	version  string
+	sections map[string]json.RawMessage
+}
+
+// New creates a Loader for the document fetched by fetch
+func New(fetch FetchFunc) *Loader {
+	return &Loader{fetch: fetch}
+}
+
+// HTTP returns a FetchFunc getting the document from url, using the ETag header as version.
+func HTTP(client *http.Client, url string) FetchFunc {
+	return func(known string) ([]byte, string, error) {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		if known != "" {
+			req.Header.Set("If-None-Match", known)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, "", err
+		}
+		defer resp.Body.Close()
+		switch resp.StatusCode {
+		case http.StatusNotModified:
+			return nil, known, nil
+		case http.StatusOK:
+		default:
+			return nil, "", fmt.Errorf("remote: cannot fetch %s: %s", url, resp.Status)
+		}
+		data, err := ioutil.ReadAll(resp.Body)
+		return data, resp.Header.Get("ETag"), err
+	}
+}
+
+// Load unmarshals the last fetched document to cfg. The document is fetched on the first call only,
+// new versions are fetched by Watch.
+func (l *Loader) Load(cfg map[string]interface{}) error {
+	return l.LoadWith(cfg, decode.Options{})
+}
+
+// LoadWith unmarshals the last fetched document to cfg using the decoding options o
+func (l *Loader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	l.mu.Lock()
+	sections := l.sections
+	l.mu.Unlock()
+	if sections == nil {
+		if _, err := l.Poll(); err != nil {
+			return err
+		}
+		l.mu.Lock()
+		sections = l.sections
+		l.mu.Unlock()
+	}
+	for name, scfg := range cfg {
+		raw, ok := sections[name]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+		if err := o.JSON(raw, scfg); err != nil {
+			return fmt.Errorf("remote: section %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// Poll fetches the document, and returns true if its version has changed since the last call.
+func (l *Loader) Poll() (bool, error) {
+	l.mu.Lock()
+	known := l.version
+	l.mu.Unlock()
+	data, version, err := l.fetch(known)
+	if err != nil {
+		return false, err
+	}
+	if data == nil || (version != "" && version == known) {
+		return false, nil
+	}
+	sections := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return false, fmt.Errorf("remote: version %s: %s", version, err)
+	}
+	l.mu.Lock()
+	l.version, l.sections = version, sections
+	l.mu.Unlock()
+	return true, nil
+}
+
+// Version returns the version of the last fetched document
+func (l *Loader) Version() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.version
+}
+
+// Watch polls the backend every interval, and reloads r when the version of the document has changed.
+// Calling the returned function stops watching.
+func (l *Loader) Watch(r Reloader, interval time.Duration) (stop func()) {
+	return r.Every(interval, func() {
+		if changed, err := l.Poll(); err == nil && changed {
+			r.TriggerReload(string(autoconfig.ReasonFileChange))
+		}
+	})
+}
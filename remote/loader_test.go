@@ -0,0 +1,138 @@
+package remote
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jfbus/autoconfig"
+)
+
+type testCfg struct {
+	Key string `json:"key"`
+}
+
+// testBackend serves a versioned document, and announces new versions to SSE subscribers
+type testBackend struct {
+	mu          sync.Mutex
+	version     int
+	notModified int
+	announce    chan int
+}
+
+func (b *testBackend) set(version int) {
+	b.mu.Lock()
+	b.version = version
+	b.mu.Unlock()
+	select {
+	case b.announce <- version:
+	default:
+	}
+}
+
+func (b *testBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/events" {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case v := <-b.announce:
+				fmt.Fprintf(w, "event: version\ndata: %d\n\n", v)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	etag := fmt.Sprint(b.version)
+	if r.Header.Get("If-None-Match") == etag {
+		b.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	fmt.Fprintf(w, `{"section": {"key": "v%d"}}`, b.version)
+}
+
+// testListener receives the keys of the reloaded section
+type testListener chan string
+
+func (l testListener) Reconfigure(cfg interface{}) {
+	l <- cfg.(*testCfg).Key
+}
+
+// waitKey waits for the section to be reloaded to key
+func waitKey(t *testing.T, l testListener, key string) {
+	t.Helper()
+	for {
+		select {
+		case got := <-l:
+			if got == key {
+				return
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("The section has not been reloaded to %s", key)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	b := &testBackend{version: 1, announce: make(chan int)}
+	srv := httptest.NewServer(b)
+	defer srv.Close()
+	l := New(HTTP(srv.Client(), srv.URL))
+	cfg := autoconfig.New(l)
+	c := &testCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	changes := make(testListener, 10)
+	cfg.Reconfigure("section", changes)
+	if c.Key != "v1" || l.Version() != "1" {
+		t.Errorf("Expected v1, got %s (version %s)", c.Key, l.Version())
+	}
+	stop := l.Watch(cfg, 5*time.Millisecond)
+	defer stop()
+	// Polls of an unchanged document send the known version in If-None-Match
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(time.Millisecond) {
+		b.mu.Lock()
+		notModified := b.notModified
+		b.mu.Unlock()
+		if notModified > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Polls should send the known version in If-None-Match")
+		}
+	}
+	b.set(2)
+	waitKey(t, changes, "v2")
+}
+
+func TestWatchSSE(t *testing.T) {
+	b := &testBackend{version: 1, announce: make(chan int)}
+	srv := httptest.NewServer(b)
+	defer srv.Close()
+	l := New(HTTP(srv.Client(), srv.URL))
+	cfg := autoconfig.New(l)
+	c := &testCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	changes := make(testListener, 10)
+	cfg.Reconfigure("section", changes)
+	stop := l.WatchSSE(cfg, srv.Client(), srv.URL+"/events")
+	defer stop()
+	// Announcements are only delivered once the subscription is established
+	b.announce <- 1
+	b.set(3)
+	b.announce <- 3
+	waitKey(t, changes, "v3")
+}
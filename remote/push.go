@@ -0,0 +1,123 @@
+package remote
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jfbus/autoconfig"
+)
+
+// retryDelay is the delay before subscribing again when the connection to the announcement endpoint has been lost
+var retryDelay = time.Second
+
+// WatchSSE subscribes to the Server-Sent Events endpoint url, and reloads r when an event announces a new version
+// (the data of the event, or its id), so that fleets can be updated centrally within seconds :
+//
+// 	event: version
+// 	data: 42
+//
+// The document is then fetched by the loader. The subscription is renewed when the connection is lost.
+// Calling the returned function stops watching.
+func (l *Loader) WatchSSE(r Reloader, client *http.Client, url string) (stop func()) {
+	return l.subscribe(r, func(ctx context.Context, announce func(version string)) error {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("remote: cannot subscribe to %s: %s", url, resp.Status)
+		}
+		var id, data string
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				// End of event
+				if v := strings.TrimSpace(data); v != "" {
+					announce(v)
+				} else if id != "" {
+					announce(id)
+				}
+				id, data = "", ""
+			case strings.HasPrefix(line, "data:"):
+				data += strings.TrimPrefix(line, "data:")
+			case strings.HasPrefix(line, "id:"):
+				id = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+			}
+		}
+		return scanner.Err()
+	})
+}
+
+// WatchLongPoll long-polls url, sending the known version in the If-None-Match header : the server holds the request
+// until a new version is available, and returns it in the ETag header (or 304 Not Modified when the request times out).
+// r is reloaded when a new version is announced. Calling the returned function stops watching.
+func (l *Loader) WatchLongPoll(r Reloader, client *http.Client, url string) (stop func()) {
+	return l.subscribe(r, func(ctx context.Context, announce func(version string)) error {
+		for {
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			if err != nil {
+				return err
+			}
+			if v := l.Version(); v != "" {
+				req.Header.Set("If-None-Match", v)
+			}
+			resp, err := client.Do(req.WithContext(ctx))
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			switch resp.StatusCode {
+			case http.StatusNotModified:
+			case http.StatusOK:
+				announce(resp.Header.Get("ETag"))
+			default:
+				return fmt.Errorf("remote: cannot long-poll %s: %s", url, resp.Status)
+			}
+		}
+	})
+}
+
+// subscribe runs watch until stopped, reloading r when a version different from the current one is announced.
+func (l *Loader) subscribe(r Reloader, watch func(ctx context.Context, announce func(version string)) error) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	announce := func(version string) {
+		if version != "" && version == l.Version() {
+			return
+		}
+		changed, err := l.Poll()
+		if err != nil {
+			log.Printf("remote: cannot fetch version %s: %s", version, err)
+			return
+		}
+		if changed {
+			r.TriggerReload(string(autoconfig.ReasonFileChange))
+		}
+	}
+	go func() {
+		for {
+			err := watch(ctx, announce)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryDelay):
+			}
+			if err != nil {
+				log.Printf("%s", err)
+			}
+		}
+	}()
+	return cancel
+}
@@ -0,0 +1,154 @@
+package autoconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReloadReport returns the outcome of the last reload, or nil if the config has not been loaded yet.
+// It allows callers to log meaningful reload summaries, e.g. for reloads triggered by signals or watchers.
+func (c *Config) ReloadReport() *ReloadResult {
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+	return c.report
+}
+
+// ReloadReport returns the outcome of the last reload of the default config.
+func ReloadReport() *ReloadResult {
+	return globalConfig.ReloadReport()
+}
+
+func (c *Config) setReport(res *ReloadResult) {
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+	c.report = res
+	if res.Err != nil {
+		c.status.LastError, c.status.LastErrorTime = res.Err, res.Time
+	} else {
+		c.status.LastSuccess = res.Time
+		c.status.Generation++
+	}
+}
+
+// LoadStatus describes the state of a config, e.g. for health checks and dashboards.
+type LoadStatus struct {
+	// LastSuccess is the time of the last successful reload
+	LastSuccess time.Time
+	// LastError is the error of the last failed reload, nil if no reload has failed.
+	// The config is failing if LastErrorTime is after LastSuccess.
+	LastError     error
+	LastErrorTime time.Time
+	// Generation is incremented on each successful reload, starting at 1 for the first load
+	Generation uint64
+}
+
+// Status returns the state of the config.
+//
+// 	if st := cfg.Status(); st.LastErrorTime.After(st.LastSuccess) {
+// 		health.Fail("config", st.LastError)
+// 	}
+func (c *Config) Status() LoadStatus {
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+	return c.status
+}
+
+// Status returns the state of the default config.
+func Status() LoadStatus {
+	return globalConfig.Status()
+}
+
+// String returns a one-line summary of the reload.
+func (r *ReloadResult) String() string {
+	parts := []string{fmt.Sprintf("reload (%s)", r.Reason)}
+	if len(r.Changed) > 0 {
+		parts = append(parts, fmt.Sprintf("changed: %s", strings.Join(r.Changed, ", ")))
+	}
+	if len(r.Unchanged) > 0 {
+		parts = append(parts, fmt.Sprintf("unchanged: %s", strings.Join(r.Unchanged, ", ")))
+	}
+	if len(r.Failed) > 0 {
+		names := make([]string, 0, len(r.Failed))
+		for name := range r.Failed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		parts = append(parts, fmt.Sprintf("failed: %s", strings.Join(names, ", ")))
+	}
+	if len(r.RestartRequired) > 0 {
+		names := make([]string, 0, len(r.RestartRequired))
+		for name := range r.RestartRequired {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		parts = append(parts, fmt.Sprintf("restart required: %s", strings.Join(names, ", ")))
+	}
+	if r.Err != nil {
+		parts = append(parts, fmt.Sprintf("error: %s", r.Err))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sectionError is implemented by errors related to a single section.
+type sectionError interface {
+	section() string
+}
+
+func (e *DecodeError) section() string {
+	return e.Section
+}
+
+func (e *FrozenError) section() string {
+	return e.Section
+}
+
+func (e *VetoError) section() string {
+	return e.Section
+}
+
+func (e *ValidationError) section() string {
+	return e.Section
+}
+
+func (e *ListenerError) section() string {
+	return e.Section
+}
+
+func (e *EnumError) section() string {
+	return strings.SplitN(e.Field, ".", 2)[0]
+}
+
+func (e *RequiredError) section() string {
+	return strings.SplitN(e.Field, ".", 2)[0]
+}
+
+// failedSections returns the errors of err related to a single section, by section name.
+func failedSections(err error) map[string]error {
+	failed := map[string]error{}
+	var walk func(err error)
+	walk = func(err error) {
+		if errs, ok := err.(Errors); ok {
+			for _, e := range errs {
+				walk(e)
+			}
+			return
+		}
+		se, ok := err.(sectionError)
+		if !ok {
+			return
+		}
+		name := se.section()
+		if prev, found := failed[name]; found {
+			failed[name] = Errors{prev, err}
+		} else {
+			failed[name] = err
+		}
+	}
+	walk(err)
+	if len(failed) == 0 {
+		return nil
+	}
+	return failed
+}
@@ -0,0 +1,54 @@
+package autoconfig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// RequiredError is returned when a field tagged with `required:"true"` has no value : it is missing from the config file
+// (and environment variables, command-line arguments and overrides), and has no default value.
+type RequiredError struct {
+	Field string
+}
+
+func (e *RequiredError) Error() string {
+	return fmt.Sprintf("Config: missing required value for %s", e.Field)
+}
+
+// checkRequired checks that fields tagged with `required:"true"` are set, i.e. are neither zero nor empty.
+//
+// 	type DBConf struct {
+// 		DSN string `yaml:"dsn" required:"true"`
+// 	}
+func checkRequired(path string, v reflect.Value) Errors {
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	errs := Errors{}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		f := v.Field(i)
+		fpath := path + "." + field.Name
+		if field.Tag.Get("required") == "true" && isEmpty(f) {
+			errs = append(errs, &RequiredError{Field: fpath})
+			continue
+		}
+		if f.Kind() == reflect.Struct || (f.Kind() == reflect.Ptr && !f.IsNil()) {
+			errs = append(errs, checkRequired(fpath, f)...)
+		}
+	}
+	return errs
+}
+
+// isEmpty returns true for zero values, and empty slices and maps.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
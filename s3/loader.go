@@ -0,0 +1,145 @@
+// Package s3 defines a loader fetching the config file from S3 (or any S3-compatible object store).
+// The last successfully loaded object is cached on disk, and used when the object store is unreachable.
+// 	autoconfig.Load(s3.New(client, "bucket", "config.yaml", "/var/cache/myapp/config.yaml", func(f string) autoconfig.Loader {
+// 		return yaml.New(f)
+// 	}))
+package s3
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jfbus/autoconfig"
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// Client fetches objects from an object store. It can be implemented on top of the AWS SDK, minio-go...
+type Client interface {
+	GetObject(bucket, key string) (io.ReadCloser, error)
+}
+
+// FormatFunc creates the loader used to decode a local copy of the object (e.g. yaml.New or ini.New).
+type FormatFunc func(filename string) autoconfig.Loader
+
+type Loader struct {
+	client Client
+	bucket string
+	key    string
+	cache  string
+	format FormatFunc
+
+	mu       sync.Mutex
+	uncached string
+}
+
+// New creates a Loader for the bucket/key object, cached in the cache file
+func New(client Client, bucket, key, cache string, format FormatFunc) *Loader {
+	return &Loader{client: client, bucket: bucket, key: key, cache: cache, format: format}
+}
+
+// Load fetches the object and unmarshals it to cfg. If the object cannot be fetched, the cached copy is used.
+func (l *Loader) Load(cfg map[string]interface{}) error {
+	return l.LoadWith(cfg, decode.Options{})
+}
+
+// LoadWith fetches the object and unmarshals it to cfg using the decoding options o, passed to the format loader.
+func (l *Loader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	load, err := l.Document()
+	if err != nil {
+		return err
+	}
+	return load(cfg, o)
+}
+
+// Document fetches the object, and returns a function unmarshaling it to sections, so that the object is fetched once per reload
+// (see autoconfig.DocumentLoader). If the object cannot be fetched, the cached copy is used.
+// The object is only cached once it has been decoded, so that an invalid object does not replace the last valid one.
+func (l *Loader) Document() (func(cfg map[string]interface{}, o decode.Options) error, error) {
+	tmp, err := l.fetch()
+	if err != nil {
+		if _, serr := os.Stat(l.cache); serr != nil {
+			return nil, err
+		}
+		log.Printf("s3: cannot fetch %s/%s, using cached copy %s: %s", l.bucket, l.key, l.cache, err)
+		return document(l.format(l.cache))
+	}
+	dl, ok := l.format(tmp).(autoconfig.DocumentLoader)
+	if !ok {
+		return l.pending(tmp), nil
+	}
+	load, err := dl.Document()
+	if err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	if err := os.Rename(tmp, l.cache); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	return load, nil
+}
+
+// pending returns a function unmarshaling the object fetched to tmp to sections, for format loaders that only decode sections.
+// tmp is renamed to the cache file once sections have been decoded from it, or removed when the next object is fetched.
+func (l *Loader) pending(tmp string) func(cfg map[string]interface{}, o decode.Options) error {
+	l.mu.Lock()
+	if l.uncached != "" {
+		os.Remove(l.uncached)
+	}
+	l.uncached = tmp
+	l.mu.Unlock()
+	return func(cfg map[string]interface{}, o decode.Options) error {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.uncached != tmp {
+			return autoconfig.LoadWith(l.format(l.cache), cfg, o)
+		}
+		if err := autoconfig.LoadWith(l.format(tmp), cfg, o); err != nil {
+			return err
+		}
+		l.uncached = ""
+		return os.Rename(tmp, l.cache)
+	}
+}
+
+// document returns a function unmarshaling the document read by fl to sections
+func document(fl autoconfig.Loader) (func(cfg map[string]interface{}, o decode.Options) error, error) {
+	if dl, ok := fl.(autoconfig.DocumentLoader); ok {
+		return dl.Document()
+	}
+	return func(cfg map[string]interface{}, o decode.Options) error {
+		return autoconfig.LoadWith(fl, cfg, o)
+	}, nil
+}
+
+// fetch downloads the object to a new temporary file next to the cache file, so that concurrent loaders do not share it
+func (l *Loader) fetch() (string, error) {
+	r, err := l.client.GetObject(l.bucket, l.key)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("s3: cannot read %s/%s: %s", l.bucket, l.key, err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(l.cache), filepath.Base(l.cache)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
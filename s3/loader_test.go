@@ -0,0 +1,106 @@
+package s3
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jfbus/autoconfig"
+	"github.com/jfbus/autoconfig/yaml"
+)
+
+type testClient struct {
+	data string
+	err  error
+	gets int
+}
+
+func (c *testClient) GetObject(bucket, key string) (io.ReadCloser, error) {
+	c.gets++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return ioutil.NopCloser(strings.NewReader(c.data)), nil
+}
+
+type testCfg struct {
+	Key string `yaml:"key"`
+}
+
+func TestLoader(t *testing.T) {
+	dir := t.TempDir()
+	cache := filepath.Join(dir, "config.yaml")
+	client := &testClient{data: "section:\n  key: one\nprofiles:\n  prod:\n    section:\n      key: prod\n"}
+	cfg := autoconfig.New(New(client, "bucket", "config.yaml", cache, func(f string) autoconfig.Loader {
+		return yaml.New(f)
+	}), autoconfig.WithProfile("prod"))
+	c := &testCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "prod" || client.gets != 1 {
+		t.Errorf("The object should be fetched once per reload, got <%s> after %d fetches", c.Key, client.gets)
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("Temporary files should be renamed to the cache file, got %d files", len(files))
+	}
+	client.data = "section: [invalid"
+	if res := cfg.TriggerReload("test"); res.Err == nil {
+		t.Error("An invalid object should be rejected")
+	}
+	if data, _ := ioutil.ReadFile(cache); !strings.Contains(string(data), "one") {
+		t.Errorf("An invalid object should not be cached, got %s", data)
+	}
+	client.err = errors.New("unreachable")
+	client.data = ""
+	if res := cfg.TriggerReload(string(autoconfig.ReasonManual)); res.Err != nil || c.Key != "prod" {
+		t.Errorf("The cached copy should be used, got <%s>, %v", c.Key, res.Err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("Temporary files should be removed, got %d files", len(files))
+	}
+	os.Remove(cache)
+	if res := cfg.TriggerReload(string(autoconfig.ReasonManual)); res.Err == nil {
+		t.Error("A missing cached copy should return the fetch error")
+	}
+}
+
+// testFormat only decodes sections, as format loaders not implementing autoconfig.DocumentLoader do
+type testFormat struct {
+	l autoconfig.Loader
+}
+
+func (f testFormat) Load(cfg map[string]interface{}) error {
+	return f.l.Load(cfg)
+}
+
+func TestLoaderSectionFormat(t *testing.T) {
+	dir := t.TempDir()
+	cache := filepath.Join(dir, "config.yaml")
+	client := &testClient{data: "section:\n  key: one\n"}
+	cfg := autoconfig.New(New(client, "bucket", "config.yaml", cache, func(f string) autoconfig.Loader {
+		return testFormat{yaml.New(f)}
+	}))
+	c := &testCfg{}
+	cfg.Register("section", c)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	client.data = "section:\n  key: [invalid\n"
+	if res := cfg.TriggerReload("test"); res.Err == nil {
+		t.Error("An invalid object should be rejected")
+	}
+	if data, _ := ioutil.ReadFile(cache); !strings.Contains(string(data), "one") {
+		t.Errorf("An invalid object should not replace the cached copy, got %s", data)
+	}
+	client.err = errors.New("unreachable")
+	if res := cfg.TriggerReload("test"); res.Err != nil || c.Key != "one" {
+		t.Errorf("The cached copy should be used, got <%s>, %v", c.Key, res.Err)
+	}
+}
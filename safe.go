@@ -0,0 +1,14 @@
+package autoconfig
+
+import "fmt"
+
+// protect calls fn, turning a panic into an error.
+// Config sources are often user-editable, and a malformed document must not crash the process on reload.
+func protect(what string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Config: %s panicked: %v", what, r)
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,166 @@
+package autoconfig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// SecretPolicy defines how fields tagged with `secret:"true"` are written by Export.
+// It returns the value to write in place of value, or false to strip the field.
+// path is the dotted path of the field, starting with the section name.
+type SecretPolicy func(path string, value interface{}) (interface{}, bool)
+
+// WithSecretPolicy sets the policy applied to secret fields when the config is exported,
+// so that exported configs can be shared (e.g. with vendors or attached to tickets) without leaking secrets.
+// By default, secrets are exported as is.
+//
+// 	type DBConf struct {
+// 		DSN      string `yaml:"dsn"`
+// 		Password string `yaml:"password" secret:"true"`
+// 	}
+//
+// 	cfg := autoconfig.New(l, autoconfig.WithSecretPolicy(autoconfig.StripSecrets))
+func WithSecretPolicy(p SecretPolicy) Option {
+	return func(c *Config) {
+		c.secretPolicy = p
+	}
+}
+
+// StripSecrets removes secret fields.
+func StripSecrets(path string, value interface{}) (interface{}, bool) {
+	return nil, false
+}
+
+// TokenizeSecrets replaces secret values by a token (an HMAC of the value using key), so that exported configs
+// can still be compared : the same value always gives the same token, without the value being disclosed.
+// Tokenized configs cannot be imported.
+func TokenizeSecrets(key []byte) SecretPolicy {
+	return func(path string, value interface{}) (interface{}, bool) {
+		raw, _ := json.Marshal(value)
+		mac := hmac.New(sha256.New, key)
+		mac.Write(raw)
+		return "secret:" + hex.EncodeToString(mac.Sum(nil))[:16], true
+	}
+}
+
+// Redacted encodes cfg, a value of the section name, as Export does, for outputs that are logged or sent to third parties (e.g. webhooks) :
+// secret fields are written according to the secret policy of the config, and stripped if no policy has been set.
+func (c *Config) Redacted(name string, cfg interface{}) ([]byte, error) {
+	raw, err := encodeJSON(cfg, c.decoding)
+	if err != nil {
+		return nil, err
+	}
+	return c.scrubSecrets(c.redactPolicy(), name, reflect.TypeOf(cfg), raw)
+}
+
+// Redacted encodes cfg, a value of a section of the default config, as Export does, secret fields being stripped if no policy has been set.
+func Redacted(name string, cfg interface{}) ([]byte, error) {
+	return globalConfig.Redacted(name, cfg)
+}
+
+// redactPolicy returns the secret policy of the config, or StripSecrets if none has been set.
+func (c *Config) redactPolicy() SecretPolicy {
+	if c.secretPolicy == nil {
+		return StripSecrets
+	}
+	return c.secretPolicy
+}
+
+// scrubSecrets applies p to raw, the JSON encoding of a value of type t.
+func (c *Config) scrubSecrets(p SecretPolicy, path string, t reflect.Type, raw []byte) ([]byte, error) {
+	if p == nil || !hasSecrets(t) {
+		return raw, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(scrub(p, c.decoding, path, t, v))
+}
+
+// scrub applies p to the secret fields of v, a value of type t encoded with the key options o (see encodeJSON).
+func scrub(p SecretPolicy, o decode.Options, path string, t reflect.Type, v interface{}) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			key := o.Key(field, "json")
+			if field.Anonymous && untagged(field) && !decode.IsText(field.Type) {
+				// Fields of untagged embedded structs are promoted
+				scrub(p, o, path, field.Type, m)
+				continue
+			}
+			fv, found := m[key]
+			if field.PkgPath != "" || key == "-" || !found {
+				continue
+			}
+			fpath := path + "." + field.Name
+			if field.Tag.Get("secret") == "true" {
+				if nv, keep := p(fpath, fv); keep {
+					m[key] = nv
+				} else {
+					delete(m, key)
+				}
+				continue
+			}
+			m[key] = scrub(p, o, fpath, field.Type, fv)
+		}
+	case reflect.Slice, reflect.Array:
+		if s, ok := v.([]interface{}); ok {
+			for i := range s {
+				s[i] = scrub(p, o, path, t.Elem(), s[i])
+			}
+		}
+	case reflect.Map:
+		if m, ok := v.(map[string]interface{}); ok {
+			for k := range m {
+				m[k] = scrub(p, o, path+"."+k, t.Elem(), m[k])
+			}
+		}
+	}
+	return v
+}
+
+// hasSecrets returns true if values of type t may hold secret fields.
+func hasSecrets(t reflect.Type) bool {
+	return hasSecretsSeen(t, map[reflect.Type]bool{})
+}
+
+func hasSecretsSeen(t reflect.Type, seen map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).Tag.Get("secret") == "true" || hasSecretsSeen(t.Field(i).Type, seen) {
+				return true
+			}
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return hasSecretsSeen(t.Elem(), seen)
+	}
+	return false
+}
+
+// untagged returns true if field has no config nor json tag naming it.
+func untagged(field reflect.StructField) bool {
+	return field.Tag.Get("config") == "" && strings.Split(field.Tag.Get("json"), ",")[0] == ""
+}
@@ -0,0 +1,88 @@
+package autoconfig
+
+import (
+	"sort"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+// RegisterWithLoader registers a section loaded by its own loader instead of the loader of the config,
+// e.g. to pull secrets from a vault while most sections come from a file. All sections are still reloaded,
+// and their listeners notified, in a single reload cycle.
+//
+// 	cfg := autoconfig.New(yaml.New(filename))
+// 	cfg.RegisterWithLoader("db", &DBConf{}, vault.New(client, "secret/myapp/db"))
+func (c *Config) RegisterWithLoader(name string, s interface{}, l Loader, opts ...SectionOption) bool {
+	return c.Register(name, s, append([]SectionOption{withLoader(l)}, opts...)...)
+}
+
+// RegisterWithLoader registers a section of the default config, loaded by its own loader.
+func RegisterWithLoader(name string, s interface{}, l Loader, opts ...SectionOption) bool {
+	return globalConfig.RegisterWithLoader(name, s, l, opts...)
+}
+
+func withLoader(l Loader) SectionOption {
+	return func(s *section) {
+		s.loader = l
+	}
+}
+
+// mainLoader returns the loader used to (re)load the config : the loader of the config, or a loader dispatching
+// sections to their own loader if some have been registered using RegisterWithLoader.
+// Middlewares are applied to the returned loader, on top of the overlays of the profile and of the application,
+// so that they process each value once.
+func (c *Config) mainLoader() Loader {
+	l := c.loader
+	for _, s := range c.sections {
+		if s.loader != nil {
+			l = sectionLoaders{c}
+			break
+		}
+	}
+	if l == nil || len(c.middlewares) == 0 {
+		return l
+	}
+	return Chain(layers(l, c.overlays()), c.middlewares...)
+}
+
+// sectionLoaders loads each section using its own loader, or the loader of the config.
+type sectionLoaders struct {
+	c *Config
+}
+
+// Raw returns the raw document of the config loader, lint rules are not run against per-section loaders.
+func (sl sectionLoaders) Raw() (map[string]interface{}, error) {
+	if rl, ok := sl.c.loader.(RawLoader); ok {
+		return rl.Raw()
+	}
+	return map[string]interface{}{}, nil
+}
+
+func (sl sectionLoaders) Load(targets map[string]interface{}) error {
+	return sl.LoadWith(targets, decode.Options{})
+}
+
+func (sl sectionLoaders) LoadWith(targets map[string]interface{}, o decode.Options) error {
+	common := map[string]interface{}{}
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if s, found := sl.c.sections[name]; found && s.loader != nil {
+			if err := LoadWith(s.loader, map[string]interface{}{name: targets[name]}, o); err != nil {
+				return err
+			}
+			continue
+		}
+		common[name] = targets[name]
+	}
+	if len(common) == 0 {
+		return nil
+	}
+	if sl.c.loader == nil {
+		return ErrNoLoader
+	}
+	return LoadWith(sl.c.loader, common, o)
+}
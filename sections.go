@@ -0,0 +1,49 @@
+package autoconfig
+
+import (
+	"reflect"
+	"sort"
+)
+
+// SectionInfo describes a registered section, see Sections.
+type SectionInfo struct {
+	Name string
+	// Type is the Go type of the section (e.g. "*db.Conf"), empty for derived sections not computed yet
+	Type string
+	// Defaults is a copy of the default values of the section, nil if none
+	Defaults interface{}
+	// Present is true if the section was found in the last loaded document. It is always false for loaders not implementing RawLoader.
+	Present   bool
+	Derived   bool
+	Listeners int
+}
+
+// Sections describes all registered sections, sorted by name, e.g. for admin UIs and debugging.
+func (c *Config) Sections() []SectionInfo {
+	sections := c.registered()
+	infos := make([]SectionInfo, 0, len(sections))
+	for name, s := range sections {
+		info := SectionInfo{
+			Name:      name,
+			Present:   c.present[name],
+			Derived:   s.derive != nil,
+			Listeners: len(s.listeners()),
+		}
+		if v := s.get(); v != nil {
+			info.Type = reflect.TypeOf(v).String()
+		}
+		if s.defaults.IsValid() {
+			info.Defaults = deepCopy(s.defaults.Interface())
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// Sections describes all sections registered to the default config, sorted by name.
+func Sections() []SectionInfo {
+	return globalConfig.Sections()
+}
@@ -0,0 +1,59 @@
+package autoconfig
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+)
+
+// DriftFunc is called when a section has a different value in the shadow loader and in the authoritative loader.
+type DriftFunc func(section string, authoritative, shadow interface{})
+
+type shadow struct {
+	loader  Loader
+	onDrift DriftFunc
+}
+
+// WithShadow loads l each time the config is loaded, and compares its sections to the ones of the authoritative loader.
+// Differences are reported to onDrift (and in ReloadResult.Drifted), but values from l are never applied.
+// It is meant to be used while migrating from a source to another (e.g. from files to a config service).
+func WithShadow(l Loader, onDrift DriftFunc) Option {
+	return func(c *Config) {
+		c.shadow = &shadow{loader: l, onDrift: onDrift}
+	}
+}
+
+// copies returns a deep copy of all loadable sections.
+func (c *Config) copies() map[string]interface{} {
+	m := map[string]interface{}{}
+	for name, s := range c.sections {
+		if s.derive == nil && s.current != nil {
+			m[name] = deepCopy(s.current)
+		}
+	}
+	return m
+}
+
+// compareShadow loads the shadow loader into prev (copies of the sections taken before the authoritative loader was run),
+// and returns the sections that differ from the applied ones.
+func (c *Config) compareShadow(prev map[string]interface{}) []string {
+	if err := LoadWith(c.shadow.loader, prev, c.decoding); err != nil {
+		log.Printf("Config: cannot load shadow config: %s", err)
+		return nil
+	}
+	drifted := []string{}
+	for name, sv := range prev {
+		cur := c.sections[name].current
+		a, aerr := json.Marshal(cur)
+		b, berr := json.Marshal(sv)
+		if aerr == nil && berr == nil && string(a) == string(b) {
+			continue
+		}
+		drifted = append(drifted, name)
+		if c.shadow.onDrift != nil {
+			c.shadow.onDrift(name, cur, sv)
+		}
+	}
+	sort.Strings(drifted)
+	return drifted
+}
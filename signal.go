@@ -0,0 +1,69 @@
+package autoconfig
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+)
+
+// SignalAction defines what happens when a signal is received (see HandleSignal).
+type SignalAction func(*Config)
+
+var (
+	// ReloadAction reloads the config, as ReloadOn does
+	ReloadAction SignalAction = func(c *Config) {
+		c.RequestReload(string(ReasonSignal))
+	}
+	// DumpAction logs the effective config, as written by ExportRedacted (secrets are stripped unless a secret policy has been set)
+	DumpAction SignalAction = func(c *Config) {
+		buf := &bytes.Buffer{}
+		if err := c.ExportRedacted(buf); err != nil {
+			log.Printf("Config: cannot dump config : %s", err)
+			return
+		}
+		log.Printf("Config: effective config : %s", buf)
+	}
+	// CheckAction checks the config without applying it (see Check), and logs the outcome
+	CheckAction SignalAction = func(c *Config) {
+		if err := c.Check(); err != nil {
+			log.Printf("Config: check failed : %s", err)
+		} else {
+			log.Printf("Config: check succeeded")
+		}
+	}
+)
+
+// HandleSignal runs action each time sig is received, until Close is called, so that different signals can trigger different actions :
+//
+// 	autoconfig.HandleSignal(syscall.SIGHUP, autoconfig.ReloadAction)
+// 	autoconfig.HandleSignal(syscall.SIGUSR1, autoconfig.DumpAction)
+// 	autoconfig.HandleSignal(syscall.SIGUSR2, autoconfig.CheckAction)
+func (c *Config) HandleSignal(sig os.Signal, action SignalAction) {
+	c.handleSignals(context.Background(), action, sig)
+}
+
+// HandleSignal runs action on the default config each time sig is received, until Close is called.
+func HandleSignal(sig os.Signal, action SignalAction) {
+	globalConfig.HandleSignal(sig, action)
+}
+
+// handleSignals runs action each time one of signals is received, until ctx is done or Close is called.
+func (c *Config) handleSignals(ctx context.Context, action SignalAction, signals ...os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ch:
+				action(c)
+			case <-ctx.Done():
+				return
+			case <-c.done:
+				return
+			}
+		}
+	}()
+}
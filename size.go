@@ -0,0 +1,121 @@
+package autoconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Size is a number of bytes, decoded from strings such as "256MB" or "1.5GiB", or from plain numbers of bytes.
+// KB, MB, GB, TB and PB are powers of 1000, KiB, MiB, GiB, TiB and PiB powers of 1024. Units are case-insensitive.
+//
+// 	type CacheConf struct {
+// 		MaxSize autoconfig.Size `yaml:"max_size" default:"64MiB"`
+// 	}
+type Size int64
+
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"pb":  1e15,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+	"pib": 1 << 50,
+}
+
+// ParseSize parses a size such as "256MB" or "1.5GiB".
+func ParseSize(s string) (Size, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	unit, found := sizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !found {
+		return 0, fmt.Errorf("invalid size %q : unknown unit %q", s, strings.TrimSpace(s[i:]))
+	}
+	if n*unit > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q : out of range", s)
+	}
+	return Size(n * unit), nil
+}
+
+// Int64 returns the size in bytes.
+func (s Size) Int64() int64 {
+	return int64(s)
+}
+
+// String returns the size using the largest binary unit it is a multiple of (e.g. "64MiB").
+func (s Size) String() string {
+	for _, u := range []string{"PiB", "TiB", "GiB", "MiB", "KiB"} {
+		if m := int64(sizeUnits[strings.ToLower(u)]); s != 0 && int64(s)%m == 0 {
+			return fmt.Sprintf("%d%s", int64(s)/m, u)
+		}
+	}
+	return fmt.Sprintf("%dB", int64(s))
+}
+
+// UnmarshalText decodes a size from a string (e.g. INI files, environment variables and command-line arguments).
+func (s *Size) UnmarshalText(text []byte) error {
+	v, err := ParseSize(string(text))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+// UnmarshalJSON decodes a size from a JSON string or number.
+func (s *Size) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return s.decode(v)
+}
+
+// UnmarshalYAML decodes a size from a YAML string or number.
+func (s *Size) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	return s.decode(v)
+}
+
+func (s *Size) decode(v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		return s.UnmarshalText([]byte(v))
+	case nil:
+		*s = 0
+		return nil
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		*s = Size(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		*s = Size(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		*s = Size(rv.Float())
+	default:
+		return fmt.Errorf("invalid size %v", v)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package autoconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/jfbus/autoconfig/decode"
+)
+
+const snapshotVersion = 1
+
+type snapshot struct {
+	Version  int                        `json:"version"`
+	Sections map[string]snapshotSection `json:"sections"`
+}
+
+type snapshotSection struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Export writes the currently applied configuration of all sections to w, as a portable JSON snapshot.
+// The snapshot can be imported into another process using Import, e.g. to replay a production config in a test.
+// Secret fields are written according to the secret policy of the config (see WithSecretPolicy).
+func (c *Config) Export(w io.Writer) error {
+	return c.export(w, c.secretPolicy)
+}
+
+// export writes the snapshot of all sections to w, secret fields being written according to p.
+func (c *Config) export(w io.Writer, p SecretPolicy) error {
+	snap := snapshot{Version: snapshotVersion, Sections: map[string]snapshotSection{}}
+	for name, s := range c.registered() {
+		v := s.get()
+		if v == nil {
+			continue
+		}
+		raw, err := s.marshal(v, c.decoding)
+		if err == nil {
+			raw, err = c.scrubSecrets(p, name, reflect.TypeOf(v), raw)
+		}
+		if err != nil {
+			return fmt.Errorf("Config: cannot export section %s: %s", name, err)
+		}
+		snap.Sections[name] = snapshotSection{Type: typeName(v), Value: raw}
+	}
+	enc, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(enc)
+	return err
+}
+
+// Export writes the currently applied configuration of the default config to w.
+func Export(w io.Writer) error {
+	return globalConfig.Export(w)
+}
+
+// ExportRedacted writes the currently applied configuration to w as Export does, for outputs that are logged or served to other processes :
+// secret fields are written according to the secret policy of the config, and stripped if no policy has been set (see Redacted).
+func (c *Config) ExportRedacted(w io.Writer) error {
+	return c.export(w, c.redactPolicy())
+}
+
+// ExportRedacted writes the currently applied configuration of the default config to w, secret fields being stripped if no policy has been set.
+func ExportRedacted(w io.Writer) error {
+	return globalConfig.ExportRedacted(w)
+}
+
+// Import reads a snapshot written by Export and applies it to the registered sections, notifying listeners as a reload would.
+// Sections of the snapshot that are not registered are ignored. A section registered with a different type is an error.
+func (c *Config) Import(r io.Reader) error {
+	snap := snapshot{}
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return err
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("Config: unsupported snapshot version %d", snap.Version)
+	}
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	for name, ss := range snap.Sections {
+		if s, found := c.sections[name]; found && s.current != nil && typeName(s.current) != ss.Type {
+			return fmt.Errorf("Config: snapshot section %s has type %s, %s registered", name, ss.Type, typeName(s.current))
+		}
+	}
+	c.loaded.Store(true)
+	return c.loadFrom(snapshotLoader(snap), ReasonManual).Err
+}
+
+// Import applies a snapshot written by Export to the default config.
+func Import(r io.Reader) error {
+	return globalConfig.Import(r)
+}
+
+// marshal encodes v, the current value of the section, using the key options o, locking it if it implements sync.Locker.
+func (s *section) marshal(v interface{}, o decode.Options) ([]byte, error) {
+	if l, ok := v.(sync.Locker); ok && !s.fresh {
+		l.Lock()
+		defer l.Unlock()
+	}
+	return encodeJSON(v, o)
+}
+
+// snapshotLoader loads the sections of a snapshot, decoding them as the config loading them does.
+type snapshotLoader snapshot
+
+func (l snapshotLoader) Load(cfg map[string]interface{}) error {
+	return l.LoadWith(cfg, decode.Options{})
+}
+
+func (l snapshotLoader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	for name, scfg := range cfg {
+		if ss, ok := l.Sections[name]; ok {
+			if err := o.JSON(ss.Value, scfg); err != nil {
+				return fmt.Errorf("Config: cannot import section %s: %s", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func typeName(v interface{}) string {
+	t := reflect.Indirect(reflect.ValueOf(v)).Type()
+	if t.Name() == "" {
+		return t.String()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
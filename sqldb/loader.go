@@ -0,0 +1,150 @@
+// Package sqldb defines a loader reading sections from a database table, one row per section.
+// Rows either hold a whole section as a JSON document (section, data), or a single key (section, key, value).
+// 	CREATE TABLE config (section VARCHAR(255) PRIMARY KEY, data TEXT);
+//
+// 	l := sqldb.New(db, "config")
+// 	cfg := autoconfig.New(l)
+// 	cfg.Load()
+// 	l.Watch(cfg, time.Minute)
+package sqldb
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jfbus/autoconfig"
+	"github.com/jfbus/autoconfig/decode"
+	"gopkg.in/yaml.v2"
+)
+
+// Reloader defines the interface of configs that can be reloaded and schedule polling (e.g. *autoconfig.Config).
+type Reloader interface {
+	TriggerReload(reason string) *autoconfig.ReloadResult
+	Every(interval time.Duration, fn func()) (stop func())
+}
+
+type Loader struct {
+	db    *sql.DB
+	query string
+}
+
+// New creates a Loader reading the (section, data) columns of table
+func New(db *sql.DB, table string) *Loader {
+	return NewQuery(db, "SELECT section, data FROM "+table)
+}
+
+// NewQuery creates a Loader using a custom query. The query must either return (section, data) rows,
+// data being a JSON document, or (section, key, value) rows.
+func NewQuery(db *sql.DB, query string) *Loader {
+	return &Loader{db: db, query: query}
+}
+
+// Load reads the rows and unmarshals them to cfg.
+// JSON documents are decoded using the yaml tags of the section structs, as for the yaml loader.
+func (l *Loader) Load(cfg map[string]interface{}) error {
+	return l.LoadWith(cfg, decode.Options{})
+}
+
+// LoadWith reads the rows and unmarshals them to cfg using the decoding options o
+func (l *Loader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	data, err := l.read()
+	if err != nil {
+		return err
+	}
+	for name, scfg := range cfg {
+		raw, ok := data[name]
+		if !ok {
+			continue
+		}
+		if err := o.YAML(raw, scfg); err != nil {
+			return fmt.Errorf("sqldb: section %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// Watch polls the table every interval, and reloads r when rows have been updated.
+// Calling the returned function stops watching.
+func (l *Loader) Watch(r Reloader, interval time.Duration) (stop func()) {
+	last, _ := l.version()
+	return r.Every(interval, func() {
+		v, err := l.version()
+		if err != nil || v == last {
+			return
+		}
+		last = v
+		r.TriggerReload(string(autoconfig.ReasonFileChange))
+	})
+}
+
+// read returns the YAML/JSON document of each section
+func (l *Loader) read() (map[string][]byte, error) {
+	rows, err := l.db.Query(l.query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	data := map[string][]byte{}
+	switch len(cols) {
+	case 2:
+		for rows.Next() {
+			var name, doc string
+			if err := rows.Scan(&name, &doc); err != nil {
+				return nil, err
+			}
+			data[name] = []byte(doc)
+		}
+	case 3:
+		kv := map[string]map[string]interface{}{}
+		for rows.Next() {
+			var name, key, value string
+			if err := rows.Scan(&name, &key, &value); err != nil {
+				return nil, err
+			}
+			if _, ok := kv[name]; !ok {
+				kv[name] = map[string]interface{}{}
+			}
+			// Parse the value as a YAML scalar, so that numbers & booleans are typed
+			var v interface{}
+			if err := yaml.Unmarshal([]byte(value), &v); err != nil {
+				v = value
+			}
+			kv[name][key] = v
+		}
+		for name, m := range kv {
+			doc, err := yaml.Marshal(m)
+			if err != nil {
+				return nil, err
+			}
+			data[name] = doc
+		}
+	default:
+		return nil, fmt.Errorf("sqldb: query must return 2 or 3 columns, got %d", len(cols))
+	}
+	return data, rows.Err()
+}
+
+func (l *Loader) version() (string, error) {
+	data, err := l.read()
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(data[name]))
+		h.Write(data[name])
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
@@ -0,0 +1,176 @@
+package sqldb
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jfbus/autoconfig"
+)
+
+// testTables holds the rows served by the test driver, by data source name
+var testTables = struct {
+	sync.Mutex
+	m map[string]*testTable
+}{m: map[string]*testTable{}}
+
+func init() {
+	sql.Register("sqldbtest", testDriver{})
+}
+
+// testTable returns the same rows for any query
+type testTable struct {
+	cols []string
+	rows [][]string
+}
+
+func (t *testTable) set(cols []string, rows ...[]string) {
+	testTables.Lock()
+	t.cols, t.rows = cols, rows
+	testTables.Unlock()
+}
+
+type testDriver struct{}
+
+func (testDriver) Open(name string) (driver.Conn, error) {
+	testTables.Lock()
+	defer testTables.Unlock()
+	t, ok := testTables.m[name]
+	if !ok {
+		return nil, errors.New("unknown table")
+	}
+	return testConn{t}, nil
+}
+
+type testConn struct {
+	t *testTable
+}
+
+func (c testConn) Prepare(query string) (driver.Stmt, error) { return testStmt(c), nil }
+func (c testConn) Close() error                              { return nil }
+func (c testConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type testStmt struct {
+	t *testTable
+}
+
+func (s testStmt) Close() error  { return nil }
+func (s testStmt) NumInput() int { return 0 }
+func (s testStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s testStmt) Query(args []driver.Value) (driver.Rows, error) {
+	testTables.Lock()
+	defer testTables.Unlock()
+	return &testRows{cols: s.t.cols, rows: s.t.rows}, nil
+}
+
+type testRows struct {
+	cols []string
+	rows [][]string
+}
+
+func (r *testRows) Columns() []string { return r.cols }
+func (r *testRows) Close() error      { return nil }
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	for i, v := range r.rows[0] {
+		dest[i] = v
+	}
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// openTable opens a database serving the rows of a new table
+func openTable(t *testing.T, cols []string, rows ...[]string) (*sql.DB, *testTable) {
+	table := &testTable{cols: cols, rows: rows}
+	testTables.Lock()
+	testTables.m[t.Name()] = table
+	testTables.Unlock()
+	db, err := sql.Open("sqldbtest", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, table
+}
+
+type testCfg struct {
+	Key   string `yaml:"key"`
+	Port  int    `yaml:"port"`
+	Debug bool   `yaml:"debug"`
+}
+
+func TestLoad(t *testing.T) {
+	db, table := openTable(t, []string{"section", "data"}, []string{"app", `{"key": "one", "port": 80}`})
+	c := &testCfg{}
+	missing := &testCfg{Key: "default"}
+	if err := New(db, "config").Load(map[string]interface{}{"app": c, "missing": missing}); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "one" || c.Port != 80 || missing.Key != "default" {
+		t.Errorf("Unexpected sections %+v and %+v", *c, *missing)
+	}
+	table.set([]string{"section", "data"}, []string{"app", `{"port": "eighty"}`})
+	if err := New(db, "config").Load(map[string]interface{}{"app": c}); err == nil || !strings.Contains(err.Error(), "section app") {
+		t.Errorf("Expected a decode error for section app, got %v", err)
+	}
+	table.set([]string{"section"}, []string{"app"})
+	if err := New(db, "config").Load(map[string]interface{}{"app": c}); err == nil {
+		t.Error("Queries returning 1 column should be rejected")
+	}
+}
+
+func TestLoadKeyValue(t *testing.T) {
+	db, _ := openTable(t, []string{"section", "key", "value"},
+		[]string{"app", "key", "two"}, []string{"app", "port", "8080"}, []string{"app", "debug", "true"})
+	c := &testCfg{}
+	if err := NewQuery(db, "SELECT section, key, value FROM config").Load(map[string]interface{}{"app": c}); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	if c.Key != "two" || c.Port != 8080 || !c.Debug {
+		t.Errorf("Values should be typed, got %+v", *c)
+	}
+}
+
+// testReloader runs the polling function on demand, and records reload reasons
+type testReloader struct {
+	poll    func()
+	reloads []string
+}
+
+func (r *testReloader) TriggerReload(reason string) *autoconfig.ReloadResult {
+	r.reloads = append(r.reloads, reason)
+	return &autoconfig.ReloadResult{}
+}
+
+func (r *testReloader) Every(interval time.Duration, fn func()) (stop func()) {
+	r.poll = fn
+	return func() {}
+}
+
+func TestWatch(t *testing.T) {
+	db, table := openTable(t, []string{"section", "data"}, []string{"app", `{"key": "one"}`})
+	r := &testReloader{}
+	New(db, "config").Watch(r, time.Minute)
+	r.poll()
+	if len(r.reloads) != 0 {
+		t.Errorf("Unchanged rows should not trigger a reload, got %v", r.reloads)
+	}
+	table.set([]string{"section", "data"}, []string{"app", `{"key": "two"}`})
+	r.poll()
+	r.poll()
+	if len(r.reloads) != 1 || r.reloads[0] != string(autoconfig.ReasonFileChange) {
+		t.Errorf("Updated rows should trigger a single reload, got %v", r.reloads)
+	}
+}
+
+var _ Reloader = &autoconfig.Config{}
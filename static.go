@@ -0,0 +1,68 @@
+package autoconfig
+
+import (
+	"reflect"
+	"strings"
+)
+
+// checkStatic keeps the previous value of the fields tagged with `autoconfig:"static"`, which cannot be changed without a restart
+// (e.g. listener addresses), and returns the paths of the fields that have been kept, by section name.
+// Other fields of the sections are still updated.
+//
+// 	type ServerConf struct {
+// 		Listen  string        `yaml:"listen" autoconfig:"static"`
+// 		Timeout time.Duration `yaml:"timeout"`
+// 	}
+func (c *Config) checkStatic(targets map[string]interface{}) map[string][]string {
+	var restart map[string][]string
+	for name, t := range targets {
+		s := c.sections[name]
+		if s.signature == "" || s.current == nil {
+			// Not loaded yet
+			continue
+		}
+		if fields := keepStatic(name, reflect.ValueOf(t), reflect.ValueOf(s.current)); len(fields) > 0 {
+			if restart == nil {
+				restart = map[string][]string{}
+			}
+			restart[name] = fields
+		}
+	}
+	return restart
+}
+
+// keepStatic sets the static fields of to that differ from the ones of from to their previous value, and returns their paths.
+func keepStatic(path string, to, from reflect.Value) []string {
+	to, from = reflect.Indirect(to), reflect.Indirect(from)
+	if to.Kind() != reflect.Struct || to.Type() != from.Type() {
+		return nil
+	}
+	kept := []string{}
+	for i := 0; i < to.NumField(); i++ {
+		field := to.Type().Field(i)
+		if field.PkgPath != "" || isLock(field.Type) {
+			continue
+		}
+		fpath := path + "." + field.Name
+		if isStatic(field) {
+			if !reflect.DeepEqual(to.Field(i).Interface(), from.Field(i).Interface()) {
+				copyValue(to.Field(i), from.Field(i))
+				kept = append(kept, fpath)
+			}
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct || (field.Type.Kind() == reflect.Ptr && !to.Field(i).IsNil() && !from.Field(i).IsNil()) {
+			kept = append(kept, keepStatic(fpath, to.Field(i), from.Field(i))...)
+		}
+	}
+	return kept
+}
+
+func isStatic(field reflect.StructField) bool {
+	for _, opt := range strings.Split(field.Tag.Get("autoconfig"), ",") {
+		if opt == "static" {
+			return true
+		}
+	}
+	return false
+}
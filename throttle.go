@@ -0,0 +1,56 @@
+package autoconfig
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttle limits the notifications of a section to max per interval, for sources that may change very often (e.g. push-based remote sources).
+// Changes exceeding the limit are coalesced into a single notification at the end of the interval, with the latest state of the section.
+//
+// 	autoconfig.Register("routes", &routes, autoconfig.Throttle(1, 5*time.Second))
+func Throttle(max int, interval time.Duration) SectionOption {
+	return func(s *section) {
+		s.throttle = &throttle{max: max, interval: interval}
+	}
+}
+
+type throttle struct {
+	sync.Mutex
+	max      int
+	interval time.Duration
+	clock    Clock
+	// reload is the reload lock of the config, held by trailing notifications so that they do not run concurrently with reloads
+	reload  sync.Locker
+	start   time.Time
+	count   int
+	pending Timer
+}
+
+// allow returns true if a notification can be sent now. Otherwise, notify is scheduled to be called at the end of the current interval.
+func (t *throttle) allow(notify func()) bool {
+	t.Lock()
+	defer t.Unlock()
+	now := t.clock.Now()
+	if now.Sub(t.start) >= t.interval {
+		t.start = now
+		t.count = 0
+	}
+	if t.count < t.max {
+		t.count++
+		return true
+	}
+	if t.pending == nil {
+		t.pending = t.clock.AfterFunc(t.start.Add(t.interval).Sub(now), func() {
+			t.Lock()
+			t.pending = nil
+			t.start = t.clock.Now()
+			t.count = 1
+			t.Unlock()
+			t.reload.Lock()
+			defer t.reload.Unlock()
+			notify()
+		})
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package autoconfig
+
+// ReloadOnChan reloads the config each time a value is received on ch, until ch is closed or Close is called.
+// It is a cross-platform alternative to ReloadOn(syscall.SIGHUP), e.g. on Windows, where reloads can be triggered
+// by a service control handler, a named pipe or an admin endpoint :
+//
+// 	reload := make(chan struct{}, 1)
+// 	autoconfig.ReloadOnChan(reload)
+// 	http.HandleFunc("/reload", func(w http.ResponseWriter, r *http.Request) {
+// 		select {
+// 		case reload <- struct{}{}:
+// 		default:
+// 		}
+// 	})
+//
+// Reloads are debounced if the config has been set up with WithDebounce.
+func (c *Config) ReloadOnChan(ch <-chan struct{}) {
+	go func() {
+		for {
+			select {
+			case _, ok := <-ch:
+				if !ok {
+					return
+				}
+				c.RequestReload(string(ReasonTrigger))
+			case <-c.done:
+				return
+			}
+		}
+	}()
+}
+
+// ReloadOnChan reloads the default config each time a value is received on ch, until ch is closed or Close is called.
+func ReloadOnChan(ch <-chan struct{}) {
+	globalConfig.ReloadOnChan(ch)
+}
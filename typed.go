@@ -0,0 +1,68 @@
+package autoconfig
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// GetOf returns the value of a section of c registered as a *T, and false if the section does not exist or is not a *T.
+func GetOf[T any](c *Config, name string) (*T, bool) {
+	cfg, ok := c.Get(name)
+	if !ok {
+		return nil, false
+	}
+	v, ok := cfg.(*T)
+	return v, ok
+}
+
+// GetAs returns the value of a section of the default config registered as a *T, and false if the section does not exist or is not a *T.
+//
+// 	if db, ok := autoconfig.GetAs[DBConf]("db"); ok {
+// 		pool.Resize(db.Size)
+// 	}
+func GetAs[T any](name string) (*T, bool) {
+	return GetOf[T](globalConfig, name)
+}
+
+// MustGetAs returns the value of a section of the default config registered as a *T, and panics if the section does not exist or is not a *T.
+func MustGetAs[T any](name string) *T {
+	v, ok := GetAs[T](name)
+	if !ok {
+		panic(fmt.Sprintf("Config: section %s is not a %T", name, v))
+	}
+	return v
+}
+
+// SectionHandle gives access to immutable snapshots of a section, see Section.
+type SectionHandle[T any] struct {
+	v atomic.Pointer[T]
+}
+
+// Load returns a snapshot of the section. Snapshots are never modified : a new snapshot is published each time the section changes,
+// so that readers do not race with reloads.
+func (h *SectionHandle[T]) Load() *T {
+	return h.v.Load()
+}
+
+// SectionOf registers a section of c (see Register), and returns a handle to its snapshots.
+func SectionOf[T any](c *Config, name string, defaults *T, opts ...SectionOption) *SectionHandle[T] {
+	h := &SectionHandle[T]{}
+	h.v.Store(deepCopy(defaults).(*T))
+	c.Register(name, defaults, opts...)
+	OnChangeOf(c, name, func(v *T) {
+		h.v.Store(deepCopy(v).(*T))
+	})
+	return h
+}
+
+// Section registers a section of the default config (see Register), and returns a handle to its snapshots.
+//
+// 	var dbConf = autoconfig.Section("db", &DBConf{Size: 10})
+//
+// 	func connect() {
+// 		conf := dbConf.Load()
+// 		...
+// 	}
+func Section[T any](name string, defaults *T, opts ...SectionOption) *SectionHandle[T] {
+	return SectionOf(globalConfig, name, defaults, opts...)
+}
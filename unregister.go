@@ -0,0 +1,41 @@
+package autoconfig
+
+import "reflect"
+
+// Unregister removes a section and its listeners, and returns false if the section was not registered.
+// The section is no longer loaded nor notified, and is ignored by sections depending on it.
+// Unregister waits for a running reload to complete, it must not be called by listeners.
+func (c *Config) Unregister(name string) bool {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	if _, found := c.sections[name]; !found {
+		return false
+	}
+	c.sectionsMu.Lock()
+	delete(c.sections, name)
+	c.sectionsMu.Unlock()
+	return true
+}
+
+// Unregister removes a section of the default config and its listeners.
+func Unregister(name string) bool {
+	return globalConfig.Unregister(name)
+}
+
+// Unsubscribe removes r from the listeners of a section (see Reconfigure), so that it can be garbage collected,
+// and returns false if r was not registered. Channels returned by Changes are removed using StopChanges.
+func (c *Config) Unsubscribe(name string, r Reconfigurable) bool {
+	s, found := c.lookup(name)
+	if !found {
+		return false
+	}
+	return s.unlisten(func(l Reconfigurable) bool {
+		// Listeners of uncomparable types (e.g. functions registered using OnChange) cannot match r
+		return reflect.TypeOf(l).Comparable() && l == r
+	}) > 0
+}
+
+// Unsubscribe removes r from the listeners of a section of the default config.
+func Unsubscribe(name string, r Reconfigurable) bool {
+	return globalConfig.Unsubscribe(name, r)
+}
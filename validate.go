@@ -0,0 +1,128 @@
+package autoconfig
+
+import "fmt"
+
+// ValidatableConfig defines the interface of sections that can veto a reload.
+// ValidateConfig is called on the current value of the section, with the newly loaded value (of the same type).
+// If it returns an error, no section is changed, and the reload returns a VetoError.
+// Sections implementing sync.Locker are locked while ValidateConfig is called.
+type ValidatableConfig interface {
+	ValidateConfig(new interface{}) error
+}
+
+// Validator defines the interface of sections checking their own values. Validate is called on each newly decoded value,
+// before it replaces the current one : if it returns an error, no section is changed, and the reload returns a ValidationError.
+//
+// 	func (c *PoolConf) Validate() error {
+// 		if c.Min > c.Max {
+// 			return errors.New("min must not exceed max")
+// 		}
+// 		return nil
+// 	}
+type Validator interface {
+	Validate() error
+}
+
+// CommittableConfig defines the interface of sections notified once all sections have accepted the new values
+// (see ValidatableConfig) and the new values have been applied, before listeners are notified.
+type CommittableConfig interface {
+	CommitConfig()
+}
+
+// VetoError is returned when a section has rejected the newly loaded values.
+type VetoError struct {
+	Section string
+	Err     error
+}
+
+func (e *VetoError) Error() string {
+	return fmt.Sprintf("Config: section %s rejected the new config : %s", e.Section, e.Err)
+}
+
+func (e *VetoError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned when the newly loaded value of a section is invalid (see Validator and AddValidator).
+// Field is the path of the invalid field (e.g. "server.TLS.Port"), if known.
+type ValidationError struct {
+	Section string
+	Field   string
+	Err     error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("Config: invalid value for %s : %s", e.Field, e.Err)
+	}
+	return fmt.Sprintf("Config: invalid section %s : %s", e.Section, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// AddValidator adds a function checking the newly decoded value of each section before it replaces the current one,
+// e.g. to enforce validation tags (see the validator package). If it returns an error, no section is changed, and the reload
+// returns the error, wrapped in a ValidationError unless it is one, or Errors of ValidationError (e.g. one per invalid field).
+func (c *Config) AddValidator(fn func(section string, cfg interface{}) error) {
+	c.validators = append(c.validators, fn)
+}
+
+// AddValidator adds a function checking the newly decoded value of each section of the default config.
+func AddValidator(fn func(section string, cfg interface{}) error) {
+	globalConfig.AddValidator(fn)
+}
+
+// validate checks the new values of sections using validators and the Validator interface,
+// then asks sections implementing ValidatableConfig to accept them.
+func (c *Config) validate(targets map[string]interface{}) error {
+	errs := Errors{}
+	for name, t := range targets {
+		verrs := c.validateSection(name, t)
+		if len(verrs) > 0 {
+			errs = append(errs, verrs...)
+			continue
+		}
+		v, ok := c.sections[name].current.(ValidatableConfig)
+		if !ok {
+			continue
+		}
+		if err := protect("validator", func() error { return v.ValidateConfig(t) }); err != nil {
+			errs = append(errs, &VetoError{Section: name, Err: err})
+		}
+	}
+	return errs.errorOrNil()
+}
+
+// validateSection returns the errors of validators and of the Validate method of t, the new value of a section.
+func (c *Config) validateSection(name string, t interface{}) Errors {
+	errs := Errors{}
+	add := func(err error) {
+		switch e := err.(type) {
+		case nil:
+		case Errors:
+			errs = append(errs, e...)
+		case *ValidationError:
+			errs = append(errs, e)
+		default:
+			errs = append(errs, &ValidationError{Section: name, Err: err})
+		}
+	}
+	for _, fn := range c.validators {
+		add(protect("validator", func() error { return fn(name, t) }))
+	}
+	if v, ok := t.(Validator); ok {
+		add(protect("validator", v.Validate))
+	}
+	return errs
+}
+
+// commitConfig notifies sections implementing CommittableConfig that new values have been applied.
+func (c *Config) commitConfig(targets map[string]interface{}) {
+	for name := range targets {
+		if cc, ok := c.sections[name].current.(CommittableConfig); ok {
+			cc.CommitConfig()
+		}
+	}
+}
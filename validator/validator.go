@@ -0,0 +1,58 @@
+// Package validator enforces https://github.com/go-playground/validator tags on config sections, on each load and reload :
+//
+// 	type ServerConf struct {
+// 		Port int    `yaml:"port" validate:"min=1,max=65535"`
+// 		Host string `yaml:"host" validate:"required,hostname"`
+// 	}
+//
+// 	cfg := autoconfig.New(yaml.New(filename))
+// 	validator.Bind(cfg, nil)
+//
+// Invalid sections are not applied, and loads return an autoconfig.ValidationError for each invalid field.
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jfbus/autoconfig"
+)
+
+// Bind validates the new value of each struct section of c before it is applied, using v, or a new validator if v is nil
+// (e.g. to register custom validations).
+func Bind(c *autoconfig.Config, v *validator.Validate) {
+	if v == nil {
+		v = validator.New()
+	}
+	c.AddValidator(func(section string, cfg interface{}) error {
+		if reflect.Indirect(reflect.ValueOf(cfg)).Kind() != reflect.Struct {
+			return nil
+		}
+		return fieldErrors(section, v.Struct(cfg))
+	})
+}
+
+// fieldErrors converts the validation errors of a section to an autoconfig.ValidationError per field.
+// Fields are named as the other errors of autoconfig name them, e.g. server.TLS.Port.
+func fieldErrors(section string, err error) error {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	errs := autoconfig.Errors{}
+	for _, fe := range verrs {
+		field := section
+		if i := strings.Index(fe.StructNamespace(), "."); i >= 0 {
+			field += fe.StructNamespace()[i:]
+		}
+		rule := fe.Tag()
+		if fe.Param() != "" {
+			rule += "=" + fe.Param()
+		}
+		errs = append(errs, &autoconfig.ValidationError{Section: section, Field: field, Err: fmt.Errorf("%#v does not satisfy %s", fe.Value(), rule)})
+	}
+	return errs
+}
@@ -0,0 +1,65 @@
+package validator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jfbus/autoconfig"
+	"github.com/jfbus/autoconfig/yaml"
+)
+
+type testTLS struct {
+	Port int `yaml:"port" validate:"min=1,max=65535"`
+}
+
+type testServer struct {
+	Host string  `yaml:"host" validate:"required"`
+	TLS  testTLS `yaml:"tls"`
+}
+
+// testLoader loads its raw yaml document, which can be changed between reloads
+type testLoader struct {
+	raw string
+}
+
+func (l *testLoader) Load(cfg map[string]interface{}) error {
+	return yaml.NewFromBytes([]byte(l.raw)).Load(cfg)
+}
+
+func TestBind(t *testing.T) {
+	l := &testLoader{raw: "server:\n  host: localhost\n  tls:\n    port: 443\n"}
+	cfg := autoconfig.New(l)
+	Bind(cfg, nil)
+	s := &testServer{}
+	cfg.Register("server", s)
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	l.raw = "server:\n  host: example.com\n  tls:\n    port: 70000\n"
+	res := cfg.TriggerReload("test")
+	if res.Err == nil {
+		t.Fatal("A field failing its validate tag should reject the reload")
+	}
+	if s.Host != "localhost" || s.TLS.Port != 443 {
+		t.Errorf("An invalid section should not be applied, got %+v", *s)
+	}
+	var verr *autoconfig.ValidationError
+	if !errors.As(res.Err, &verr) {
+		t.Fatalf("Expected a ValidationError, got %v", res.Err)
+	}
+	if verr.Section != "server" || verr.Field != "server.TLS.Port" {
+		t.Errorf("Expected a ValidationError for server.TLS.Port, got %s/%s", verr.Section, verr.Field)
+	}
+	if msg := verr.Err.Error(); msg != "70000 does not satisfy max=65535" {
+		t.Errorf("Unexpected message %q", msg)
+	}
+	l.raw = "server:\n  tls:\n    port: 8443\n"
+	res = cfg.TriggerReload("test")
+	if !errors.As(res.Err, &verr) || verr.Field != "server.Host" || verr.Err.Error() != `"" does not satisfy required` {
+		t.Errorf("Expected a ValidationError for server.Host, got %v", res.Err)
+	}
+	l.raw = "server:\n  host: example.com\n  tls:\n    port: 8443\n"
+	if res = cfg.TriggerReload("test"); res.Err != nil || s.Host != "example.com" || s.TLS.Port != 8443 {
+		t.Errorf("A valid section should be applied, got %+v, %v", *s, res.Err)
+	}
+}
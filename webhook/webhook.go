@@ -0,0 +1,272 @@
+// Package webhook posts config change notifications to an external HTTP endpoint, e.g. to feed audit logs or deployment automation.
+// Payloads are signed using HMAC-SHA256, so that receivers can check that notifications are authentic.
+// 	s := webhook.New("https://hooks.example.com/config", []byte(secret))
+// 	s.Watch(autoconfig.Default(), "db", "cache")
+//
+// Each notification is a JSON document :
+// 	{"section": "db", "reason": "signal", "revision": 3, "time": "...", "diff": {"pool.max": {"old": 10, "new": 20}}}
+// and is sent with the following headers :
+// 	X-Autoconfig-Signature: sha256=<hex encoded HMAC-SHA256 of the body>
+// 	X-Autoconfig-Revision: <revision>
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jfbus/autoconfig"
+)
+
+const (
+	// SignatureHeader is the header holding the signature of the payload
+	SignatureHeader = "X-Autoconfig-Signature"
+	// RevisionHeader is the header holding the revision of the notification
+	RevisionHeader = "X-Autoconfig-Revision"
+)
+
+// queueSize is the number of notifications waiting to be sent before new ones are dropped
+const queueSize = 100
+
+// retryDelay is the delay before the first retry of a notification that could not be sent, doubled after each retry
+var retryDelay = time.Second
+
+// Change is the old and new value of a changed key. Values are nil for added and removed keys.
+type Change struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// Payload is the body of a notification.
+type Payload struct {
+	Section string `json:"section"`
+	Reason  string `json:"reason"`
+	// Revision is incremented on each notification sent by a Sink
+	Revision uint64    `json:"revision"`
+	Time     time.Time `json:"time"`
+	// Diff maps the dotted path of changed keys to their old and new values
+	Diff map[string]Change `json:"diff"`
+}
+
+// Sink sends notifications to a webhook endpoint. Notifications are sent in order, in the background.
+type Sink struct {
+	url    string
+	secret []byte
+	// Client is the client used to send notifications
+	Client *http.Client
+	// Retries is the number of times a notification is sent again when it could not be sent. It must be set before Watch is called.
+	Retries int
+
+	mu        sync.Mutex
+	revision  uint64
+	last      map[string]map[string]interface{}
+	listeners []*listener
+	queue     chan notification
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates a Sink posting notifications to url, signed using secret
+func New(url string, secret []byte) *Sink {
+	s := &Sink{
+		url:     url,
+		secret:  secret,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+		Retries: 3,
+		last:    map[string]map[string]interface{}{},
+		queue:   make(chan notification, queueSize),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Watch sends a notification each time one of sections of c changes.
+// The initial load of a section is not notified, it is only used as the base of the next diff.
+// Secret fields are diffed according to the secret policy of c, and are not sent if no policy has been set (see Config.Redacted).
+// Notifications are timestamped using the clock of c.
+func (s *Sink) Watch(c *autoconfig.Config, sections ...string) {
+	for _, name := range sections {
+		l := &listener{sink: s, c: c, section: name}
+		s.mu.Lock()
+		s.listeners = append(s.listeners, l)
+		s.mu.Unlock()
+		c.Reconfigure(name, l)
+	}
+}
+
+// Close stops watching sections and sending notifications. Pending notifications are dropped.
+func (s *Sink) Close() {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		listeners := s.listeners
+		s.listeners = nil
+		s.mu.Unlock()
+		for _, l := range listeners {
+			l.c.Unsubscribe(l.section, l)
+		}
+		close(s.done)
+	})
+}
+
+// Sign returns the signature of body, as sent in the SignatureHeader header
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a notification received by a webhook endpoint
+func Verify(secret, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+type notification struct {
+	revision uint64
+	body     []byte
+}
+
+type listener struct {
+	sink    *Sink
+	c       *autoconfig.Config
+	section string
+}
+
+func (l *listener) Reconfigure(cfg interface{}) {
+	l.ReconfigureEvent(autoconfig.Event{Section: l.section, Reason: autoconfig.ReasonManual, Config: cfg})
+}
+
+func (l *listener) ReconfigureEvent(ev autoconfig.Event) {
+	l.sink.changed(l.c, ev)
+}
+
+func (s *Sink) changed(c *autoconfig.Config, ev autoconfig.Event) {
+	values, err := flatten(c, ev.Section, ev.Config)
+	if err != nil {
+		log.Printf("webhook: cannot encode section %s: %s", ev.Section, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, known := s.last[ev.Section]
+	s.last[ev.Section] = values
+	if !known || ev.Reason == autoconfig.ReasonInitialLoad {
+		return
+	}
+	d := diff(old, values)
+	if len(d) == 0 {
+		return
+	}
+	s.revision++
+	body, err := json.Marshal(Payload{Section: ev.Section, Reason: string(ev.Reason), Revision: s.revision, Time: c.Now(), Diff: d})
+	if err != nil {
+		log.Printf("webhook: cannot encode notification for section %s: %s", ev.Section, err)
+		return
+	}
+	select {
+	case s.queue <- notification{revision: s.revision, body: body}:
+	default:
+		log.Printf("webhook: queue full, dropping notification %d for section %s", s.revision, ev.Section)
+	}
+}
+
+func (s *Sink) run() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case n := <-s.queue:
+			s.deliver(n)
+		}
+	}
+}
+
+// deliver sends n, retrying up to Retries times, until the Sink is closed.
+func (s *Sink) deliver(n notification) {
+	delay := retryDelay
+	for retry := 0; ; retry++ {
+		err := s.send(n)
+		if err == nil {
+			return
+		}
+		if retry >= s.Retries {
+			log.Printf("webhook: cannot send notification %d to %s: %s", n.revision, s.url, err)
+			return
+		}
+		select {
+		case <-s.done:
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (s *Sink) send(n notification) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(n.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(s.secret, n.body))
+	req.Header.Set(RevisionHeader, strconv.FormatUint(n.revision, 10))
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+// flatten returns the values of a section by dotted path, as encoded by c (secrets being redacted)
+func flatten(c *autoconfig.Config, section string, cfg interface{}) (map[string]interface{}, error) {
+	data, err := c.Redacted(section, cfg)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	out := map[string]interface{}{}
+	flattenValue(out, nil, v)
+	return out, nil
+}
+
+func flattenValue(out map[string]interface{}, path []string, v interface{}) {
+	if m, ok := v.(map[string]interface{}); ok && len(m) > 0 {
+		for k, sub := range m {
+			flattenValue(out, append(path[:len(path):len(path)], k), sub)
+		}
+		return
+	}
+	out[strings.Join(path, ".")] = v
+}
+
+func diff(old, new map[string]interface{}) map[string]Change {
+	d := map[string]Change{}
+	for k, o := range old {
+		if n, ok := new[k]; !ok || !reflect.DeepEqual(o, n) {
+			d[k] = Change{Old: o, New: new[k]}
+		}
+	}
+	for k, n := range new {
+		if _, ok := old[k]; !ok {
+			d[k] = Change{New: n}
+		}
+	}
+	return d
+}
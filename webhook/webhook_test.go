@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jfbus/autoconfig"
+	"github.com/jfbus/autoconfig/yaml"
+)
+
+type testCfg struct {
+	Key      string `json:"key" yaml:"key"`
+	Password string `json:"password" yaml:"password" secret:"true"`
+}
+
+// testLoader loads its raw yaml document, which can be changed between reloads
+type testLoader struct {
+	raw string
+}
+
+func (l *testLoader) Load(cfg map[string]interface{}) error {
+	return yaml.NewFromBytes([]byte(l.raw)).Load(cfg)
+}
+
+// testEndpoint fails the first fail requests, and records the payloads of the other ones
+type testEndpoint struct {
+	mu       sync.Mutex
+	fail     int
+	attempts int
+	payloads chan Payload
+}
+
+func (e *testEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	e.mu.Lock()
+	e.attempts++
+	fail := e.attempts <= e.fail
+	e.mu.Unlock()
+	if fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if !Verify([]byte("secret"), body, r.Header.Get(SignatureHeader)) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	p := Payload{}
+	json.Unmarshal(body, &p)
+	e.payloads <- p
+}
+
+func TestSink(t *testing.T) {
+	retryDelay = time.Millisecond
+	e := &testEndpoint{fail: 2, payloads: make(chan Payload, 10)}
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	l := &testLoader{raw: "section:\n  key: one\n  password: a\n"}
+	cfg := autoconfig.New(l)
+	cfg.Register("section", &testCfg{})
+	if err := cfg.Load(); err != nil {
+		t.Fatalf("Load() returned %s", err)
+	}
+	s := New(srv.URL, []byte("secret"))
+	s.Watch(cfg, "section")
+	l.raw = "section:\n  key: two\n  password: b\n"
+	cfg.TriggerReload("test")
+	select {
+	case p := <-e.payloads:
+		if p.Section != "section" || p.Revision != 1 || len(p.Diff) != 1 || p.Diff["key"].Old != "one" || p.Diff["key"].New != "two" {
+			t.Errorf("Unexpected payload %+v", p)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("The notification should be sent once the endpoint is available again")
+	}
+	e.mu.Lock()
+	if e.attempts != 3 {
+		t.Errorf("The notification should have been retried twice, got %d attempts", e.attempts)
+	}
+	e.mu.Unlock()
+
+	s.Close()
+	s.Close()
+	if info := cfg.Sections()[0]; info.Listeners != 0 {
+		t.Errorf("Closed sinks should stop watching sections, got %d listeners", info.Listeners)
+	}
+	l.raw = "section:\n  key: three\n"
+	cfg.TriggerReload("test")
+	select {
+	case p := <-e.payloads:
+		t.Errorf("Closed sinks should not send notifications, got %+v", p)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
@@ -0,0 +1,143 @@
+package yaml
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// includeKey is the top-level key listing the files included by a document.
+// Relative paths are resolved from the directory of the including file.
+const includeKey = "include"
+
+// includer reads included files
+type includer interface {
+	// resolve returns the name of file, included by base
+	resolve(base, file string) string
+	readFile(name string) ([]byte, error)
+}
+
+type osIncluder struct{}
+
+func (osIncluder) resolve(base, file string) string {
+	if filepath.IsAbs(file) || base == "" {
+		return file
+	}
+	return filepath.Join(filepath.Dir(base), file)
+}
+
+func (osIncluder) readFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+type fsIncluder struct {
+	fsys fs.FS
+}
+
+func (fsIncluder) resolve(base, file string) string {
+	return path.Join(path.Dir(base), file)
+}
+
+func (f fsIncluder) readFile(name string) ([]byte, error) {
+	return fs.ReadFile(f.fsys, name)
+}
+
+// document reads and decodes the config file, merging the files it includes
+func (l *Loader) document() (map[string]interface{}, error) {
+	data, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	var included []string
+	doc, err := l.decode(data, l.name, []string{l.name}, &included)
+	if err == nil {
+		l.mu.Lock()
+		l.included = included
+		l.mu.Unlock()
+	}
+	return doc, err
+}
+
+// decode decodes data, read from the name file. stack lists the files being included, to detect cycles.
+// Included files are added to included.
+func (l *Loader) decode(data []byte, name string, stack []string, included *[]string) (map[string]interface{}, error) {
+	tmp := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, tmp); err != nil {
+		if len(stack) > 1 {
+			return nil, fmt.Errorf("yaml: %s: %s", name, err)
+		}
+		return nil, err
+	}
+	files, err := includes(tmp[includeKey])
+	if err != nil {
+		return nil, fmt.Errorf("yaml: %s: %s", name, err)
+	}
+	if len(files) == 0 {
+		return tmp, nil
+	}
+	delete(tmp, includeKey)
+	doc := map[string]interface{}{}
+	for _, f := range files {
+		inc := l.includes.resolve(name, f)
+		for _, s := range stack {
+			if s == inc {
+				return nil, fmt.Errorf("yaml: include cycle: %s", strings.Join(append(stack, inc), " -> "))
+			}
+		}
+		data, err := l.includes.readFile(inc)
+		if err != nil {
+			return nil, err
+		}
+		*included = append(*included, inc)
+		sub, err := l.decode(data, inc, append(stack[:len(stack):len(stack)], inc), included)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range sub {
+			doc[k] = merge(doc[k], v)
+		}
+	}
+	for k, v := range tmp {
+		doc[k] = merge(doc[k], v)
+	}
+	return doc, nil
+}
+
+// includes returns the files listed by the include key
+func includes(v interface{}) ([]string, error) {
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{t}, nil
+	case []interface{}:
+		files := make([]string, 0, len(t))
+		for _, f := range t {
+			s, ok := f.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s should be a file name or a list of file names", includeKey)
+			}
+			files = append(files, s)
+		}
+		return files, nil
+	}
+	return nil, fmt.Errorf("%s should be a file name or a list of file names", includeKey)
+}
+
+// merge deep merges maps decoded by yaml, values of b taking precedence
+func merge(a, b interface{}) interface{} {
+	am, aok := a.(map[interface{}]interface{})
+	bm, bok := b.(map[interface{}]interface{})
+	if !aok || !bok {
+		return b
+	}
+	for k, v := range bm {
+		am[k] = merge(am[k], v)
+	}
+	return am
+}
@@ -1,48 +1,185 @@
 // Package yaml defines a loader for yaml config files
 // 	autoconfig.Load(yaml.New(filename))
+//
+// A config file can include other files, listed by the top-level include key.
+// Included files are merged in order, the including file overriding them :
+// 	include:
+// 	  - common.yaml
+// 	  - /etc/myapp/secrets.yaml
 package yaml
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
+	"strings"
+	"sync"
 
-	"gopkg.in/yaml.v2"
+	"github.com/jfbus/autoconfig/decode"
 )
 
 type Loader struct {
-	filename string
+	read func() ([]byte, error)
+	// name is the name of the file read by read, included files are resolved relative to it
+	name     string
+	includes includer
+
+	mu sync.Mutex
+	// included lists the files included on the last load
+	included []string
 }
 
 // New creates a Loader for YAML files
 func New(filename string) *Loader {
-	return &Loader{filename: filename}
+	return &Loader{read: func() ([]byte, error) {
+		return ioutil.ReadFile(filename)
+	}, name: filename, includes: osIncluder{}}
+}
+
+// NewFS creates a Loader for the YAML file name of fsys, e.g. an embed.FS
+func NewFS(fsys fs.FS, name string) *Loader {
+	return &Loader{read: func() ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	}, name: name, includes: fsIncluder{fsys}}
+}
+
+// NewFromBytes creates a Loader for YAML data, e.g. embedded in the binary
+func NewFromBytes(data []byte) *Loader {
+	return &Loader{read: func() ([]byte, error) {
+		return data, nil
+	}, includes: osIncluder{}}
 }
 
-// Load loads the config file and unmarshals it to cfg
+// NewFromReader creates a Loader for YAML data read from r. r is only read once, all reloads use the same data.
+func NewFromReader(r io.Reader) *Loader {
+	var (
+		once sync.Once
+		data []byte
+		err  error
+	)
+	return &Loader{read: func() ([]byte, error) {
+		once.Do(func() {
+			data, err = ioutil.ReadAll(r)
+		})
+		return data, err
+	}, includes: osIncluder{}}
+}
+
+// Load loads the config file, merged with the files it includes, and unmarshals it to cfg
 func (l *Loader) Load(cfg map[string]interface{}) error {
-	data, err := ioutil.ReadFile(l.filename)
+	return l.LoadWith(cfg, decode.Options{})
+}
+
+// LoadWith loads the config file, merged with the files it includes, and unmarshals it to cfg using the decoding options o
+func (l *Loader) LoadWith(cfg map[string]interface{}, o decode.Options) error {
+	load, err := l.Document()
 	if err != nil {
 		return err
 	}
+	return load(cfg, o)
+}
 
-	tmp := map[string]interface{}{}
-	err = yaml.Unmarshal(data, tmp)
+// Document loads the config file, merged with the files it includes, and returns a function unmarshaling it to sections
+func (l *Loader) Document() (func(cfg map[string]interface{}, o decode.Options) error, error) {
+	tmp, err := l.document()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return func(cfg map[string]interface{}, o decode.Options) error {
+		return unmarshal(tmp, cfg, o)
+	}, nil
+}
+
+// unmarshal unmarshals the sections of tmp, a decoded document, to cfg
+func unmarshal(tmp map[string]interface{}, cfg map[string]interface{}, o decode.Options) error {
 	for name, scfg := range cfg {
-		if syam, ok := tmp[name]; ok {
+		if syam, ok := lookup(tmp, name); ok {
 			if syam == nil {
 				continue
 			}
-			buf, err := yaml.Marshal(syam)
-			if err != nil {
-				return err
-			}
-			err = yaml.Unmarshal(buf, scfg)
-			if err != nil {
-				return err
+			if err := o.Into(syam, scfg, "yaml"); err != nil {
+				return fmt.Errorf("yaml: section %s: %s", name, err)
 			}
 		}
 	}
 	return nil
 }
+
+// Raw loads the config file and returns the raw decoded document
+func (l *Loader) Raw() (map[string]interface{}, error) {
+	tmp, err := l.document()
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range tmp {
+		tmp[k] = stringKeys(v)
+	}
+	return tmp, nil
+}
+
+// stringKeys converts the map[interface{}]interface{} maps produced by yaml to map[string]interface{}
+func stringKeys(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprint(k)] = stringKeys(v)
+		}
+		return m
+	case []interface{}:
+		for i := range t {
+			t[i] = stringKeys(t[i])
+		}
+	}
+	return v
+}
+
+// lookup returns the value of a top-level key, or of a nested one using a dotted path (e.g. apps.api.section)
+func lookup(tmp map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := tmp[name]; ok {
+		return v, true
+	}
+	var (
+		cur interface{} = tmp
+		ok  bool
+	)
+	for _, p := range strings.Split(name, ".") {
+		switch m := cur.(type) {
+		case map[string]interface{}:
+			cur, ok = m[p]
+		case map[interface{}]interface{}:
+			cur, ok = m[p]
+		default:
+			return nil, false
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// Checksum returns a checksum of the config file and of the files it included on the last load,
+// so that autoconfig can skip reloads when they have not changed
+func (l *Loader) Checksum() (string, error) {
+	data, err := l.read()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write(data)
+	l.mu.Lock()
+	included := l.included
+	l.mu.Unlock()
+	for _, name := range included {
+		data, err := l.includes.readFile(name)
+		if err != nil {
+			return "", err
+		}
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}